@@ -16,6 +16,7 @@ import (
 // - Client certificate authentication
 // - Client secret authentication
 // - OIDC authentication
+// - Workload identity authentication
 // - GitHub OIDC authentication
 // - MSI authentication
 // - Azure CLI authentication
@@ -23,27 +24,52 @@ import (
 // Whether one of these is returned depends on whether it is enabled in the Credentials, and whether sufficient
 // configuration fields are set to enable that authentication method.
 //
-// For client certificate authentication, specify TenantID, ClientID and ClientCertificateData / ClientCertificatePath.
+// For client certificate authentication, specify TenantID, ClientID and ClientCertificateData / ClientCertificatePath,
+// which can be a PKCS#12 bundle or a PEM or DER encoded certificate, optionally with ClientCertificateKeyData /
+// ClientCertificateKeyPath.
 // For client secret authentication, specify TenantID, ClientID and ClientSecret.
 // For OIDC authentication, specify TenantID, ClientID and OIDCAssertionToken.
+// For workload identity authentication, specify TenantID, ClientID and WorkloadIdentityFederatedTokenFile, or set the
+// AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_FEDERATED_TOKEN_FILE environment variables.
 // For GitHub OIDC authentication, specify TenantID, ClientID, GitHubOIDCTokenRequestURL and GitHubOIDCTokenRequestToken.
-// MSI authentication (if enabled) using the Azure Metadata Service is then attempted
+// MSI authentication (if enabled) is then attempted, using the endpoint for the detected hosting environment (App Service,
+// Azure Arc, Service Fabric or otherwise the Azure Metadata Service). A user-assigned identity can be selected by
+// specifying ClientID, ManagedIdentityObjectID or ManagedIdentityResourceID.
 // Azure CLI authentication (if enabled) is attempted last
 //
 // It's recommended to only enable the mechanisms you have configured and are known to work in the execution
 // environment. If any authentication mechanism fails due to misconfiguration or some other error, the function
 // will return (nil, error) and later mechanisms will not be attempted.
+//
+// When a TokenCache is specified, tokens obtained by the selected Authorizer are persisted in it.
 func NewAuthorizerFromCredentials(ctx context.Context, c Credentials, api environments.Api) (Authorizer, error) {
+	a, err := newAuthorizerFromCredentials(ctx, c, api)
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := a.(*CachedAuthorizer); ok && c.TokenCache != nil {
+		cached.Cache = c.TokenCache
+	}
+	return a, nil
+}
+
+func newAuthorizerFromCredentials(ctx context.Context, c Credentials, api environments.Api) (Authorizer, error) {
 	if c.EnableAuthenticatingUsingClientCertificate && strings.TrimSpace(c.TenantID) != "" && strings.TrimSpace(c.ClientID) != "" && (len(c.ClientCertificateData) > 0 || strings.TrimSpace(c.ClientCertificatePath) != "") {
 		opts := ClientCertificateAuthorizerOptions{
-			Environment:  c.Environment,
-			Api:          api,
-			TenantId:     c.TenantID,
-			AuxTenantIds: c.AuxiliaryTenantIDs,
-			ClientId:     c.ClientID,
-			Pkcs12Data:   c.ClientCertificateData,
-			Pkcs12Path:   c.ClientCertificatePath,
-			Pkcs12Pass:   c.ClientCertificatePassword,
+			Environment:               c.Environment,
+			Api:                       api,
+			TenantId:                  c.TenantID,
+			AuxTenantIds:              c.AuxiliaryTenantIDs,
+			ClientId:                  c.ClientID,
+			PrivateKeyData:            c.ClientCertificateKeyData,
+			PrivateKeyPath:            c.ClientCertificateKeyPath,
+			SendCertificateChain:      c.ClientCertificateSendChain,
+			Logger:                    c.Logger,
+			ClientCapabilities:        c.ClientCapabilities,
+			DisableClientCapabilities: c.DisableClientCapabilities,
+		}
+		if err := opts.setCertificate(c.ClientCertificateData, c.ClientCertificatePath, c.ClientCertificatePassword); err != nil {
+			return nil, fmt.Errorf("could not configure ClientCertificate Authorizer: %s", err)
 		}
 		a, err := NewClientCertificateAuthorizer(ctx, opts)
 		if err != nil {
@@ -56,12 +82,15 @@ func NewAuthorizerFromCredentials(ctx context.Context, c Credentials, api enviro
 
 	if c.EnableAuthenticatingUsingClientSecret && strings.TrimSpace(c.TenantID) != "" && strings.TrimSpace(c.ClientID) != "" && strings.TrimSpace(c.ClientSecret) != "" {
 		opts := ClientSecretAuthorizerOptions{
-			Environment:  c.Environment,
-			Api:          api,
-			TenantId:     c.TenantID,
-			AuxTenantIds: c.AuxiliaryTenantIDs,
-			ClientId:     c.ClientID,
-			ClientSecret: c.ClientSecret,
+			Environment:               c.Environment,
+			Api:                       api,
+			TenantId:                  c.TenantID,
+			AuxTenantIds:              c.AuxiliaryTenantIDs,
+			ClientId:                  c.ClientID,
+			ClientSecret:              c.ClientSecret,
+			Logger:                    c.Logger,
+			ClientCapabilities:        c.ClientCapabilities,
+			DisableClientCapabilities: c.DisableClientCapabilities,
 		}
 		a, err := NewClientSecretAuthorizer(ctx, opts)
 		if err != nil {
@@ -74,12 +103,15 @@ func NewAuthorizerFromCredentials(ctx context.Context, c Credentials, api enviro
 
 	if c.EnableAuthenticationUsingOIDC && strings.TrimSpace(c.TenantID) != "" && strings.TrimSpace(c.ClientID) != "" && strings.TrimSpace(c.OIDCAssertionToken) != "" {
 		opts := OIDCAuthorizerOptions{
-			Environment:        c.Environment,
-			Api:                api,
-			TenantId:           c.TenantID,
-			AuxiliaryTenantIds: c.AuxiliaryTenantIDs,
-			ClientId:           c.ClientID,
-			FederatedAssertion: c.OIDCAssertionToken,
+			Environment:               c.Environment,
+			Api:                       api,
+			TenantId:                  c.TenantID,
+			AuxiliaryTenantIds:        c.AuxiliaryTenantIDs,
+			ClientId:                  c.ClientID,
+			FederatedAssertion:        c.OIDCAssertionToken,
+			Logger:                    c.Logger,
+			ClientCapabilities:        c.ClientCapabilities,
+			DisableClientCapabilities: c.DisableClientCapabilities,
 		}
 		a, err := NewOIDCAuthorizer(ctx, opts)
 		if err != nil {
@@ -90,15 +122,41 @@ func NewAuthorizerFromCredentials(ctx context.Context, c Credentials, api enviro
 		}
 	}
 
+	if c.EnableAuthenticationUsingWorkloadIdentity {
+		opts := WorkloadIdentityAuthorizerOptions{
+			Environment:               c.Environment,
+			Api:                       api,
+			TenantId:                  c.TenantID,
+			AuxiliaryTenantIds:        c.AuxiliaryTenantIDs,
+			ClientId:                  c.ClientID,
+			FederatedTokenFilePath:    c.WorkloadIdentityFederatedTokenFile,
+			Logger:                    c.Logger,
+			ClientCapabilities:        c.ClientCapabilities,
+			DisableClientCapabilities: c.DisableClientCapabilities,
+		}.withDefaults()
+		if strings.TrimSpace(opts.TenantId) != "" && strings.TrimSpace(opts.ClientId) != "" && strings.TrimSpace(opts.FederatedTokenFilePath) != "" {
+			a, err := NewWorkloadIdentityAuthorizer(ctx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("could not configure WorkloadIdentity Authorizer: %s", err)
+			}
+			if a != nil {
+				return a, nil
+			}
+		}
+	}
+
 	if c.EnableAuthenticationUsingGitHubOIDC && strings.TrimSpace(c.TenantID) != "" && strings.TrimSpace(c.ClientID) != "" && strings.TrimSpace(c.GitHubOIDCTokenRequestURL) != "" && strings.TrimSpace(c.GitHubOIDCTokenRequestToken) != "" {
 		opts := GitHubOIDCAuthorizerOptions{
-			Api:                 api,
-			AuxiliaryTenantIds:  c.AuxiliaryTenantIDs,
-			ClientId:            c.ClientID,
-			Environment:         c.Environment,
-			IdTokenRequestUrl:   c.GitHubOIDCTokenRequestURL,
-			IdTokenRequestToken: c.GitHubOIDCTokenRequestToken,
-			TenantId:            c.TenantID,
+			Api:                       api,
+			AuxiliaryTenantIds:        c.AuxiliaryTenantIDs,
+			ClientId:                  c.ClientID,
+			Environment:               c.Environment,
+			IdTokenRequestUrl:         c.GitHubOIDCTokenRequestURL,
+			IdTokenRequestToken:       c.GitHubOIDCTokenRequestToken,
+			TenantId:                  c.TenantID,
+			Logger:                    c.Logger,
+			ClientCapabilities:        c.ClientCapabilities,
+			DisableClientCapabilities: c.DisableClientCapabilities,
 		}
 		a, err := NewGitHubOIDCAuthorizer(context.Background(), opts)
 		if err != nil {
@@ -114,6 +172,12 @@ func NewAuthorizerFromCredentials(ctx context.Context, c Credentials, api enviro
 			Api:                           api,
 			ClientId:                      c.ClientID,
 			CustomManagedIdentityEndpoint: c.CustomManagedIdentityEndpoint,
+			ObjectId:                      c.ManagedIdentityObjectID,
+			ResourceId:                    c.ManagedIdentityResourceID,
+			Logger:                        c.Logger,
+		}
+		if opts.ObjectId != "" || opts.ResourceId != "" {
+			opts.ClientId = ""
 		}
 		a, err := NewManagedIdentityAuthorizer(ctx, opts)
 		if err != nil {
@@ -129,6 +193,7 @@ func NewAuthorizerFromCredentials(ctx context.Context, c Credentials, api enviro
 			Api:          api,
 			TenantId:     c.TenantID,
 			AuxTenantIds: c.AuxiliaryTenantIDs,
+			Logger:       c.Logger,
 		}
 		a, err := NewAzureCliAuthorizer(ctx, opts)
 		if err != nil {
@@ -61,6 +61,30 @@ func TestNewAuthorizerFromCredentials(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			credentials: func() (ret auth.Credentials) {
+				ret = credentials
+				ret.EnableAuthenticatingUsingClientCertificate = true
+				ret.ClientCertificateData = []byte(dummyEcdsaCertificate)
+				ret.ClientCertificateKeyData = []byte(dummyEncryptedEcdsaKey)
+				ret.ClientCertificatePassword = "keypassword"
+				ret.ClientCertificateSendChain = true
+				return
+			},
+			check: func(a auth.Authorizer) error {
+				b, ok := a.(*auth.CachedAuthorizer)
+				if !ok {
+					return fmt.Errorf("authorizer was not an *auth.CachedAuthorizer")
+				}
+
+				_, ok = b.Source.(*auth.ClientAssertionAuthorizer)
+				if !ok {
+					return fmt.Errorf("authorizer source was not an *auth.ClientAssertionAuthorizer")
+				}
+
+				return nil
+			},
+		},
 		{
 			credentials: func() (ret auth.Credentials) {
 				ret = credentials
@@ -141,6 +165,27 @@ func TestNewAuthorizerFromCredentials(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			credentials: func() (ret auth.Credentials) {
+				ret = credentials
+				ret.EnableAuthenticationUsingWorkloadIdentity = true
+				ret.WorkloadIdentityFederatedTokenFile = "/path/to/token"
+				return
+			},
+			check: func(a auth.Authorizer) error {
+				b, ok := a.(*auth.CachedAuthorizer)
+				if !ok {
+					return fmt.Errorf("authorizer was not an *auth.CachedAuthorizer")
+				}
+
+				_, ok = b.Source.(*auth.WorkloadIdentityAuthorizer)
+				if !ok {
+					return fmt.Errorf("authorizer source was not an *auth.WorkloadIdentityAuthorizer")
+				}
+
+				return nil
+			},
+		},
 		{
 			credentials: func() auth.Credentials {
 				return credentials
@@ -13,6 +13,7 @@ import (
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
 	"github.com/hashicorp/go-azure-sdk/sdk/internal/azurecli"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"golang.org/x/oauth2"
 )
 
@@ -27,6 +28,10 @@ type AzureCliAuthorizerOptions struct {
 	// used for Resource Manager when auxiliary tenants are needed.
 	// e.g. https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/authenticate-multi-tenant
 	AuxTenantIds []string
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
 }
 
 // NewAzureCliAuthorizer returns an Authorizer which authenticates using the Azure CLI.
@@ -35,6 +40,7 @@ func NewAzureCliAuthorizer(ctx context.Context, options AzureCliAuthorizerOption
 	if err != nil {
 		return nil, err
 	}
+	conf.Logger = options.Logger
 	return conf.TokenSource(ctx)
 }
 
@@ -90,6 +96,25 @@ func (a *AzureCliAuthorizer) Token(_ context.Context, _ *http.Request) (*oauth2.
 	}, nil
 }
 
+// tokenCacheKey identifies the tokens obtained from the Azure CLI, so that they can be persisted in a TokenCache. The
+// account signed in to the Azure CLI is identified by its default subscription, since the Azure CLI does not otherwise
+// describe it without being invoked.
+func (a *AzureCliAuthorizer) tokenCacheKey() (TokenCacheKey, bool) {
+	if a.conf == nil || a.conf.DefaultSubscriptionID == "" {
+		return TokenCacheKey{}, false
+	}
+	scope, err := environments.Scope(a.conf.Api)
+	if err != nil {
+		return TokenCacheKey{}, false
+	}
+	return TokenCacheKey{
+		Authority: "azure-cli",
+		TenantId:  a.conf.TenantID,
+		ClientId:  a.conf.DefaultSubscriptionID,
+		Scope:     *scope,
+	}, true
+}
+
 // AuxiliaryTokens returns additional tokens for auxiliary tenant IDs, for use in multi-tenant scenarios
 func (a *AzureCliAuthorizer) AuxiliaryTokens(_ context.Context, _ *http.Request) ([]*oauth2.Token, error) {
 	if a.conf == nil {
@@ -144,6 +169,9 @@ type azureCliConfig struct {
 
 	// DefaultSubscriptionID is the optional default subscription ID
 	DefaultSubscriptionID string
+
+	// Logger is used when obtaining tokens
+	Logger logging.Logger
 }
 
 // newAzureCliConfig validates the supplied tenant ID and returns a new azureCliConfig.
@@ -190,11 +218,14 @@ func newAzureCliConfig(api environments.Api, tenantId string, auxiliaryTenantIds
 // TokenSource provides a source for obtaining access tokens using AzureCliAuthorizer.
 func (c *azureCliConfig) TokenSource(ctx context.Context) (Authorizer, error) {
 	// Cache access tokens internally to avoid unnecessary `az` invocations
-	return NewCachedAuthorizer(&AzureCliAuthorizer{
-		TenantID:              c.TenantID,
-		DefaultSubscriptionID: c.DefaultSubscriptionID,
-		conf:                  c,
-	})
+	return &CachedAuthorizer{
+		Source: &AzureCliAuthorizer{
+			TenantID:              c.TenantID,
+			DefaultSubscriptionID: c.DefaultSubscriptionID,
+			conf:                  c,
+		},
+		Logger: c.Logger,
+	}, nil
 }
 
 type azureCliToken struct {
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"golang.org/x/oauth2"
 )
 
@@ -20,42 +21,114 @@ type CachedAuthorizer struct {
 	// Source contains the underlying Authorizer for obtaining tokens
 	Source Authorizer
 
+	// Cache optionally persists the tokens obtained by Source, so that they can be reused by other Authorizers or
+	// processes. Tokens are only persisted when Source is able to describe them, and auxiliary tokens are not persisted.
+	Cache TokenCache
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
+
 	mutex     sync.RWMutex
 	token     *oauth2.Token
 	auxTokens []*oauth2.Token
 }
 
-// Token returns the current token if it's still valid, else will acquire a new token
+// Token returns the current token if it's still valid, else will acquire a new token. A new token is always acquired
+// when claims are requested using WithClaims.
 func (c *CachedAuthorizer) Token(ctx context.Context, req *http.Request) (*oauth2.Token, error) {
+	ctx = withLogger(ctx, c.Logger)
+	_, claimsRequested := ClaimsFromContext(ctx)
+
 	c.mutex.RLock()
-	dueForRenewal := tokenDueForRenewal(c.token)
+	token := c.token
 	c.mutex.RUnlock()
 
-	if dueForRenewal {
+	if claimsRequested || tokenDueForRenewal(token) {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
+		if !claimsRequested {
+			if token := c.cachedToken(ctx); token != nil {
+				c.token = token
+				return c.token, nil
+			}
+		}
+
 		var err error
 		c.token, err = c.Source.Token(ctx, req)
 		if err != nil {
 			return nil, err
 		}
+		c.storeToken(ctx, c.token)
+		return c.token, nil
+	}
+
+	return token, nil
+}
+
+// cachedToken returns a valid token from Cache, if one exists. When the cached token has expired but includes a
+// refresh token, the refresh token is passed on to Source so that it can be redeemed.
+func (c *CachedAuthorizer) cachedToken(ctx context.Context) *oauth2.Token {
+	key, ok := c.cacheKey()
+	if !ok {
+		return nil
+	}
+
+	token, found, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		loggerFromContext(ctx).Debug("could not retrieve token from cache", "error", err)
+		return nil
+	}
+	if !found || token == nil {
+		return nil
+	}
+	if !tokenDueForRenewal(token) {
+		return token
+	}
+	if seeder, ok := c.Source.(refreshTokenSeeder); ok && token.RefreshToken != "" {
+		seeder.seedRefreshToken(token.RefreshToken)
+	}
+	return nil
+}
+
+// storeToken persists the token in Cache, if configured
+func (c *CachedAuthorizer) storeToken(ctx context.Context, token *oauth2.Token) {
+	key, ok := c.cacheKey()
+	if !ok || token == nil {
+		return
+	}
+	if err := c.Cache.Set(ctx, key, token); err != nil {
+		loggerFromContext(ctx).Debug("could not store token in cache", "error", err)
 	}
+}
 
-	return c.token, nil
+// cacheKey returns the key for tokens obtained by Source, when Cache is configured and Source can describe its tokens
+func (c *CachedAuthorizer) cacheKey() (TokenCacheKey, bool) {
+	if c.Cache == nil {
+		return TokenCacheKey{}, false
+	}
+	keyer, ok := c.Source.(tokenCacheKeyer)
+	if !ok {
+		return TokenCacheKey{}, false
+	}
+	return keyer.tokenCacheKey()
 }
 
 // AuxiliaryTokens returns additional tokens for auxiliary tenant IDs, for use in multi-tenant scenarios
 func (c *CachedAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Request) ([]*oauth2.Token, error) {
+	ctx = withLogger(ctx, c.Logger)
 	c.mutex.RLock()
-	var dueForRenewal bool
-	for _, token := range c.auxTokens {
+	auxTokens := c.auxTokens
+	c.mutex.RUnlock()
+
+	dueForRenewal := len(auxTokens) == 0
+	for _, token := range auxTokens {
 		if dueForRenewal = tokenDueForRenewal(token); dueForRenewal {
 			break
 		}
 	}
-	c.mutex.RUnlock()
 
-	if dueForRenewal || len(c.auxTokens) == 0 {
+	if dueForRenewal {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
 		var err error
@@ -63,24 +136,50 @@ func (c *CachedAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Reques
 		if err != nil {
 			return nil, err
 		}
+		return c.auxTokens, nil
 	}
 
-	return c.auxTokens, nil
+	return auxTokens, nil
 }
 
 // InvalidateCachedTokens expires the currently cached token and auxTokens, forcing new
-// tokens to be acquired when Token() or AuxiliaryTokens() are next called
+// tokens to be acquired when Token() or AuxiliaryTokens() are next called. Any token persisted
+// in Cache is also expired, retaining its refresh token.
+//
+// The cached tokens are replaced with expired copies, so tokens previously returned to callers are not modified.
 func (c *CachedAuthorizer) InvalidateCachedTokens() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	if c.token == nil {
 		return nil
 	}
-	c.token.Expiry = time.Now()
-	for i := range c.auxTokens {
-		c.auxTokens[i].Expiry = time.Now()
+	now := time.Now()
+	c.token = expiredToken(c.token, now)
+	auxTokens := make([]*oauth2.Token, 0, len(c.auxTokens))
+	for _, token := range c.auxTokens {
+		auxTokens = append(auxTokens, expiredToken(token, now))
+	}
+	c.auxTokens = auxTokens
+
+	if key, ok := c.cacheKey(); ok {
+		if err := c.Cache.Set(context.Background(), key, c.token); err != nil {
+			return fmt.Errorf("invalidating cached token: %v", err)
+		}
 	}
 	return nil
 }
 
+// expiredToken returns a copy of token which expired at the specified time
+func expiredToken(token *oauth2.Token, expiry time.Time) *oauth2.Token {
+	if token == nil {
+		return nil
+	}
+	expired := *token
+	expired.Expiry = expiry
+	return &expired
+}
+
 // NewCachedAuthorizer returns an Authorizer that caches an access token for the duration of its validity.
 // If the cached token expires, a new one is acquired and cached.
 func NewCachedAuthorizer(src Authorizer) (CachingAuthorizer, error) {
@@ -7,6 +7,7 @@ import (
 	"context"
 	"net/http"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
@@ -162,3 +163,40 @@ func TestCachedAuthorizer(t *testing.T) {
 		}
 	}
 }
+
+func TestCachedAuthorizer_InvalidateCachedTokens(t *testing.T) {
+	ctx := context.Background()
+	authorizer, err := auth.NewCachedAuthorizer(&test.TestAuthorizer{})
+	if err != nil {
+		t.Fatalf("NewCachedAuthorizer(): %+v", err)
+	}
+
+	token, err := authorizer.Token(ctx, nil)
+	if err != nil {
+		t.Fatalf("CachedAuthorizer.Token(): %+v", err)
+	}
+	expiry := token.Expiry
+
+	// tokens can be invalidated whilst they are being obtained, and tokens already returned are not modified
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := authorizer.Token(ctx, nil); err != nil {
+				t.Errorf("CachedAuthorizer.Token(): %+v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := authorizer.InvalidateCachedTokens(); err != nil {
+				t.Errorf("CachedAuthorizer.InvalidateCachedTokens(): %+v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !token.Expiry.Equal(expiry) {
+		t.Fatalf("expected the previously returned token to be unchanged, but its expiry changed to %s", token.Expiry)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+var _ CachingAuthorizer = &ChainedAuthorizer{}
+
+// ChainedAuthorizer tries each of the Sources in order when a token is first requested, and then continues to use the
+// first Authorizer which successfully obtained a token. This allows falling back between authentication methods
+// which may or may not be available in the execution environment, e.g. workload identity, then managed identity,
+// then the Azure CLI.
+type ChainedAuthorizer struct {
+	// Sources contains the Authorizers to try, in order of preference
+	Sources []Authorizer
+
+	mutex    sync.Mutex
+	selected Authorizer
+}
+
+// NewChainedAuthorizer returns an Authorizer which uses the first of the provided Authorizers that successfully
+// obtains a token. Any nil Authorizers are ignored.
+func NewChainedAuthorizer(sources ...Authorizer) (CachingAuthorizer, error) {
+	a := &ChainedAuthorizer{}
+	for _, source := range sources {
+		if source != nil {
+			a.Sources = append(a.Sources, source)
+		}
+	}
+	if len(a.Sources) == 0 {
+		return nil, fmt.Errorf("at least one Authorizer must be specified")
+	}
+	return a, nil
+}
+
+// ChainedAuthorizerError is returned when none of the Authorizers in a ChainedAuthorizer could obtain a token
+type ChainedAuthorizerError struct {
+	// Errors contains the error returned by each Authorizer, in the order they were tried
+	Errors []error
+
+	names []string
+}
+
+func (e ChainedAuthorizerError) Error() string {
+	lines := []string{"no Authorizer in the chain could obtain a token:"}
+	for i, err := range e.Errors {
+		lines = append(lines, fmt.Sprintf("  - %s: %v", e.names[i], err))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e ChainedAuthorizerError) Unwrap() []error {
+	return e.Errors
+}
+
+// Token returns an access token from the selected Authorizer, trying each of the Sources in turn if one has not yet
+// been selected. The Sources are not tried whilst holding a lock, so concurrent callers may each try them before an
+// Authorizer has been selected, in which case the first to succeed is retained.
+func (a *ChainedAuthorizer) Token(ctx context.Context, req *http.Request) (*oauth2.Token, error) {
+	if selected := a.Selected(); selected != nil {
+		return selected.Token(ctx, req)
+	}
+
+	chainErr := ChainedAuthorizerError{}
+	for _, source := range a.Sources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		token, err := source.Token(ctx, req)
+		if err == nil {
+			loggerFromContext(ctx).Debug("selected Authorizer from chain", "authorizer", authorizerName(source))
+			a.mutex.Lock()
+			if a.selected == nil {
+				a.selected = source
+			}
+			a.mutex.Unlock()
+			return token, nil
+		}
+		loggerFromContext(ctx).Debug("Authorizer in chain could not obtain a token", "authorizer", authorizerName(source), "error", err)
+		chainErr.Errors = append(chainErr.Errors, err)
+		chainErr.names = append(chainErr.names, authorizerName(source))
+	}
+
+	return nil, chainErr
+}
+
+// AuxiliaryTokens returns additional tokens for auxiliary tenant IDs from the selected Authorizer, for use in
+// multi-tenant scenarios
+func (a *ChainedAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Request) ([]*oauth2.Token, error) {
+	if _, err := a.Token(ctx, req); err != nil {
+		return nil, err
+	}
+	return a.Selected().AuxiliaryTokens(ctx, req)
+}
+
+// InvalidateCachedTokens invalidates any cached tokens held by the selected Authorizer
+func (a *ChainedAuthorizer) InvalidateCachedTokens() error {
+	if cachingAuthorizer, ok := a.Selected().(CachingAuthorizer); ok {
+		return cachingAuthorizer.InvalidateCachedTokens()
+	}
+	return nil
+}
+
+// Selected returns the Authorizer which first obtained a token, or nil when no Authorizer has yet been selected
+func (a *ChainedAuthorizer) Selected() Authorizer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.selected
+}
+
+// authorizerName returns a description of an Authorizer for diagnostic purposes
+func authorizerName(a Authorizer) string {
+	if cached, ok := a.(*CachedAuthorizer); ok && cached.Source != nil {
+		a = cached.Source
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", a), "*")
+}
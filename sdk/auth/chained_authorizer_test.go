@@ -0,0 +1,120 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"github.com/hashicorp/go-azure-sdk/sdk/internal/test"
+	"golang.org/x/oauth2"
+)
+
+// failingAuthorizer is an Authorizer which always fails to obtain a token
+type failingAuthorizer struct {
+	calls int
+}
+
+func (a *failingAuthorizer) Token(_ context.Context, _ *http.Request) (*oauth2.Token, error) {
+	a.calls++
+	return nil, fmt.Errorf("no credentials available")
+}
+
+func (a *failingAuthorizer) AuxiliaryTokens(_ context.Context, _ *http.Request) ([]*oauth2.Token, error) {
+	a.calls++
+	return nil, fmt.Errorf("no credentials available")
+}
+
+func TestChainedAuthorizer(t *testing.T) {
+	ctx := context.Background()
+
+	first := &failingAuthorizer{}
+	second := &test.TestAuthorizer{}
+	authorizer, err := auth.NewChainedAuthorizer(first, nil, second, &failingAuthorizer{})
+	if err != nil {
+		t.Fatalf("NewChainedAuthorizer(): %v", err)
+	}
+
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = authorizer.AuxiliaryTokens(ctx, nil); err != nil {
+		t.Fatalf("authorizer.AuxiliaryTokens(): %v", err)
+	}
+
+	if first.calls != 1 {
+		t.Fatalf("expected the failing Authorizer to be tried once, but it was called %d times", first.calls)
+	}
+	if selected := authorizer.(*auth.ChainedAuthorizer).Selected(); selected != second {
+		t.Fatalf("expected the second Authorizer to be selected, got %T", selected)
+	}
+}
+
+func TestChainedAuthorizerAllFail(t *testing.T) {
+	authorizer, err := auth.NewChainedAuthorizer(&failingAuthorizer{}, &failingAuthorizer{})
+	if err != nil {
+		t.Fatalf("NewChainedAuthorizer(): %v", err)
+	}
+
+	_, err = authorizer.Token(context.Background(), nil)
+	var chainErr auth.ChainedAuthorizerError
+	if !errors.As(err, &chainErr) {
+		t.Fatalf("expected a ChainedAuthorizerError, got %T: %v", err, err)
+	}
+	if len(chainErr.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(chainErr.Errors))
+	}
+	if strings.Count(err.Error(), "auth_test.failingAuthorizer: no credentials available") != 2 {
+		t.Fatalf("expected the error to describe each failure, got: %v", err)
+	}
+
+	if _, err = auth.NewChainedAuthorizer(); err == nil {
+		t.Fatal("expected an error when no Authorizers are specified")
+	}
+}
+
+// cancellingAuthorizer cancels the context and fails to obtain a token, after checking that the chain is not locked
+type cancellingAuthorizer struct {
+	chain  *auth.ChainedAuthorizer
+	cancel context.CancelFunc
+}
+
+func (a *cancellingAuthorizer) Token(_ context.Context, _ *http.Request) (*oauth2.Token, error) {
+	// this would deadlock if the chain were locked whilst obtaining a token
+	_ = a.chain.Selected()
+	a.cancel()
+	return nil, fmt.Errorf("no credentials available")
+}
+
+func (a *cancellingAuthorizer) AuxiliaryTokens(_ context.Context, _ *http.Request) ([]*oauth2.Token, error) {
+	return nil, fmt.Errorf("no credentials available")
+}
+
+func TestChainedAuthorizerCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := &cancellingAuthorizer{cancel: cancel}
+	second := &failingAuthorizer{}
+	authorizer, err := auth.NewChainedAuthorizer(first, second)
+	if err != nil {
+		t.Fatalf("NewChainedAuthorizer(): %v", err)
+	}
+	first.chain = authorizer.(*auth.ChainedAuthorizer)
+
+	if _, err = authorizer.Token(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the context to be cancelled, got: %v", err)
+	}
+	if second.calls != 0 {
+		t.Fatalf("expected no further Authorizers to be tried once the context was cancelled, but one was called %d times", second.calls)
+	}
+}
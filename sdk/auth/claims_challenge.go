@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ClientCapabilityCP1 is the client capability which indicates that claims challenges can be handled, so that
+// long-lived tokens can be issued for services supporting Continuous Access Evaluation (CAE). This is advertised by
+// default by Authorizers which support claims challenges, unless other ClientCapabilities are specified or
+// DisableClientCapabilities is set.
+//
+// For more information see:
+// https://learn.microsoft.com/en-us/entra/identity-platform/claims-challenge
+const ClientCapabilityCP1 = "CP1"
+
+// clientCapabilities returns the client capabilities to advertise, defaulting to ClientCapabilityCP1
+func clientCapabilities(capabilities []string, disabled bool) []string {
+	if disabled {
+		return nil
+	}
+	if len(capabilities) == 0 {
+		return []string{ClientCapabilityCP1}
+	}
+	return capabilities
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a context which requests that tokens obtained using it include the specified claims, as
+// requested by a claims challenge. Cached tokens are not used when claims are requested.
+func WithClaims(ctx context.Context, claims string) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims requested using WithClaims, if any
+func ClaimsFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(string)
+	return claims, ok && claims != ""
+}
+
+// ClaimsChallenge returns the claims requested by a claims challenge in the `WWW-Authenticate` header of a 401
+// response, such as when a token has been revoked by Continuous Access Evaluation or a Conditional Access policy
+// requires additional authentication. The returned claims are decoded and can be passed to WithClaims.
+func ClaimsChallenge(resp *http.Response) (string, bool) {
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return "", false
+	}
+
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		params := parseBearerChallenge(header)
+		if params == nil || params["error"] != "insufficient_claims" || params["claims"] == "" {
+			continue
+		}
+		if claims, ok := decodeClaims(params["claims"]); ok {
+			return claims, true
+		}
+	}
+	return "", false
+}
+
+// parseBearerChallenge returns the parameters of the Bearer challenge in a `WWW-Authenticate` header value, or nil
+// when there is no Bearer challenge
+func parseBearerChallenge(header string) map[string]string {
+	i := strings.Index(strings.ToLower(header), "bearer ")
+	if i < 0 {
+		return nil
+	}
+	s := header[i+len("bearer "):]
+
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.Index(s, "=")
+		if eq <= 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		if strings.Contains(key, " ") {
+			// the start of another challenge
+			return params
+		}
+		s = s[eq+1:]
+
+		var value strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			s = s[1:]
+			for len(s) > 0 {
+				c := s[0]
+				s = s[1:]
+				if c == '\\' && len(s) > 0 {
+					value.WriteByte(s[0])
+					s = s[1:]
+					continue
+				}
+				if c == '"' {
+					break
+				}
+				value.WriteByte(c)
+			}
+		} else {
+			end := strings.Index(s, ",")
+			if end < 0 {
+				end = len(s)
+			}
+			value.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+		params[key] = value.String()
+	}
+}
+
+// decodeClaims decodes the base64-encoded claims from a challenge, accepting unencoded JSON for robustness
+func decodeClaims(claims string) (string, bool) {
+	if json.Valid([]byte(claims)) {
+		return claims, true
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := encoding.DecodeString(claims); err == nil && json.Valid(b) {
+			return string(b), true
+		}
+	}
+	return "", false
+}
+
+// tokenRequestClaims returns the value of the `claims` parameter for a token request, combining any claims requested
+// using WithClaims with the specified client capabilities
+func tokenRequestClaims(ctx context.Context, clientCapabilities []string) (string, error) {
+	requested, hasClaims := ClaimsFromContext(ctx)
+	if !hasClaims && len(clientCapabilities) == 0 {
+		return "", nil
+	}
+
+	claims := map[string]interface{}{}
+	if hasClaims {
+		if err := json.Unmarshal([]byte(requested), &claims); err != nil {
+			return "", fmt.Errorf("parsing requested claims: %v", err)
+		}
+	}
+
+	if len(clientCapabilities) > 0 {
+		accessToken, ok := claims["access_token"].(map[string]interface{})
+		if !ok {
+			accessToken = map[string]interface{}{}
+		}
+		accessToken["xms_cc"] = map[string]interface{}{
+			"values": clientCapabilities,
+		}
+		claims["access_token"] = accessToken
+	}
+
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("marshaling claims: %v", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"github.com/hashicorp/go-azure-sdk/sdk/internal/test"
+	"golang.org/x/oauth2"
+)
+
+func TestClaimsChallenge(t *testing.T) {
+	testData := []struct {
+		name     string
+		status   int
+		headers  []string
+		expected string
+	}{
+		{
+			name:     "base64 encoded claims",
+			status:   http.StatusUnauthorized,
+			headers:  []string{`Bearer realm="", authorization_uri="https://login.microsoftonline.com/common/oauth2/authorize", error="insufficient_claims", claims="eyJhY2Nlc3NfdG9rZW4iOnsibmJmIjp7ImVzc2VudGlhbCI6dHJ1ZSwidmFsdWUiOiIxNzI2MDc3NTk1In19fQ=="`},
+			expected: `{"access_token":{"nbf":{"essential":true,"value":"1726077595"}}}`,
+		},
+		{
+			name:     "unpadded base64 encoded claims after another challenge",
+			status:   http.StatusUnauthorized,
+			headers:  []string{`Basic realm="example"`, `Bearer error="insufficient_claims", claims="eyJhY2Nlc3NfdG9rZW4iOnsiYWNycyI6eyJlc3NlbnRpYWwiOnRydWV9fX0"`},
+			expected: `{"access_token":{"acrs":{"essential":true}}}`,
+		},
+		{
+			name:     "unencoded claims with escaped quotes",
+			status:   http.StatusUnauthorized,
+			headers:  []string{`Bearer error="insufficient_claims", claims="{\"access_token\":{}}"`},
+			expected: `{"access_token":{}}`,
+		},
+		{
+			name:    "invalid token",
+			status:  http.StatusUnauthorized,
+			headers: []string{`Bearer error="invalid_token", error_description="The access token has expired"`},
+		},
+		{
+			name:    "not a 401",
+			status:  http.StatusForbidden,
+			headers: []string{`Bearer error="insufficient_claims", claims="e30="`},
+		},
+		{
+			name:    "no challenge",
+			status:  http.StatusUnauthorized,
+			headers: []string{},
+		},
+	}
+
+	for _, v := range testData {
+		resp := &http.Response{
+			StatusCode: v.status,
+			Header:     http.Header{},
+		}
+		for _, h := range v.headers {
+			resp.Header.Add("WWW-Authenticate", h)
+		}
+
+		claims, ok := auth.ClaimsChallenge(resp)
+		if ok != (v.expected != "") {
+			t.Fatalf("%s: expected a claims challenge: %t, got %t", v.name, v.expected != "", ok)
+		}
+		if claims != v.expected {
+			t.Fatalf("%s: expected the claims %q, got %q", v.name, v.expected, claims)
+		}
+	}
+}
+
+// countingAuthorizer counts the tokens obtained from it
+type countingAuthorizer struct {
+	test.TestAuthorizer
+	calls int
+}
+
+func (a *countingAuthorizer) Token(ctx context.Context, req *http.Request) (*oauth2.Token, error) {
+	a.calls++
+	return a.TestAuthorizer.Token(ctx, req)
+}
+
+func TestCachedAuthorizer_Claims(t *testing.T) {
+	ctx := context.Background()
+
+	source := &countingAuthorizer{}
+	authorizer, err := auth.NewCachedAuthorizer(source)
+	if err != nil {
+		t.Fatalf("NewCachedAuthorizer(): %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err = authorizer.Token(ctx, nil); err != nil {
+			t.Fatalf("Token(): %v", err)
+		}
+	}
+	if _, err = authorizer.Token(auth.WithClaims(ctx, `{"access_token":{}}`), nil); err != nil {
+		t.Fatalf("Token(): %v", err)
+	}
+	if source.calls != 2 {
+		t.Fatalf("expected a new token to be obtained when claims are requested, got %d calls", source.calls)
+	}
+}
+
+func TestClientCapabilities(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	testData := []struct {
+		name         string
+		capabilities []string
+		disabled     bool
+		expected     []string
+	}{
+		{
+			name: "default",
+			expected: []string{
+				`{"access_token":{"xms_cc":{"values":["CP1"]}}}`,
+				`{"access_token":{"nbf":{"essential":true,"value":"1726077595"},"xms_cc":{"values":["CP1"]}}}`,
+			},
+		},
+		{
+			name:         "explicit",
+			capabilities: []string{"CP2"},
+			expected: []string{
+				`{"access_token":{"xms_cc":{"values":["CP2"]}}}`,
+				`{"access_token":{"nbf":{"essential":true,"value":"1726077595"},"xms_cc":{"values":["CP2"]}}}`,
+			},
+		},
+		{
+			name:         "disabled",
+			capabilities: []string{auth.ClientCapabilityCP1},
+			disabled:     true,
+			expected: []string{
+				"",
+				`{"access_token":{"nbf":{"essential":true,"value":"1726077595"}}}`,
+			},
+		},
+	}
+
+	for _, v := range testData {
+		authorizer, err := auth.NewDeviceCodeAuthorizer(ctx, auth.DeviceCodeAuthorizerOptions{
+			Environment:               env,
+			Api:                       env.ResourceManager,
+			ClientCapabilities:        v.capabilities,
+			DisableClientCapabilities: v.disabled,
+			Prompt: func(context.Context, auth.DeviceCode) error {
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: NewDeviceCodeAuthorizer(): %v", v.name, err)
+		}
+
+		if _, err = authorizer.Token(ctx, nil); err != nil {
+			t.Fatalf("%s: Token(): %v", v.name, err)
+		}
+		if _, err = authorizer.Token(auth.WithClaims(ctx, `{"access_token":{"nbf":{"essential":true,"value":"1726077595"}}}`), nil); err != nil {
+			t.Fatalf("%s: Token(): %v", v.name, err)
+		}
+
+		last := server.claims[len(server.claims)-2:]
+		if last[0] != v.expected[0] || last[1] != v.expected[1] {
+			t.Fatalf("%s: expected the claims %q, got %q", v.name, v.expected, last)
+		}
+	}
+}
@@ -5,14 +5,19 @@ package auth
 
 import (
 	"context"
+	"crypto/sha1"
 	"crypto/tls"
-	"log"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"math"
 	"net"
 	"net/http"
 	"net/url"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
@@ -26,6 +31,9 @@ var (
 	MetadataClient HTTPClient
 )
 
+// errServerCertificateThumbprintMismatch is returned when a pinned server certificate does not match
+var errServerCertificateThumbprintMismatch = errors.New("the server certificate thumbprint did not match the expected thumbprint")
+
 func init() {
 	Client = httpClient(defaultHttpClientParams())
 	MetadataClient = httpClient(httpClientParams{
@@ -45,6 +53,10 @@ type httpClientParams struct {
 	retryWaitMax  time.Duration
 	retryMaxCount int
 	useProxy      bool
+
+	// serverCertificateThumbprint optionally pins the server certificate to the specified SHA-1 thumbprint, in place
+	// of verifying the certificate chain, for endpoints which use self-signed certificates such as Service Fabric
+	serverCertificateThumbprint string
 }
 
 func defaultHttpClientParams() httpClientParams {
@@ -63,7 +75,13 @@ func defaultHttpClientParams() httpClientParams {
 func httpClient(params httpClientParams) *http.Client {
 	r := retryablehttp.NewClient()
 
-	r.Logger = log.Default()
+	r.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		// a pinned certificate won't change between attempts
+		if errors.Is(err, errServerCertificateThumbprintMismatch) {
+			return false, err
+		}
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
 
 	r.Backoff = func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
 		// note: min and max contain the values of r.RetryWaitMin and r.RetryWaitMax
@@ -105,6 +123,20 @@ func httpClient(params httpClientParams) *http.Client {
 	tlsConfig := tls.Config{
 		MinVersion: tls.VersionTLS12,
 	}
+	if params.serverCertificateThumbprint != "" {
+		// the certificate chain isn't verified since the certificate is self-signed, instead the certificate is pinned
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("no server certificate was presented")
+			}
+			thumbprint := sha1.Sum(rawCerts[0])
+			if !strings.EqualFold(hex.EncodeToString(thumbprint[:]), params.serverCertificateThumbprint) {
+				return fmt.Errorf("%w %q", errServerCertificateThumbprintMismatch, params.serverCertificateThumbprint)
+			}
+			return nil
+		}
+	}
 	r.HTTPClient = &http.Client{
 		Transport: &http.Transport{
 			Proxy: proxyFunc,
@@ -122,5 +154,44 @@ func httpClient(params httpClientParams) *http.Client {
 		},
 	}
 
-	return r.StandardClient()
+	return &http.Client{
+		Transport: &retryableRoundTripper{
+			client: r,
+		},
+	}
+}
+
+// retryableRoundTripper sends requests using the configured retryablehttp Client, logging messages (including
+// errors and retries) using the Logger configured for the Authorizer, which is carried by the request context
+type retryableRoundTripper struct {
+	client *retryablehttp.Client
+}
+
+// RoundTrip implements http.RoundTripper
+func (rt *retryableRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryableReq, err := retryablehttp.FromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// the Client is shared between Authorizers, so a copy is made for each request using the appropriate Logger
+	client := &retryablehttp.Client{
+		HTTPClient:      rt.client.HTTPClient,
+		Logger:          retryablehttp.LeveledLogger(loggerFromContext(req.Context())),
+		RetryWaitMin:    rt.client.RetryWaitMin,
+		RetryWaitMax:    rt.client.RetryWaitMax,
+		RetryMax:        rt.client.RetryMax,
+		RequestLogHook:  rt.client.RequestLogHook,
+		ResponseLogHook: rt.client.ResponseLogHook,
+		CheckRetry:      rt.client.CheckRetry,
+		Backoff:         rt.client.Backoff,
+		ErrorHandler:    rt.client.ErrorHandler,
+	}
+
+	resp, err := client.Do(retryableReq)
+	// unwrap errors returned by the standard library's Do method, as they will be wrapped again by the http.Client
+	if _, ok := err.(*url.Error); ok {
+		return resp, errors.Unwrap(err)
+	}
+	return resp, err
 }
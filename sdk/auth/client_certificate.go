@@ -0,0 +1,275 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// isPemOrDerCertificate returns whether data contains PEM encoded data, or a DER encoded certificate, rather than a
+// PKCS#12 archive
+func isPemOrDerCertificate(data []byte) bool {
+	if block, _ := pem.Decode(data); block != nil {
+		return true
+	}
+	_, err := x509.ParseCertificates(data)
+	return err == nil
+}
+
+// parseCertificates parses one or more PEM or DER encoded certificates, along with any private key contained in PEM
+// data. The first certificate is expected to be the client certificate, followed by any intermediate certificates.
+func parseCertificates(data []byte, password string) ([]*x509.Certificate, crypto.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block == nil {
+		certs, err := x509.ParseCertificates(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing DER encoded certificate: %v", err)
+		}
+		return certs, nil, nil
+	}
+
+	var certs []*x509.Certificate
+	var key crypto.PrivateKey
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing PEM encoded certificate: %v", err)
+			}
+			certs = append(certs, cert)
+			continue
+		}
+
+		k, err := parsePemPrivateKey(block, password)
+		if err != nil {
+			return nil, nil, err
+		}
+		if k != nil {
+			if key != nil {
+				return nil, nil, fmt.Errorf("more than one private key was found")
+			}
+			key = k
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("no certificates were found in the PEM data")
+	}
+	return certs, key, nil
+}
+
+// parsePrivateKey parses a PEM or DER encoded private key, which may be an (optionally encrypted) PKCS#8 key, a
+// PKCS#1 RSA key or a SEC 1 EC key
+func parsePrivateKey(data []byte, password string) (crypto.PrivateKey, error) {
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		key, err := parsePemPrivateKey(block, password)
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			return key, nil
+		}
+	}
+
+	// not PEM encoded, so try the DER encodings in turn
+	if key, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := decryptPkcs8PrivateKey(data, password); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no supported private key was found")
+}
+
+// parsePemPrivateKey parses a PEM block containing a private key, returning nil when the block is not a private key
+func parsePemPrivateKey(block *pem.Block, password string) (crypto.PrivateKey, error) {
+	if _, encrypted := block.Headers["Proc-Type"]; encrypted {
+		return nil, fmt.Errorf("legacy encrypted PEM private keys are not supported, please convert the key to an encrypted PKCS#8 key")
+	}
+
+	var key crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		key, err = decryptPkcs8PrivateKey(block.Bytes, password)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", block.Type, err)
+	}
+	return key, nil
+}
+
+// validateCertificateKey ensures that the private key is supported and matches the public key in the certificate
+func validateCertificateKey(cert *x509.Certificate, key crypto.PrivateKey) error {
+	signer, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return fmt.Errorf("unsupported private key type %T, only RSA and ECDSA keys are supported", key)
+	}
+	if publicKey, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !publicKey.Equal(signer.Public()) {
+		return fmt.Errorf("the private key does not match the certificate")
+	}
+	return nil
+}
+
+var (
+	oidPbes2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPbkdf2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHmacSha1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHmacSha256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHmacSha384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHmacSha512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAes128Cbc  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAes192Cbc  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAes256Cbc  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDesEde3Cbc = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+// encryptedPrivateKeyInfo is defined in RFC 5208 section 6
+type encryptedPrivateKeyInfo struct {
+	EncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+// pbes2Params is defined in RFC 8018 appendix A.4
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is defined in RFC 8018 appendix A.2
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	Prf            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPkcs8PrivateKey decrypts a PKCS#8 private key encrypted using PBES2, as produced by e.g. `openssl pkcs8`
+func decryptPkcs8PrivateKey(data []byte, password string) (crypto.PrivateKey, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("parsing encrypted private key: %v", err)
+	}
+	if !info.EncryptionAlgorithm.Algorithm.Equal(oidPbes2) {
+		return nil, fmt.Errorf("unsupported encryption algorithm %s, only PBES2 is supported", info.EncryptionAlgorithm.Algorithm)
+	}
+	if password == "" {
+		return nil, fmt.Errorf("the private key is encrypted, but no password was specified")
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.EncryptionAlgorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("parsing PBES2 parameters: %v", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPbkdf2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, fmt.Errorf("parsing PBKDF2 parameters: %v", err)
+	}
+
+	var prf func() hash.Hash
+	switch prfOid := kdfParams.Prf.Algorithm; {
+	case len(prfOid) == 0 || prfOid.Equal(oidHmacSha1):
+		prf = sha1.New
+	case prfOid.Equal(oidHmacSha256):
+		prf = sha256.New
+	case prfOid.Equal(oidHmacSha384):
+		prf = sha512.New384
+	case prfOid.Equal(oidHmacSha512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 pseudorandom function %s", prfOid)
+	}
+
+	var keyLength int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAes128Cbc):
+		keyLength, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAes192Cbc):
+		keyLength, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAes256Cbc):
+		keyLength, newCipher = 32, aes.NewCipher
+	case scheme.Equal(oidDesEde3Cbc):
+		keyLength, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme %s", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("parsing encryption scheme parameters: %v", err)
+	}
+
+	key := pbkdf2.Key([]byte(password), kdfParams.Salt, kdfParams.IterationCount, keyLength, prf)
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("invalid encrypted private key")
+	}
+
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+	// remove the PKCS#7 padding, an invalid padding most likely indicates an incorrect password
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > block.BlockSize() || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("decrypting private key: the password may be incorrect")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(plaintext[:len(plaintext)-padding])
+	if err != nil {
+		return nil, fmt.Errorf("decrypting private key: the password may be incorrect: %v", err)
+	}
+	return privateKey, nil
+}
@@ -5,10 +5,13 @@ package auth
 
 import (
 	"context"
+	"crypto"
+	"crypto/x509"
 	"fmt"
 	"os"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"software.sslmate.com/src/go-pkcs12"
 )
 
@@ -38,22 +41,46 @@ type ClientCertificateAuthorizerOptions struct {
 
 	// Pkcs12Pass is the challenge passphrase to decrypt the PKCS#12 archive
 	Pkcs12Pass string
+
+	// CertificateData is the PEM or DER encoded certificate, optionally followed by any intermediate certificates.
+	// PEM data may also contain the private key. Used when no PKCS#12 archive is specified.
+	CertificateData []byte
+
+	// CertificatePath is a path to a PEM or DER encoded certificate on the filesystem
+	CertificatePath string
+
+	// PrivateKeyData is the PEM or DER encoded private key, when not contained in CertificateData. RSA and ECDSA keys
+	// are supported, in PKCS#8 (optionally encrypted using PBES2), PKCS#1 or SEC 1 format.
+	PrivateKeyData []byte
+
+	// PrivateKeyPath is a path to a PEM or DER encoded private key on the filesystem
+	PrivateKeyPath string
+
+	// PrivateKeyPass is the passphrase to decrypt an encrypted PKCS#8 private key
+	PrivateKeyPass string
+
+	// SendCertificateChain specifies whether to send the certificate chain in the `x5c` header of the client
+	// assertion, which is required for subject name/issuer (SN+I) authentication
+	SendCertificateChain bool
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
 }
 
 // NewClientCertificateAuthorizer returns an authorizer which uses client certificate authentication.
 func NewClientCertificateAuthorizer(ctx context.Context, options ClientCertificateAuthorizerOptions) (Authorizer, error) {
-	if len(options.Pkcs12Data) == 0 {
-		var err error
-		options.Pkcs12Data, err = os.ReadFile(options.Pkcs12Path)
-		if err != nil {
-			return nil, fmt.Errorf("could not read PKCS#12 archive at %q: %s", options.Pkcs12Path, err)
-		}
-	}
-
-	// we aren't interested in the issuer chain, but we use the DecodeChain method to parse them out in case they are present
-	key, cert, _, err := pkcs12.DecodeChain(options.Pkcs12Data, options.Pkcs12Pass)
+	key, chain, err := options.loadCertificate()
 	if err != nil {
-		return nil, fmt.Errorf("could not decode PKCS#12 archive: %s", err)
+		return nil, err
 	}
 
 	scope, err := environments.Scope(options.Api)
@@ -62,15 +89,97 @@ func NewClientCertificateAuthorizer(ctx context.Context, options ClientCertifica
 	}
 
 	conf := clientCredentialsConfig{
-		Environment:        options.Environment,
-		TenantID:           options.TenantId,
-		AuxiliaryTenantIDs: options.AuxTenantIds,
-		ClientID:           options.ClientId,
-		PrivateKey:         key,
-		Certificate:        cert,
+		Environment:          options.Environment,
+		TenantID:             options.TenantId,
+		AuxiliaryTenantIDs:   options.AuxTenantIds,
+		ClientID:             options.ClientId,
+		PrivateKey:           key,
+		Certificate:          chain[0],
+		CertificateChain:     chain,
+		SendCertificateChain: options.SendCertificateChain,
+		Logger:               options.Logger,
+		ClientCapabilities:   clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities),
 		Scopes: []string{
 			*scope,
 		},
 	}
 	return conf.TokenSource(ctx, clientCredentialsAssertionType)
 }
+
+// setCertificate configures either a PKCS#12 archive or a PEM/DER encoded certificate, depending on the format of the
+// specified data or file
+func (o *ClientCertificateAuthorizerOptions) setCertificate(data []byte, path, password string) error {
+	if len(data) == 0 {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("could not read certificate at %q: %s", path, err)
+		}
+	}
+	if isPemOrDerCertificate(data) {
+		o.CertificateData = data
+		o.PrivateKeyPass = password
+	} else {
+		o.Pkcs12Data = data
+		o.Pkcs12Pass = password
+	}
+	return nil
+}
+
+// loadCertificate returns the private key and the certificate chain, starting with the client certificate, from
+// either the PKCS#12 archive or the PEM/DER encoded certificate and private key
+func (o ClientCertificateAuthorizerOptions) loadCertificate() (crypto.PrivateKey, []*x509.Certificate, error) {
+	if len(o.Pkcs12Data) == 0 && o.Pkcs12Path == "" && (len(o.CertificateData) > 0 || o.CertificatePath != "") {
+		return o.loadPemOrDerCertificate()
+	}
+
+	if len(o.Pkcs12Data) == 0 {
+		var err error
+		o.Pkcs12Data, err = os.ReadFile(o.Pkcs12Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read PKCS#12 archive at %q: %s", o.Pkcs12Path, err)
+		}
+	}
+
+	key, cert, caCerts, err := pkcs12.DecodeChain(o.Pkcs12Data, o.Pkcs12Pass)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode PKCS#12 archive: %s", err)
+	}
+	return key, append([]*x509.Certificate{cert}, caCerts...), nil
+}
+
+func (o ClientCertificateAuthorizerOptions) loadPemOrDerCertificate() (crypto.PrivateKey, []*x509.Certificate, error) {
+	if len(o.CertificateData) == 0 {
+		var err error
+		o.CertificateData, err = os.ReadFile(o.CertificatePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read certificate at %q: %s", o.CertificatePath, err)
+		}
+	}
+	chain, key, err := parseCertificates(o.CertificateData, o.PrivateKeyPass)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse certificate: %s", err)
+	}
+
+	if len(o.PrivateKeyData) == 0 && o.PrivateKeyPath != "" {
+		o.PrivateKeyData, err = os.ReadFile(o.PrivateKeyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read private key at %q: %s", o.PrivateKeyPath, err)
+		}
+	}
+	if len(o.PrivateKeyData) > 0 {
+		if key != nil {
+			return nil, nil, fmt.Errorf("a private key was specified, but the certificate data also contains a private key")
+		}
+		if key, err = parsePrivateKey(o.PrivateKeyData, o.PrivateKeyPass); err != nil {
+			return nil, nil, fmt.Errorf("could not parse private key: %s", err)
+		}
+	}
+	if key == nil {
+		return nil, nil, fmt.Errorf("no private key was found, please specify the private key or include it with the certificate")
+	}
+
+	if err = validateCertificateKey(chain[0], key); err != nil {
+		return nil, nil, fmt.Errorf("invalid client certificate: %s", err)
+	}
+	return key, chain, nil
+}
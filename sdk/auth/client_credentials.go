@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
@@ -23,6 +24,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"github.com/hashicorp/go-uuid"
 	"golang.org/x/oauth2"
 )
@@ -69,6 +71,13 @@ type clientCredentialsConfig struct {
 	// for the application with which you are authenticating. Used when FederatedAssertion is empty.
 	Certificate *x509.Certificate
 
+	// CertificateChain contains the client certificate followed by any intermediate certificates
+	CertificateChain []*x509.Certificate
+
+	// SendCertificateChain specifies whether CertificateChain is sent in the `x5c` header of the client assertion,
+	// which is required for subject name/issuer (SN+I) authentication
+	SendCertificateChain bool
+
 	// FederatedAssertion contains a JWT provided by a trusted third-party vendor
 	// for obtaining an access token with a federated credential. When empty, an
 	// assertion will be created and signed using the specified PrivateKey and Certificate
@@ -84,27 +93,57 @@ type clientCredentialsConfig struct {
 	// request.  If empty, the value of TokenURL is used as the
 	// intended audience.
 	Audience string
+
+	// ClientCapabilities are advertised to the authorization service when requesting tokens
+	ClientCapabilities []string
+
+	// Logger is used when obtaining tokens
+	Logger logging.Logger
 }
 
 // TokenSource provides a source for obtaining access tokens using ClientAssertionAuthorizer or ClientSecretAuthorizer.
 func (c *clientCredentialsConfig) TokenSource(_ context.Context, authType clientCredentialsType) (Authorizer, error) {
 	switch authType {
 	case clientCredentialsAssertionType:
-		return NewCachedAuthorizer(&ClientAssertionAuthorizer{
-			conf: c,
-		})
+		return &CachedAuthorizer{
+			Source: &ClientAssertionAuthorizer{
+				conf: c,
+			},
+			Logger: c.Logger,
+		}, nil
 	case clientCredentialsSecretType:
-		return NewCachedAuthorizer(&ClientSecretAuthorizer{
-			conf: c,
-		})
+		return &CachedAuthorizer{
+			Source: &ClientSecretAuthorizer{
+				conf: c,
+			},
+			Logger: c.Logger,
+		}, nil
 	}
 	return nil, fmt.Errorf("internal-error: unimplemented authType %q", string(authType))
 }
 
+// tokenCacheKey identifies the tokens obtained using the client credentials, so that they can be persisted in a TokenCache
+func (c *clientCredentialsConfig) tokenCacheKey() (TokenCacheKey, bool) {
+	authority := c.TokenURL
+	if authority == "" {
+		if c.Environment.Authorization == nil {
+			return TokenCacheKey{}, false
+		}
+		authority = c.Environment.Authorization.LoginEndpoint
+	}
+	return TokenCacheKey{
+		Authority: authority,
+		TenantId:  c.TenantID,
+		ClientId:  c.ClientID,
+		Scope:     strings.Join(c.Scopes, " "),
+	}, true
+}
+
 type clientAssertionTokenHeader struct {
-	Algorithm string `json:"alg"`
-	Type      string `json:"typ"`
-	KeyId     string `json:"kid"`
+	Algorithm string   `json:"alg"`
+	Type      string   `json:"typ"`
+	KeyId     string   `json:"kid"`
+	X5c       []string `json:"x5c,omitempty"`
 }
 
 func (h *clientAssertionTokenHeader) encode() (string, error) {
@@ -154,9 +193,30 @@ func (c *clientAssertionToken) encode(key crypto.PrivateKey) (*string, error) {
 
 	// determine algorithm and signing function, fail for unsupported keys
 	if k, ok := key.(*ecdsa.PrivateKey); ok {
-		c.header.Algorithm = "ES256"
+		switch k.Curve {
+		case elliptic.P256():
+			c.header.Algorithm = "ES256"
+		case elliptic.P384():
+			c.header.Algorithm = "ES384"
+			hash = crypto.SHA384
+		case elliptic.P521():
+			c.header.Algorithm = "ES512"
+			hash = crypto.SHA512
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve for ECDSA key: %s", k.Curve.Params().Name)
+		}
 		sign = func(data []byte, sum []byte) ([]byte, error) {
-			return ecdsa.SignASN1(rand.Reader, k, sum)
+			r, s, err := ecdsa.Sign(rand.Reader, k, sum)
+			if err != nil {
+				return nil, err
+			}
+
+			// JWS signatures are the fixed-width concatenation of R and S, each padded to the size of the curve
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig := make([]byte, 2*size)
+			r.FillBytes(sig[:size])
+			s.FillBytes(sig[size:])
+			return sig, nil
 		}
 	} else if k, ok := key.(*rsa.PrivateKey); ok {
 		c.header.Algorithm = "RS256"
@@ -215,10 +275,22 @@ func (a *ClientAssertionAuthorizer) assertion(tokenUrl string) (*string, error)
 		audience = tokenUrl
 	}
 
+	var x5c []string
+	if a.conf.SendCertificateChain {
+		chain := a.conf.CertificateChain
+		if len(chain) == 0 {
+			chain = []*x509.Certificate{a.conf.Certificate}
+		}
+		for _, cert := range chain {
+			x5c = append(x5c, base64.StdEncoding.EncodeToString(cert.Raw))
+		}
+	}
+
 	t := clientAssertionToken{
 		header: clientAssertionTokenHeader{
 			Type:  "JWT",
 			KeyId: keyId,
+			X5c:   x5c,
 		},
 		claims: clientAssertionTokenClaims{
 			Audience: audience,
@@ -263,7 +335,7 @@ func (a *ClientAssertionAuthorizer) token(ctx context.Context, tokenUrl string)
 		},
 	}
 
-	return clientCredentialsToken(ctx, tokenUrl, &v)
+	return clientCredentialsToken(ctx, tokenUrl, &v, a.conf.ClientCapabilities)
 }
 
 func (a *ClientAssertionAuthorizer) Token(ctx context.Context, _ *http.Request) (*oauth2.Token, error) {
@@ -282,6 +354,13 @@ func (a *ClientAssertionAuthorizer) Token(ctx context.Context, _ *http.Request)
 	return a.token(ctx, tokenUrl)
 }
 
+func (a *ClientAssertionAuthorizer) tokenCacheKey() (TokenCacheKey, bool) {
+	if a.conf == nil {
+		return TokenCacheKey{}, false
+	}
+	return a.conf.tokenCacheKey()
+}
+
 // AuxiliaryTokens returns additional tokens for auxiliary tenant IDs, for use in multi-tenant scenarios
 func (a *ClientAssertionAuthorizer) AuxiliaryTokens(ctx context.Context, _ *http.Request) ([]*oauth2.Token, error) {
 	if a.conf == nil {
@@ -314,7 +393,21 @@ func (a *ClientAssertionAuthorizer) AuxiliaryTokens(ctx context.Context, _ *http
 	return tokens, nil
 }
 
-func clientCredentialsToken(ctx context.Context, endpoint string, params *url.Values) (*oauth2.Token, error) {
+func clientCredentialsToken(ctx context.Context, endpoint string, params *url.Values, clientCapabilities []string) (*oauth2.Token, error) {
+	claims, err := tokenRequestClaims(ctx, clientCapabilities)
+	if err != nil {
+		return nil, fmt.Errorf("clientCredentialsToken: %v", err)
+	}
+	if claims != "" {
+		// the parameters are copied, since the caller may reuse them for further requests
+		values := url.Values{}
+		for k, v := range *params {
+			values[k] = v
+		}
+		values.Set("claims", claims)
+		params = &values
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer([]byte(params.Encode())))
 	if err != nil {
 		return nil, fmt.Errorf("clientCredentialsToken: failed to build request: %+v", err)
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"golang.org/x/oauth2"
 )
 
@@ -34,6 +35,18 @@ type ClientSecretAuthorizerOptions struct {
 
 	// ClientSecret is the client secret used when authenticating
 	ClientSecret string
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
 }
 
 // NewClientSecretAuthorizer returns an authorizer which uses client secret authentication.
@@ -49,6 +62,8 @@ func NewClientSecretAuthorizer(ctx context.Context, options ClientSecretAuthoriz
 		AuxiliaryTenantIDs: options.AuxTenantIds,
 		ClientID:           options.ClientId,
 		ClientSecret:       options.ClientSecret,
+		Logger:             options.Logger,
+		ClientCapabilities: clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities),
 		Scopes: []string{
 			*scope,
 		},
@@ -86,7 +101,14 @@ func (a *ClientSecretAuthorizer) Token(ctx context.Context, _ *http.Request) (*o
 		tokenUrl = tokenEndpoint(*a.conf.Environment.Authorization, a.conf.TenantID)
 	}
 
-	return clientCredentialsToken(ctx, tokenUrl, &v)
+	return clientCredentialsToken(ctx, tokenUrl, &v, a.conf.ClientCapabilities)
+}
+
+func (a *ClientSecretAuthorizer) tokenCacheKey() (TokenCacheKey, bool) {
+	if a.conf == nil {
+		return TokenCacheKey{}, false
+	}
+	return a.conf.tokenCacheKey()
 }
 
 // AuxiliaryTokens returns additional tokens for auxiliary tenant IDs, for use in multi-tenant scenarios
@@ -108,7 +130,7 @@ func (a *ClientSecretAuthorizer) AuxiliaryTokens(ctx context.Context, _ *http.Re
 			"grant_type":    {"client_credentials"},
 			// NOTE: at this time we only support v2 (MSAL) Tokens since v1 (ADAL) is EOL.
 			"scope": []string{
-				// TODO: given the Request, could we use a dynamic scope?
+				// a scope for each request is supported by MultiResourceAuthorizer
 				strings.Join(a.conf.Scopes, " "),
 			},
 		}
@@ -121,7 +143,7 @@ func (a *ClientSecretAuthorizer) AuxiliaryTokens(ctx context.Context, _ *http.Re
 			tokenUrl = tokenEndpoint(*a.conf.Environment.Authorization, tenantId)
 		}
 
-		token, err := clientCredentialsToken(ctx, tokenUrl, &v)
+		token, err := clientCredentialsToken(ctx, tokenUrl, &v, a.conf.ClientCapabilities)
 		if err != nil {
 			return tokens, err
 		}
@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+)
+
+func TestHttpClient_Logger(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := httpClient(httpClientParams{
+		retryWaitMin: time.Millisecond,
+		retryWaitMax: time.Millisecond,
+	})
+	send := func(ctx context.Context, url string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("building request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// retries are logged using the Logger carried by the request context
+	buf := &bytes.Buffer{}
+	ctx := withLogger(context.Background(), logging.NewStandardLogger(log.New(buf, "", 0), logging.LevelDebug))
+	if err := send(ctx, server.URL); err != nil {
+		t.Fatalf("sending request: %v", err)
+	}
+	for _, expected := range []string{"[DEBUG] performing request", "[DEBUG] retrying request"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %q to be logged, got:\n%s", expected, buf.String())
+		}
+	}
+
+	// as are errors
+	buf.Reset()
+	server.Close()
+	if err := send(ctx, server.URL); err == nil {
+		t.Fatal("expected an error when the server is unavailable")
+	}
+	if !strings.Contains(buf.String(), "[ERROR] request failed") {
+		t.Fatalf("expected the failed request to be logged, got:\n%s", buf.String())
+	}
+}
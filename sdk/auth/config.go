@@ -5,6 +5,7 @@ package auth
 
 import (
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 )
 
 // Credentials sets up NewAuthorizer to return an Authorizer based on the provided credentails.
@@ -24,12 +25,24 @@ type Credentials struct {
 
 	// EnableAuthenticatingUsingClientCertificate specifies whether Client Certificate authentication should be checked.
 	EnableAuthenticatingUsingClientCertificate bool
-	// ClientCertificateData specifies the contents of a Client Certificate PKCS#12 bundle.
+	// ClientCertificateData specifies the contents of a Client Certificate PKCS#12 bundle, or a PEM or DER encoded
+	// certificate. PEM data may also contain the private key.
 	ClientCertificateData []byte
-	// ClientCertificatePath specifies the path to a Client Certificate PKCS#12 bundle (.pfx file)
+	// ClientCertificatePath specifies the path to a Client Certificate PKCS#12 bundle (.pfx file), or a PEM or DER
+	// encoded certificate
 	ClientCertificatePath string
-	// ClientCertificatePassword specifies the encryption password to unlock a Client Certificate.
+	// ClientCertificatePassword specifies the encryption password to unlock a Client Certificate, or its encrypted
+	// PKCS#8 private key.
 	ClientCertificatePassword string
+	// ClientCertificateKeyData specifies the contents of a PEM or DER encoded private key for a PEM or DER encoded
+	// Client Certificate, when not included with the certificate.
+	ClientCertificateKeyData []byte
+	// ClientCertificateKeyPath specifies the path to a PEM or DER encoded private key for a PEM or DER encoded
+	// Client Certificate, when not included with the certificate.
+	ClientCertificateKeyPath string
+	// ClientCertificateSendChain specifies whether to send the certificate chain when authenticating, which is
+	// required for subject name/issuer (SN+I) authentication.
+	ClientCertificateSendChain bool
 
 	// EnableAuthenticatingUsingClientSecret specifies whether Client Secret authentication should be used.
 	EnableAuthenticatingUsingClientSecret bool
@@ -40,16 +53,45 @@ type Credentials struct {
 	EnableAuthenticatingUsingManagedIdentity bool
 	// CustomManagedIdentityEndpoint specifies a custom endpoint which should be used for Managed Identity.
 	CustomManagedIdentityEndpoint string
+	// ManagedIdentityObjectID specifies the object ID of a user-assigned Managed Identity, which is used in place of ClientID.
+	ManagedIdentityObjectID string
+	// ManagedIdentityResourceID specifies the resource ID of a user-assigned Managed Identity, which is used in place of ClientID.
+	ManagedIdentityResourceID string
 
 	// Enables OIDC authentication (federated client credentials).
 	EnableAuthenticationUsingOIDC bool
 	// OIDCAssertionToken specifies the OIDC Assertion Token to authenticate using Client Credentials.
 	OIDCAssertionToken string
 
+	// EnableAuthenticationUsingWorkloadIdentity specifies whether workload identity authentication (federated client
+	// credentials read from a file, such as on AKS) should be checked.
+	EnableAuthenticationUsingWorkloadIdentity bool
+	// WorkloadIdentityFederatedTokenFile specifies the path to a file containing the federated token. Defaults to the
+	// value of the AZURE_FEDERATED_TOKEN_FILE environment variable.
+	WorkloadIdentityFederatedTokenFile string
+
 	// EnableAuthenticationUsingGitHubOIDC specifies whether GitHub OIDC
 	EnableAuthenticationUsingGitHubOIDC bool
 	// GitHubOIDCTokenRequestURL specifies the URL for GitHub's OIDC provider
 	GitHubOIDCTokenRequestURL string
 	// GitHubOIDCTokenRequestToken specifies the bearer token for the request to GitHub's OIDC provider
 	GitHubOIDCTokenRequestToken string
+
+	// TokenCache optionally persists the tokens obtained by the selected Authorizer, e.g. using NewFileTokenCache so
+	// that tokens can be reused between processes. With Azure CLI authentication, tokens are only persisted when the
+	// Azure CLI has a default subscription, which is used to distinguish between signed-in accounts.
+	TokenCache TokenCache
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client. Not
+	// supported with managed identity or Azure CLI authentication.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used by the selected Authorizer when obtaining tokens, which defaults to
+	// logging.Default(). Sensitive values are always redacted.
+	Logger logging.Logger
 }
@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+)
+
+// CredentialsFromEnvironment returns Credentials populated from the standard `ARM_*` and `AZURE_*` environment
+// variables, including the Environment, with each authentication method enabled when its configuration is present.
+// Where both an `ARM_*` and an `AZURE_*` variable are supported for the same setting, they must not conflict.
+//
+// The following environment variables are supported:
+//   - ARM_ENVIRONMENT / AZURE_ENVIRONMENT: the name of the environment, defaults to `public`
+//   - ARM_METADATA_HOSTNAME: the metadata host from which to load the environment, e.g. for Azure Stack
+//   - ARM_TENANT_ID / AZURE_TENANT_ID and ARM_CLIENT_ID / AZURE_CLIENT_ID
+//   - ARM_AUXILIARY_TENANT_IDS: a semicolon separated list of auxiliary tenant IDs
+//   - ARM_CLIENT_SECRET / AZURE_CLIENT_SECRET
+//   - ARM_CLIENT_CERTIFICATE (base64 encoded), ARM_CLIENT_CERTIFICATE_PATH / AZURE_CLIENT_CERTIFICATE_PATH and
+//     ARM_CLIENT_CERTIFICATE_PASSWORD / AZURE_CLIENT_CERTIFICATE_PASSWORD, containing a PKCS#12 bundle or a PEM or
+//     DER encoded certificate
+//   - ARM_CLIENT_CERTIFICATE_KEY_PATH: the private key for a PEM or DER encoded certificate
+//   - ARM_CLIENT_SEND_CERTIFICATE_CHAIN / AZURE_CLIENT_SEND_CERTIFICATE_CHAIN: sends the certificate chain for
+//     subject name/issuer authentication
+//   - ARM_USE_OIDC and ARM_OIDC_TOKEN
+//   - ARM_OIDC_TOKEN_FILE_PATH, which is read each time a token is acquired using workload identity authentication,
+//     so that the token can be rotated
+//   - ARM_OIDC_REQUEST_URL / ACTIONS_ID_TOKEN_REQUEST_URL and ARM_OIDC_REQUEST_TOKEN / ACTIONS_ID_TOKEN_REQUEST_TOKEN
+//   - ARM_USE_AKS_WORKLOAD_IDENTITY and AZURE_FEDERATED_TOKEN_FILE
+//   - ARM_USE_MSI and ARM_MSI_ENDPOINT
+//   - ARM_USE_CLI, which defaults to true
+//
+// A client secret and a client certificate cannot both be specified. Otherwise, when more than one authentication
+// method is configured, the order of precedence is that of NewAuthorizerFromCredentials.
+func CredentialsFromEnvironment(ctx context.Context) (*Credentials, error) {
+	v := environmentVariables{}
+
+	environment, err := v.environment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Credentials{
+		Environment:                   *environment,
+		TenantID:                      v.lookup("ARM_TENANT_ID", "AZURE_TENANT_ID"),
+		ClientID:                      v.lookup("ARM_CLIENT_ID", "AZURE_CLIENT_ID"),
+		ClientSecret:                  v.lookup("ARM_CLIENT_SECRET", "AZURE_CLIENT_SECRET"),
+		ClientCertificatePath:         v.lookup("ARM_CLIENT_CERTIFICATE_PATH", "AZURE_CLIENT_CERTIFICATE_PATH"),
+		ClientCertificatePassword:     v.lookup("ARM_CLIENT_CERTIFICATE_PASSWORD", "AZURE_CLIENT_CERTIFICATE_PASSWORD"),
+		ClientCertificateKeyPath:      v.lookup("ARM_CLIENT_CERTIFICATE_KEY_PATH"),
+		GitHubOIDCTokenRequestURL:     v.lookup("ARM_OIDC_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_URL"),
+		GitHubOIDCTokenRequestToken:   v.lookup("ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN"),
+		CustomManagedIdentityEndpoint: v.lookup("ARM_MSI_ENDPOINT"),
+	}
+
+	if auxTenantIds := v.lookup("ARM_AUXILIARY_TENANT_IDS"); auxTenantIds != "" {
+		for _, tenantId := range strings.Split(auxTenantIds, ";") {
+			if tenantId = strings.TrimSpace(tenantId); tenantId != "" {
+				c.AuxiliaryTenantIDs = append(c.AuxiliaryTenantIDs, tenantId)
+			}
+		}
+	}
+
+	if certificate := v.lookup("ARM_CLIENT_CERTIFICATE"); certificate != "" {
+		if c.ClientCertificatePath != "" {
+			v.errorf("only one of ARM_CLIENT_CERTIFICATE or ARM_CLIENT_CERTIFICATE_PATH can be set")
+		}
+		if c.ClientCertificateData, err = base64.StdEncoding.DecodeString(certificate); err != nil {
+			v.errorf("ARM_CLIENT_CERTIFICATE must be base64 encoded: %+v", err)
+		}
+	}
+
+	c.ClientCertificateSendChain = v.lookupBool(false, "ARM_CLIENT_SEND_CERTIFICATE_CHAIN", "AZURE_CLIENT_SEND_CERTIFICATE_CHAIN")
+
+	c.OIDCAssertionToken = v.lookup("ARM_OIDC_TOKEN")
+
+	// a token file is re-read by the workload identity authorizer, rather than being read once here
+	oidcTokenFilePath := v.lookup("ARM_OIDC_TOKEN_FILE_PATH")
+	if oidcTokenFilePath != "" {
+		if _, err := os.Stat(oidcTokenFilePath); err != nil {
+			v.errorf("reading ARM_OIDC_TOKEN_FILE_PATH: %+v", err)
+		}
+	}
+	c.WorkloadIdentityFederatedTokenFile = v.lookup("ARM_OIDC_TOKEN_FILE_PATH", workloadIdentityFederatedTokenFileEnvVar)
+
+	useOIDC := v.lookupBool(false, "ARM_USE_OIDC")
+	useWorkloadIdentity := v.lookupBool(false, "ARM_USE_AKS_WORKLOAD_IDENTITY")
+	c.EnableAuthenticatingUsingClientSecret = c.ClientSecret != ""
+	c.EnableAuthenticatingUsingClientCertificate = len(c.ClientCertificateData) > 0 || c.ClientCertificatePath != ""
+	c.EnableAuthenticationUsingOIDC = c.OIDCAssertionToken != ""
+	c.EnableAuthenticationUsingGitHubOIDC = useOIDC && c.GitHubOIDCTokenRequestURL != "" && c.GitHubOIDCTokenRequestToken != ""
+	c.EnableAuthenticationUsingWorkloadIdentity = useWorkloadIdentity || oidcTokenFilePath != ""
+	c.EnableAuthenticatingUsingManagedIdentity = v.lookupBool(false, "ARM_USE_MSI")
+	c.EnableAuthenticatingUsingAzureCLI = v.lookupBool(true, "ARM_USE_CLI")
+
+	if useOIDC && !c.EnableAuthenticationUsingOIDC && !c.EnableAuthenticationUsingGitHubOIDC && oidcTokenFilePath == "" {
+		v.errorf("ARM_USE_OIDC is set, but neither ARM_OIDC_TOKEN, ARM_OIDC_TOKEN_FILE_PATH or ARM_OIDC_REQUEST_URL and ARM_OIDC_REQUEST_TOKEN are set")
+	}
+	if useWorkloadIdentity && c.WorkloadIdentityFederatedTokenFile == "" {
+		v.errorf("ARM_USE_AKS_WORKLOAD_IDENTITY is set, but %s is not set", workloadIdentityFederatedTokenFileEnvVar)
+	}
+	if c.EnableAuthenticatingUsingClientSecret && c.EnableAuthenticatingUsingClientCertificate {
+		v.errorf("only one of a client secret or a client certificate can be specified")
+	}
+	if c.EnableAuthenticatingUsingClientSecret || c.EnableAuthenticatingUsingClientCertificate || c.EnableAuthenticationUsingOIDC || c.EnableAuthenticationUsingGitHubOIDC || oidcTokenFilePath != "" {
+		if c.TenantID == "" {
+			v.errorf("ARM_TENANT_ID must be set when authenticating using a service principal")
+		}
+		if c.ClientID == "" {
+			v.errorf("ARM_CLIENT_ID must be set when authenticating using a service principal")
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return nil, fmt.Errorf("loading credentials from environment variables:\n  - %s", strings.Join(v.errors, "\n  - "))
+	}
+
+	return &c, nil
+}
+
+// environmentVariables looks up environment variables, collecting any validation errors
+type environmentVariables struct {
+	errors []string
+}
+
+// environment returns the Environment specified by ARM_ENVIRONMENT / AZURE_ENVIRONMENT, loaded from
+// ARM_METADATA_HOSTNAME when specified
+func (v *environmentVariables) environment(ctx context.Context) (*environments.Environment, error) {
+	name := v.lookup("ARM_ENVIRONMENT", "AZURE_ENVIRONMENT")
+	metadataHost := v.lookup("ARM_METADATA_HOSTNAME")
+	if len(v.errors) > 0 {
+		return nil, fmt.Errorf("loading environment from environment variables: %s", strings.Join(v.errors, ", "))
+	}
+
+	if metadataHost != "" {
+		if !strings.HasPrefix(metadataHost, "https://") && !strings.HasPrefix(metadataHost, "http://") {
+			metadataHost = fmt.Sprintf("https://%s", metadataHost)
+		}
+		environment, err := environments.FromEndpoint(ctx, metadataHost, name)
+		if err != nil {
+			return nil, fmt.Errorf("loading environment from ARM_METADATA_HOSTNAME: %+v", err)
+		}
+		return environment, nil
+	}
+
+	if name == "" {
+		name = "public"
+	}
+	environment, err := environments.FromName(name)
+	if err != nil {
+		return nil, fmt.Errorf("loading environment from ARM_ENVIRONMENT: %+v", err)
+	}
+	return environment, nil
+}
+
+// lookup returns the value of the first of the named environment variables which is set, recording an error if
+// more than one is set with different values
+func (v *environmentVariables) lookup(names ...string) string {
+	value, from := "", ""
+	for _, name := range names {
+		current := strings.TrimSpace(os.Getenv(name))
+		if current == "" {
+			continue
+		}
+		if value == "" {
+			value, from = current, name
+			continue
+		}
+		if current != value {
+			v.errorf("%s and %s are both set, but with different values", from, name)
+		}
+	}
+	return value
+}
+
+// lookupBool parses the named environment variables as a boolean, returning defaultValue when none are set
+func (v *environmentVariables) lookupBool(defaultValue bool, names ...string) bool {
+	value := v.lookup(names...)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		v.errorf("%s must be a boolean, got %q", strings.Join(names, " / "), value)
+		return defaultValue
+	}
+	return b
+}
+
+func (v *environmentVariables) errorf(format string, a ...interface{}) {
+	v.errors = append(v.errors, fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,219 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+)
+
+var credentialsEnvironmentVariables = []string{
+	"ARM_ENVIRONMENT", "AZURE_ENVIRONMENT", "ARM_METADATA_HOSTNAME",
+	"ARM_TENANT_ID", "AZURE_TENANT_ID", "ARM_CLIENT_ID", "AZURE_CLIENT_ID", "ARM_AUXILIARY_TENANT_IDS",
+	"ARM_CLIENT_SECRET", "AZURE_CLIENT_SECRET",
+	"ARM_CLIENT_CERTIFICATE", "ARM_CLIENT_CERTIFICATE_PATH", "AZURE_CLIENT_CERTIFICATE_PATH", "ARM_CLIENT_CERTIFICATE_PASSWORD", "AZURE_CLIENT_CERTIFICATE_PASSWORD",
+	"ARM_CLIENT_CERTIFICATE_KEY_PATH", "ARM_CLIENT_SEND_CERTIFICATE_CHAIN", "AZURE_CLIENT_SEND_CERTIFICATE_CHAIN",
+	"ARM_USE_OIDC", "ARM_OIDC_TOKEN", "ARM_OIDC_TOKEN_FILE_PATH",
+	"ARM_OIDC_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_URL", "ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN",
+	"ARM_USE_AKS_WORKLOAD_IDENTITY", "AZURE_FEDERATED_TOKEN_FILE",
+	"ARM_USE_MSI", "ARM_MSI_ENDPOINT", "ARM_USE_CLI",
+}
+
+func TestCredentialsFromEnvironment(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("idtoken\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		env         map[string]string
+		shouldError string
+		check       func(t *testing.T, c *auth.Credentials)
+	}{
+		{
+			name: "Client Secret",
+			env: map[string]string{
+				"ARM_ENVIRONMENT":          "usgovernment",
+				"ARM_TENANT_ID":            "00000000-1111-0000-0000-000000000000",
+				"AZURE_TENANT_ID":          "00000000-1111-0000-0000-000000000000",
+				"AZURE_CLIENT_ID":          "11111111-0000-0000-0000-000000000000",
+				"ARM_CLIENT_SECRET":        "supersecret",
+				"ARM_AUXILIARY_TENANT_IDS": "00000000-2222-0000-0000-000000000000;00000000-3333-0000-0000-000000000000",
+				"ARM_USE_CLI":              "false",
+			},
+			check: func(t *testing.T, c *auth.Credentials) {
+				if c.Environment.Name != "USGovernment" {
+					t.Fatalf("unexpected environment %q", c.Environment.Name)
+				}
+				if c.ClientID != "11111111-0000-0000-0000-000000000000" || c.ClientSecret != "supersecret" || !c.EnableAuthenticatingUsingClientSecret {
+					t.Fatalf("client secret authentication was not configured")
+				}
+				if len(c.AuxiliaryTenantIDs) != 2 {
+					t.Fatalf("expected 2 auxiliary tenants, got %d", len(c.AuxiliaryTenantIDs))
+				}
+				if c.EnableAuthenticatingUsingAzureCLI || c.EnableAuthenticatingUsingClientCertificate {
+					t.Fatalf("unexpected authentication methods enabled")
+				}
+			},
+		},
+		{
+			name: "Client Certificate",
+			env: map[string]string{
+				"ARM_TENANT_ID":                       "00000000-1111-0000-0000-000000000000",
+				"ARM_CLIENT_ID":                       "11111111-0000-0000-0000-000000000000",
+				"ARM_CLIENT_CERTIFICATE":              base64.StdEncoding.EncodeToString([]byte("certificate")),
+				"ARM_CLIENT_CERTIFICATE_PASSWORD":     "password",
+				"ARM_CLIENT_CERTIFICATE_KEY_PATH":     "/path/to/key",
+				"AZURE_CLIENT_SEND_CERTIFICATE_CHAIN": "true",
+			},
+			check: func(t *testing.T, c *auth.Credentials) {
+				if string(c.ClientCertificateData) != "certificate" || c.ClientCertificatePassword != "password" || !c.EnableAuthenticatingUsingClientCertificate {
+					t.Fatalf("client certificate authentication was not configured")
+				}
+				if c.ClientCertificateKeyPath != "/path/to/key" || !c.ClientCertificateSendChain {
+					t.Fatalf("expected the private key path and sending the certificate chain to be configured")
+				}
+				if c.Environment.Name != "Public" || !c.EnableAuthenticatingUsingAzureCLI {
+					t.Fatalf("expected the public environment and Azure CLI to be used by default")
+				}
+			},
+		},
+		{
+			name: "OIDC Token File",
+			env: map[string]string{
+				"ARM_TENANT_ID":            "00000000-1111-0000-0000-000000000000",
+				"ARM_CLIENT_ID":            "11111111-0000-0000-0000-000000000000",
+				"ARM_USE_OIDC":             "true",
+				"ARM_OIDC_TOKEN_FILE_PATH": tokenFile,
+			},
+			check: func(t *testing.T, c *auth.Credentials) {
+				// the token file is read by the workload identity authorizer each time a token is acquired
+				if !c.EnableAuthenticationUsingWorkloadIdentity || c.WorkloadIdentityFederatedTokenFile != tokenFile {
+					t.Fatalf("workload identity authentication was not configured using the token file")
+				}
+				if c.OIDCAssertionToken != "" || c.EnableAuthenticationUsingOIDC || c.EnableAuthenticationUsingGitHubOIDC {
+					t.Fatalf("expected the token file not to be read into a static assertion")
+				}
+			},
+		},
+		{
+			name: "Missing OIDC Token File",
+			env: map[string]string{
+				"ARM_TENANT_ID":            "00000000-1111-0000-0000-000000000000",
+				"ARM_CLIENT_ID":            "11111111-0000-0000-0000-000000000000",
+				"ARM_OIDC_TOKEN_FILE_PATH": filepath.Join(t.TempDir(), "missing"),
+			},
+			shouldError: "reading ARM_OIDC_TOKEN_FILE_PATH",
+		},
+		{
+			name: "GitHub OIDC",
+			env: map[string]string{
+				"ARM_TENANT_ID":                  "00000000-1111-0000-0000-000000000000",
+				"ARM_CLIENT_ID":                  "11111111-0000-0000-0000-000000000000",
+				"ARM_USE_OIDC":                   "true",
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   "https://githubtokenendpoint",
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "githubtoken",
+			},
+			check: func(t *testing.T, c *auth.Credentials) {
+				if !c.EnableAuthenticationUsingGitHubOIDC || c.GitHubOIDCTokenRequestURL != "https://githubtokenendpoint" {
+					t.Fatalf("GitHub OIDC authentication was not configured")
+				}
+			},
+		},
+		{
+			name: "Managed Identity and Workload Identity",
+			env: map[string]string{
+				"ARM_USE_MSI":                   "true",
+				"ARM_MSI_ENDPOINT":              "http://localhost:8080",
+				"ARM_USE_AKS_WORKLOAD_IDENTITY": "true",
+				"AZURE_FEDERATED_TOKEN_FILE":    tokenFile,
+			},
+			check: func(t *testing.T, c *auth.Credentials) {
+				if !c.EnableAuthenticatingUsingManagedIdentity || c.CustomManagedIdentityEndpoint != "http://localhost:8080" {
+					t.Fatalf("managed identity authentication was not configured")
+				}
+				if !c.EnableAuthenticationUsingWorkloadIdentity || c.WorkloadIdentityFederatedTokenFile != tokenFile {
+					t.Fatalf("workload identity authentication was not configured")
+				}
+			},
+		},
+		{
+			name: "Conflicting Client IDs",
+			env: map[string]string{
+				"ARM_CLIENT_ID":   "11111111-0000-0000-0000-000000000000",
+				"AZURE_CLIENT_ID": "22222222-0000-0000-0000-000000000000",
+			},
+			shouldError: "ARM_CLIENT_ID and AZURE_CLIENT_ID are both set, but with different values",
+		},
+		{
+			name: "Conflicting Certificates",
+			env: map[string]string{
+				"ARM_TENANT_ID":               "00000000-1111-0000-0000-000000000000",
+				"ARM_CLIENT_ID":               "11111111-0000-0000-0000-000000000000",
+				"ARM_CLIENT_CERTIFICATE":      base64.StdEncoding.EncodeToString([]byte("certificate")),
+				"ARM_CLIENT_CERTIFICATE_PATH": "/path/to/cert",
+			},
+			shouldError: "only one of ARM_CLIENT_CERTIFICATE or ARM_CLIENT_CERTIFICATE_PATH can be set",
+		},
+		{
+			name: "Client Secret and Certificate",
+			env: map[string]string{
+				"ARM_TENANT_ID":               "00000000-1111-0000-0000-000000000000",
+				"ARM_CLIENT_ID":               "11111111-0000-0000-0000-000000000000",
+				"ARM_CLIENT_SECRET":           "secret",
+				"ARM_CLIENT_CERTIFICATE_PATH": "/path/to/cert",
+			},
+			shouldError: "only one of a client secret or a client certificate can be specified",
+		},
+		{
+			name: "Missing Tenant",
+			env: map[string]string{
+				"ARM_CLIENT_ID":     "11111111-0000-0000-0000-000000000000",
+				"ARM_CLIENT_SECRET": "supersecret",
+			},
+			shouldError: "ARM_TENANT_ID must be set",
+		},
+		{
+			name: "Invalid Boolean",
+			env: map[string]string{
+				"ARM_USE_MSI": "sometimes",
+			},
+			shouldError: "ARM_USE_MSI must be a boolean",
+		},
+		{
+			name: "Unknown Environment",
+			env: map[string]string{
+				"ARM_ENVIRONMENT": "mars",
+			},
+			shouldError: "no environment was found",
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.name, func(t *testing.T) {
+			for _, name := range credentialsEnvironmentVariables {
+				t.Setenv(name, v.env[name])
+			}
+
+			c, err := auth.CredentialsFromEnvironment(context.Background())
+			if v.shouldError != "" {
+				if err == nil || !strings.Contains(err.Error(), v.shouldError) {
+					t.Fatalf("expected an error containing %q, got: %v", v.shouldError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CredentialsFromEnvironment(): %v", err)
+			}
+			v.check(t, c)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+	"golang.org/x/oauth2"
+)
+
+type DeviceCodeAuthorizerOptions struct {
+	// Environment is the Azure environment/cloud being targeted
+	Environment environments.Environment
+
+	// Api describes the Azure API being used
+	Api environments.Api
+
+	// TenantId is the tenant to authenticate against, defaults to `organizations`
+	TenantId string
+
+	// AuxiliaryTenantIds lists additional tenants to authenticate against, currently only
+	// used for Resource Manager when auxiliary tenants are needed.
+	// e.g. https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/authenticate-multi-tenant
+	AuxiliaryTenantIds []string
+
+	// ClientId is the client ID of the public client application used when authenticating, defaults to the Azure CLI
+	ClientId string
+
+	// Prompt is called with the device code which the user should enter to authenticate. When nil, the message
+	// is written to PromptWriter.
+	Prompt func(ctx context.Context, code DeviceCode) error
+
+	// PromptWriter optionally specifies where the message containing the device code is written when no Prompt is
+	// specified. When nil, the message is logged at the Info level using Logger.
+	PromptWriter io.Writer
+
+	// TokenCache optionally persists the tokens obtained for the user, including the refresh token, so that the user
+	// is not prompted again by other processes, e.g. when using NewFileTokenCache
+	TokenCache TokenCache
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
+}
+
+// DeviceCode describes the code which the user should enter at the verification URI to authenticate
+type DeviceCode struct {
+	// UserCode is the code which the user should enter
+	UserCode string
+
+	// VerificationUri is the URI at which the user should enter the code
+	VerificationUri string
+
+	// ExpiresOn is the time at which the code expires
+	ExpiresOn time.Time
+
+	// Message contains instructions for the user, as returned by the authorization service
+	Message string
+}
+
+// NewDeviceCodeAuthorizer returns an authorizer which authenticates a user using the device code flow, prompting the
+// user to enter a code on another device. Once authenticated, the refresh token is used to obtain further tokens.
+func NewDeviceCodeAuthorizer(ctx context.Context, options DeviceCodeAuthorizerOptions) (Authorizer, error) {
+	conf, err := newPublicClientConfig(options.Environment, options.Api, options.TenantId, options.AuxiliaryTenantIds, options.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	conf.ClientCapabilities = clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities)
+
+	a := &DeviceCodeAuthorizer{
+		refreshableTokenSource: refreshableTokenSource{
+			conf: conf,
+		},
+		prompt: options.Prompt,
+	}
+	a.acquire = a.deviceCodeToken
+	if a.prompt == nil {
+		a.prompt = defaultDeviceCodePrompt(options.PromptWriter)
+	}
+
+	return &CachedAuthorizer{
+		Source: a,
+		Cache:  options.TokenCache,
+		Logger: options.Logger,
+	}, nil
+}
+
+// defaultDeviceCodePrompt returns a prompt which writes the message to w, or logs it when w is nil
+func defaultDeviceCodePrompt(w io.Writer) func(ctx context.Context, code DeviceCode) error {
+	return func(ctx context.Context, code DeviceCode) error {
+		if w == nil {
+			loggerFromContext(ctx).Info(code.Message)
+			return nil
+		}
+		_, err := fmt.Fprintln(w, code.Message)
+		return err
+	}
+}
+
+var _ Authorizer = &DeviceCodeAuthorizer{}
+
+// DeviceCodeAuthorizer is an Authorizer which authenticates a user using the device code flow
+type DeviceCodeAuthorizer struct {
+	refreshableTokenSource
+
+	prompt func(ctx context.Context, code DeviceCode) error
+}
+
+// defaultDeviceCodeInterval is the interval at which to poll for the token when not specified by the authorization service
+const defaultDeviceCodeInterval = 5 * time.Second
+
+// deviceCodeToken requests a device code, prompts the user, then polls until the user has authenticated
+func (a *DeviceCodeAuthorizer) deviceCodeToken(ctx context.Context) (*oauth2.Token, error) {
+	var codeRes struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationUri string `json:"verification_uri"`
+		ExpiresIn       int64  `json:"expires_in"`
+		Interval        *int64 `json:"interval"`
+		Message         string `json:"message"`
+	}
+	params := url.Values{
+		"client_id": {a.conf.ClientID},
+		"scope":     {a.conf.scope()},
+	}
+	if err := publicClientRequest(ctx, a.conf.endpoint(a.conf.TenantID, "devicecode"), params, &codeRes); err != nil {
+		return nil, fmt.Errorf("DeviceCodeAuthorizer: requesting device code: %v", err)
+	}
+
+	code := DeviceCode{
+		UserCode:        codeRes.UserCode,
+		VerificationUri: codeRes.VerificationUri,
+		ExpiresOn:       time.Now().Add(time.Duration(codeRes.ExpiresIn) * time.Second),
+		Message:         codeRes.Message,
+	}
+	if code.Message == "" {
+		code.Message = fmt.Sprintf("To sign in, use a web browser to open the page %s and enter the code %s to authenticate.", code.VerificationUri, code.UserCode)
+	}
+	if err := a.prompt(ctx, code); err != nil {
+		return nil, fmt.Errorf("DeviceCodeAuthorizer: prompting user: %v", err)
+	}
+
+	interval := defaultDeviceCodeInterval
+	if codeRes.Interval != nil {
+		interval = time.Duration(*codeRes.Interval) * time.Second
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("DeviceCodeAuthorizer: waiting for user to authenticate: %v", ctx.Err())
+		case <-time.After(interval):
+		}
+		if time.Now().After(code.ExpiresOn) {
+			return nil, fmt.Errorf("DeviceCodeAuthorizer: the device code expired before the user authenticated")
+		}
+
+		token, err := a.conf.publicClientToken(ctx, a.conf.TenantID, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {codeRes.DeviceCode},
+		})
+		if err == nil {
+			return token, nil
+		}
+
+		var e *publicClientError
+		if !errors.As(err, &e) {
+			return nil, fmt.Errorf("DeviceCodeAuthorizer: requesting token: %v", err)
+		}
+		switch e.Code {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		}
+		return nil, fmt.Errorf("DeviceCodeAuthorizer: requesting token: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+)
+
+func TestDeviceCodeAuthorizer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	var prompted []auth.DeviceCode
+	opts := auth.DeviceCodeAuthorizerOptions{
+		Environment:        env,
+		Api:                env.ResourceManager,
+		TenantId:           "00000000-1111-0000-0000-000000000000",
+		AuxiliaryTenantIds: []string{"00000000-2222-0000-0000-000000000000"},
+		Prompt: func(_ context.Context, code auth.DeviceCode) error {
+			prompted = append(prompted, code)
+			return nil
+		},
+	}
+
+	authorizer, err := auth.NewDeviceCodeAuthorizer(ctx, opts)
+	if err != nil {
+		t.Fatalf("NewDeviceCodeAuthorizer(): %v", err)
+	}
+
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+	if len(prompted) != 1 || prompted[0].UserCode != "ABCD1234" || !strings.Contains(prompted[0].Message, "ABCD1234") {
+		t.Fatalf("expected the user to be prompted once with the user code, got %+v", prompted)
+	}
+
+	// obtaining further tokens should use the refresh token without prompting the user
+	source := authorizer.(*auth.CachedAuthorizer).Source
+	if _, err = testObtainAccessToken(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	auxTokens, err := authorizer.AuxiliaryTokens(ctx, nil)
+	if err != nil {
+		t.Fatalf("authorizer.AuxiliaryTokens(): %v", err)
+	}
+	if len(auxTokens) != 1 {
+		t.Fatalf("expected 1 auxiliary token, got %d", len(auxTokens))
+	}
+	if len(prompted) != 1 {
+		t.Fatalf("expected the user to be prompted once, but was prompted %d times", len(prompted))
+	}
+
+	expectedGrants := []string{
+		"urn:ietf:params:oauth:grant-type:device_code",
+		"urn:ietf:params:oauth:grant-type:device_code",
+		"refresh_token",
+		"refresh_token",
+	}
+	if strings.Join(server.grants, ",") != strings.Join(expectedGrants, ",") {
+		t.Fatalf("expected the grants %v, got %v", expectedGrants, server.grants)
+	}
+	if server.tenants[3] != "00000000-2222-0000-0000-000000000000" {
+		t.Fatalf("expected the auxiliary token to be requested from the auxiliary tenant, got %q", server.tenants[3])
+	}
+}
+
+func TestDeviceCodeAuthorizer_Logger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	// when no Prompt is specified, the message is logged using the Logger for the authorizer
+	buf := &bytes.Buffer{}
+	authorizer, err := auth.NewDeviceCodeAuthorizer(ctx, auth.DeviceCodeAuthorizerOptions{
+		Environment: env,
+		Api:         env.ResourceManager,
+		TenantId:    "00000000-1111-0000-0000-000000000000",
+		Logger:      logging.NewStandardLogger(log.New(buf, "", 0), logging.LevelDebug),
+	})
+	if err != nil {
+		t.Fatalf("NewDeviceCodeAuthorizer(): %v", err)
+	}
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "ABCD1234") {
+		t.Fatalf("expected the device code to be logged, got:\n%s", buf.String())
+	}
+
+	// the message can instead be written to a PromptWriter
+	prompt := &bytes.Buffer{}
+	authorizer, err = auth.NewDeviceCodeAuthorizer(ctx, auth.DeviceCodeAuthorizerOptions{
+		Environment:  env,
+		Api:          env.ResourceManager,
+		TenantId:     "00000000-1111-0000-0000-000000000000",
+		PromptWriter: prompt,
+		Logger:       logging.Discard(),
+	})
+	if err != nil {
+		t.Fatalf("NewDeviceCodeAuthorizer(): %v", err)
+	}
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt.String(), "ABCD1234") {
+		t.Fatalf("expected the device code to be written to the PromptWriter, got %q", prompt.String())
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// fileTokenCacheHeader identifies the format of the cache file
+	fileTokenCacheHeader = "go-azure-sdk-token-cache-v1\n"
+
+	// fileTokenCacheLockRetryInterval is the interval at which to retry acquiring the lock file
+	fileTokenCacheLockRetryInterval = 50 * time.Millisecond
+
+	// fileTokenCacheStaleLockAge is the age after which a lock file is assumed to have been abandoned by a process
+	// which exited without removing it
+	fileTokenCacheStaleLockAge = 30 * time.Second
+)
+
+var _ TokenCache = &FileTokenCache{}
+
+// FileTokenCache is a TokenCache which persists tokens in a file encrypted using AES-GCM, so that they can be reused
+// between processes. Access to the file is serialized between processes using a lock file alongside it.
+type FileTokenCache struct {
+	path string
+	aead cipher.AEAD
+
+	// mutex serializes access to the file within this process
+	mutex sync.Mutex
+}
+
+// fileTokenCacheEntry is the persisted form of a token
+type fileTokenCacheEntry struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// NewFileTokenCache returns a TokenCache which persists tokens in the file at path, encrypted with the specified key.
+// The key must be 16, 24 or 32 bytes long, selecting AES-128, AES-192 or AES-256 respectively, and should be stored
+// securely, e.g. in the operating system's keychain. The file and its directory are created when tokens are first
+// stored.
+func NewFileTokenCache(path string, key []byte) (TokenCache, error) {
+	if path == "" {
+		return nil, fmt.Errorf("FileTokenCache: a path must be specified")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("FileTokenCache: invalid encryption key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("FileTokenCache: configuring encryption: %v", err)
+	}
+
+	return &FileTokenCache{
+		path: path,
+		aead: aead,
+	}, nil
+}
+
+func (c *FileTokenCache) Get(ctx context.Context, key TokenCacheKey) (*oauth2.Token, bool, error) {
+	unlock, err := c.lock(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		return nil, false, err
+	}
+	entry, ok := entries[key.String()]
+	if !ok {
+		return nil, false, nil
+	}
+
+	return &oauth2.Token{
+		AccessToken:  entry.AccessToken,
+		TokenType:    entry.TokenType,
+		RefreshToken: entry.RefreshToken,
+		Expiry:       entry.Expiry,
+	}, true, nil
+}
+
+func (c *FileTokenCache) Set(ctx context.Context, key TokenCacheKey, token *oauth2.Token) error {
+	if token == nil {
+		return nil
+	}
+
+	unlock, err := c.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		// the file cannot be decrypted, e.g. when the key has been rotated, so it's replaced
+		loggerFromContext(ctx).Debug("discarding unreadable token cache", "path", c.path, "error", err)
+		entries = map[string]fileTokenCacheEntry{}
+	}
+	entries[key.String()] = fileTokenCacheEntry{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		RefreshToken: token.RefreshToken,
+		Expiry:       token.Expiry,
+	}
+
+	return c.write(entries)
+}
+
+func (c *FileTokenCache) Delete(ctx context.Context, key TokenCacheKey) error {
+	unlock, err := c.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[key.String()]; !ok {
+		return nil
+	}
+	delete(entries, key.String())
+
+	return c.write(entries)
+}
+
+// lock acquires exclusive access to the cache file, by creating a lock file which other processes will wait for. The
+// returned func releases the lock.
+func (c *FileTokenCache) lock(ctx context.Context) (func(), error) {
+	c.mutex.Lock()
+
+	lockPath := c.path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o700); err != nil {
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("FileTokenCache: creating directory: %v", err)
+	}
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() {
+				if err := os.Remove(lockPath); err != nil {
+					loggerFromContext(ctx).Debug("could not remove token cache lock file", "path", lockPath, "error", err)
+				}
+				c.mutex.Unlock()
+			}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			c.mutex.Unlock()
+			return nil, fmt.Errorf("FileTokenCache: creating lock file: %v", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > fileTokenCacheStaleLockAge {
+			if removeStaleLock(ctx, lockPath) {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			c.mutex.Unlock()
+			return nil, fmt.Errorf("FileTokenCache: waiting for lock file %q: %v", lockPath, ctx.Err())
+		case <-time.After(fileTokenCacheLockRetryInterval):
+		}
+	}
+}
+
+// removeStaleLock removes a lock file which has been abandoned, returning true when it was removed. The lock file is
+// first renamed to a unique name, which only one process can do, and is then checked again so that a lock file which
+// was replaced by another process since it was found to be stale is restored rather than removed.
+func removeStaleLock(ctx context.Context, lockPath string) bool {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return false
+	}
+	stalePath := fmt.Sprintf("%s.%x.stale", lockPath, suffix)
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		// another process has already taken over or released the lock
+		return false
+	}
+
+	defer os.Remove(stalePath)
+
+	info, err := os.Stat(stalePath)
+	if err != nil {
+		return false
+	}
+	if time.Since(info.ModTime()) <= fileTokenCacheStaleLockAge {
+		// this is a lock file held by another process, which is put back unless yet another lock file now exists
+		if err = os.Link(stalePath, lockPath); err != nil {
+			loggerFromContext(ctx).Debug("could not restore token cache lock file", "path", lockPath, "error", err)
+		}
+		return false
+	}
+
+	loggerFromContext(ctx).Debug("removed stale token cache lock file", "path", lockPath, "modified", info.ModTime())
+	return true
+}
+
+// read decrypts and returns the entries in the cache file, the caller must hold the lock
+func (c *FileTokenCache) read() (map[string]fileTokenCacheEntry, error) {
+	entries := map[string]fileTokenCacheEntry{}
+
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FileTokenCache: reading file: %v", err)
+	}
+
+	if !bytes.HasPrefix(b, []byte(fileTokenCacheHeader)) {
+		return nil, fmt.Errorf("FileTokenCache: file %q is not a token cache", c.path)
+	}
+	b = b[len(fileTokenCacheHeader):]
+	if len(b) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("FileTokenCache: file %q is truncated", c.path)
+	}
+
+	nonce, ciphertext := b[:c.aead.NonceSize()], b[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(fileTokenCacheHeader))
+	if err != nil {
+		return nil, fmt.Errorf("FileTokenCache: decrypting file %q: %v", c.path, err)
+	}
+
+	if err = json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("FileTokenCache: unmarshaling file %q: %v", c.path, err)
+	}
+	return entries, nil
+}
+
+// write encrypts the entries and replaces the cache file, the caller must hold the lock
+func (c *FileTokenCache) write(entries map[string]fileTokenCacheEntry) error {
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("FileTokenCache: marshaling entries: %v", err)
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("FileTokenCache: generating nonce: %v", err)
+	}
+
+	b := append([]byte(fileTokenCacheHeader), nonce...)
+	b = c.aead.Seal(b, nonce, plaintext, []byte(fileTokenCacheHeader))
+
+	// the file is written in full before being moved into place, so that it's never observed partially written
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("FileTokenCache: creating temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("FileTokenCache: writing temporary file: %v", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("FileTokenCache: writing temporary file: %v", err)
+	}
+	if err = os.Rename(f.Name(), c.path); err != nil {
+		return fmt.Errorf("FileTokenCache: replacing file: %v", err)
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"net/url"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"golang.org/x/oauth2"
 )
 
@@ -40,6 +41,18 @@ type GitHubOIDCAuthorizerOptions struct {
 	// IdTokenRequestToken is the bearer token for the request to the OIDC provider.
 	// Usually exposed via the ACTIONS_ID_TOKEN_REQUEST_TOKEN environment variable when running in GitHub Actions
 	IdTokenRequestToken string
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
 }
 
 // NewGitHubOIDCAuthorizer returns an authorizer which acquires a client assertion from a GitHub endpoint, then uses client assertion authentication to obtain an access token.
@@ -56,6 +69,8 @@ func NewGitHubOIDCAuthorizer(ctx context.Context, options GitHubOIDCAuthorizerOp
 		ClientID:            options.ClientId,
 		IDTokenRequestURL:   options.IdTokenRequestUrl,
 		IDTokenRequestToken: options.IdTokenRequestToken,
+		Logger:              options.Logger,
+		ClientCapabilities:  clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities),
 		Scopes: []string{
 			*scope,
 		},
@@ -132,6 +147,7 @@ func (a *GitHubOIDCAuthorizer) tokenSource(ctx context.Context, req *http.Reques
 		ClientID:           a.conf.ClientID,
 		FederatedAssertion: *assertion,
 		Scopes:             a.conf.Scopes,
+		ClientCapabilities: a.conf.ClientCapabilities,
 		TokenURL:           a.conf.TokenURL,
 		Audience:           a.conf.Audience,
 	}
@@ -159,6 +175,20 @@ func (a *GitHubOIDCAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Re
 	return source.AuxiliaryTokens(ctx, req)
 }
 
+func (a *GitHubOIDCAuthorizer) tokenCacheKey() (TokenCacheKey, bool) {
+	if a.conf == nil {
+		return TokenCacheKey{}, false
+	}
+	conf := clientCredentialsConfig{
+		Environment: a.conf.Environment,
+		TenantID:    a.conf.TenantID,
+		ClientID:    a.conf.ClientID,
+		Scopes:      a.conf.Scopes,
+		TokenURL:    a.conf.TokenURL,
+	}
+	return conf.tokenCacheKey()
+}
+
 type gitHubOIDCConfig struct {
 	// Environment is the national cloud environment to use
 	Environment environments.Environment
@@ -188,10 +218,19 @@ type gitHubOIDCConfig struct {
 	// request.  If empty, the value of TokenURL is used as the
 	// intended audience.
 	Audience string
+
+	// ClientCapabilities are advertised to the authorization service when requesting tokens
+	ClientCapabilities []string
+
+	// Logger is used when obtaining tokens
+	Logger logging.Logger
 }
 
 func (c *gitHubOIDCConfig) TokenSource(ctx context.Context) (Authorizer, error) {
-	return NewCachedAuthorizer(&GitHubOIDCAuthorizer{
-		conf: c,
-	})
+	return &CachedAuthorizer{
+		Source: &GitHubOIDCAuthorizer{
+			conf: c,
+		},
+		Logger: c.Logger,
+	}, nil
 }
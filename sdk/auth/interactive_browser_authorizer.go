@@ -0,0 +1,228 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+	"golang.org/x/oauth2"
+)
+
+type InteractiveBrowserAuthorizerOptions struct {
+	// Environment is the Azure environment/cloud being targeted
+	Environment environments.Environment
+
+	// Api describes the Azure API being used
+	Api environments.Api
+
+	// TenantId is the tenant to authenticate against, defaults to `organizations`
+	TenantId string
+
+	// AuxiliaryTenantIds lists additional tenants to authenticate against, currently only
+	// used for Resource Manager when auxiliary tenants are needed.
+	// e.g. https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/authenticate-multi-tenant
+	AuxiliaryTenantIds []string
+
+	// ClientId is the client ID of the public client application used when authenticating, defaults to the Azure CLI.
+	// The application must allow the redirect URI `http://localhost`.
+	ClientId string
+
+	// RedirectPort is the port on which to listen for the redirect from the authorization service. When zero, a
+	// random port is used.
+	RedirectPort int
+
+	// OpenBrowser is called with the URL which the user should open to authenticate. When nil, the system browser is opened.
+	OpenBrowser func(ctx context.Context, url string) error
+
+	// Timeout is the maximum duration to wait for the user to authenticate, defaults to 5 minutes
+	Timeout time.Duration
+
+	// TokenCache optionally persists the tokens obtained for the user, including the refresh token, so that the user
+	// is not prompted again by other processes, e.g. when using NewFileTokenCache
+	TokenCache TokenCache
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
+}
+
+// NewInteractiveBrowserAuthorizer returns an authorizer which authenticates a user by opening a browser, using the
+// authorization code flow with PKCE and a loopback redirect. Once authenticated, the refresh token is used to obtain
+// further tokens.
+func NewInteractiveBrowserAuthorizer(ctx context.Context, options InteractiveBrowserAuthorizerOptions) (Authorizer, error) {
+	conf, err := newPublicClientConfig(options.Environment, options.Api, options.TenantId, options.AuxiliaryTenantIds, options.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	conf.ClientCapabilities = clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities)
+
+	a := &InteractiveBrowserAuthorizer{
+		refreshableTokenSource: refreshableTokenSource{
+			conf: conf,
+		},
+		openBrowser:  options.OpenBrowser,
+		redirectPort: options.RedirectPort,
+		timeout:      options.Timeout,
+	}
+	a.acquire = a.authorizationCodeToken
+	if a.openBrowser == nil {
+		a.openBrowser = openSystemBrowser
+	}
+	if a.timeout == 0 {
+		a.timeout = 5 * time.Minute
+	}
+
+	return &CachedAuthorizer{
+		Source: a,
+		Cache:  options.TokenCache,
+		Logger: options.Logger,
+	}, nil
+}
+
+var _ Authorizer = &InteractiveBrowserAuthorizer{}
+
+// InteractiveBrowserAuthorizer is an Authorizer which authenticates a user using the authorization code flow in a browser
+type InteractiveBrowserAuthorizer struct {
+	refreshableTokenSource
+
+	openBrowser  func(ctx context.Context, url string) error
+	redirectPort int
+	timeout      time.Duration
+}
+
+// authorizationCodeToken opens a browser for the user to authenticate, receives the authorization code using a
+// loopback redirect, then redeems the code for a token
+func (a *InteractiveBrowserAuthorizer) authorizationCodeToken(ctx context.Context) (*oauth2.Token, error) {
+	verifier, err := randomUrlSafeString(32)
+	if err != nil {
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: generating code verifier: %v", err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	state, err := randomUrlSafeString(16)
+	if err != nil {
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: generating state: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", a.redirectPort))
+	if err != nil {
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: listening for redirect: %v", err)
+	}
+	redirectUri := fmt.Sprintf("http://localhost:%d", listener.Addr().(*net.TCPAddr).Port)
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			var res result
+			switch {
+			case query.Get("state") != state:
+				res.err = fmt.Errorf("the state returned in the redirect did not match")
+			case query.Get("error") != "":
+				res.err = fmt.Errorf("authentication failed with error %q: %s", query.Get("error"), query.Get("error_description"))
+			case query.Get("code") == "":
+				res.err = fmt.Errorf("the redirect did not contain an authorization code")
+			default:
+				res.code = query.Get("code")
+			}
+
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if res.err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Authentication failed: %v", res.err)
+			} else {
+				fmt.Fprint(w, "Authentication complete. You can close this window.")
+			}
+			select {
+			case results <- res:
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorizeUrl := fmt.Sprintf("%s?%s", a.conf.endpoint(a.conf.TenantID, "authorize"), url.Values{
+		"client_id":             {a.conf.ClientID},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"prompt":                {"select_account"},
+		"redirect_uri":          {redirectUri},
+		"response_type":         {"code"},
+		"scope":                 {a.conf.scope()},
+		"state":                 {state},
+	}.Encode())
+	if err = a.openBrowser(ctx, authorizeUrl); err != nil {
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: opening browser: %v", err)
+	}
+
+	var res result
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: waiting for user to authenticate: %v", ctx.Err())
+	case <-time.After(a.timeout):
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: timed out waiting for user to authenticate")
+	}
+	if res.err != nil {
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: %v", res.err)
+	}
+
+	token, err := a.conf.publicClientToken(ctx, a.conf.TenantID, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"code_verifier": {verifier},
+		"redirect_uri":  {redirectUri},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("InteractiveBrowserAuthorizer: redeeming authorization code: %v", err)
+	}
+	return token, nil
+}
+
+// openSystemBrowser opens the specified URL using the default browser for the operating system
+func openSystemBrowser(_ context.Context, url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+func randomUrlSafeString(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
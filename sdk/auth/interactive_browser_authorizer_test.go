@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+)
+
+func TestInteractiveBrowserAuthorizer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	browserOpened := 0
+	opts := auth.InteractiveBrowserAuthorizerOptions{
+		Environment: env,
+		Api:         env.MicrosoftGraph,
+		OpenBrowser: func(ctx context.Context, url string) error {
+			browserOpened++
+			if !strings.HasPrefix(url, server.URL+"/organizations/oauth2/v2.0/authorize?") {
+				t.Errorf("unexpected authorize URL %q", url)
+			}
+
+			// follows the redirect back to the loopback listener, as the user's browser would
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			return nil
+		},
+	}
+
+	authorizer, err := auth.NewInteractiveBrowserAuthorizer(ctx, opts)
+	if err != nil {
+		t.Fatalf("NewInteractiveBrowserAuthorizer(): %v", err)
+	}
+
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+
+	// obtaining further tokens should use the refresh token without opening the browser
+	source := authorizer.(*auth.CachedAuthorizer).Source
+	if _, err = testObtainAccessToken(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	if browserOpened != 1 {
+		t.Fatalf("expected the browser to be opened once, but was opened %d times", browserOpened)
+	}
+	if expected := "authorization_code,refresh_token"; strings.Join(server.grants, ",") != expected {
+		t.Fatalf("expected the grants %q, got %q", expected, strings.Join(server.grants, ","))
+	}
+}
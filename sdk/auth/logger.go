@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+)
+
+type loggerContextKey struct{}
+
+// withLogger returns a context which carries the Logger configured for an Authorizer, so that it is used for any
+// messages logged whilst obtaining tokens, including by the shared HTTP clients. The context is returned unchanged
+// when no Logger is configured.
+func withLogger(ctx context.Context, l logging.Logger) context.Context {
+	if l == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerContextKey{}, logging.NewRedactingLogger(l))
+}
+
+// loggerFromContext returns the Logger carried by the context, falling back to logging.Default(). Sensitive values
+// are always redacted.
+func loggerFromContext(ctx context.Context) logging.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerContextKey{}).(logging.Logger); ok && l != nil {
+			return l
+		}
+	}
+	return logging.Default()
+}
+
+// loggerOrDefault returns the specified Logger, wrapped so that sensitive values are redacted, or logging.Default()
+// when nil
+func loggerOrDefault(l logging.Logger) logging.Logger {
+	if l != nil {
+		return logging.NewRedactingLogger(l)
+	}
+	return logging.Default()
+}
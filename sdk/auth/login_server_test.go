@@ -0,0 +1,150 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/internal/test"
+)
+
+// fakeLoginServer implements the device code, authorization code and on-behalf-of flows of the Microsoft identity
+// platform
+type fakeLoginServer struct {
+	*httptest.Server
+
+	mutex         sync.Mutex
+	pendingPolls  int
+	challenge     string
+	refreshTokens int
+	grants        []string
+	tenants       []string
+	claims        []string
+	assertions    []string
+}
+
+func newFakeLoginServer(t *testing.T) *fakeLoginServer {
+	s := &fakeLoginServer{
+		pendingPolls: 1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+
+	original := auth.Client
+	auth.Client = &http.Client{}
+	t.Cleanup(func() {
+		auth.Client = original
+	})
+
+	return s
+}
+
+func (s *fakeLoginServer) environment() environments.Environment {
+	env := environments.AzurePublic()
+	env.Authorization.LoginEndpoint = s.URL
+	return *env
+}
+
+func (s *fakeLoginServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(segments) != 4 || segments[1] != "oauth2" || segments[2] != "v2.0" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	tenant, endpoint := segments[0], segments[3]
+
+	w.Header().Set("Content-Type", "application/json")
+	writeError := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error":%q,"error_description":"fake error"}`, code)
+	}
+
+	switch endpoint {
+	case "authorize":
+		query := r.URL.Query()
+		s.challenge = query.Get("code_challenge")
+		redirect := fmt.Sprintf("%s?code=authcode&state=%s", query.Get("redirect_uri"), query.Get("state"))
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+
+	case "devicecode":
+		if !strings.Contains(r.FormValue("scope"), "offline_access") {
+			writeError("invalid_scope")
+			return
+		}
+		fmt.Fprint(w, `{"device_code":"devicecode","user_code":"ABCD1234","verification_uri":"https://microsoft.com/devicelogin","expires_in":900,"interval":0}`)
+		return
+
+	case "token":
+		grant := r.FormValue("grant_type")
+		s.grants = append(s.grants, grant)
+		s.tenants = append(s.tenants, tenant)
+		s.claims = append(s.claims, r.FormValue("claims"))
+
+		switch grant {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if r.FormValue("device_code") != "devicecode" {
+				writeError("invalid_grant")
+				return
+			}
+			if s.pendingPolls > 0 {
+				s.pendingPolls--
+				writeError("authorization_pending")
+				return
+			}
+
+		case "authorization_code":
+			verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			if r.FormValue("code") != "authcode" || base64.RawURLEncoding.EncodeToString(verifier[:]) != s.challenge {
+				writeError("invalid_grant")
+				return
+			}
+
+		case "refresh_token":
+			if r.FormValue("refresh_token") != fmt.Sprintf("refreshtoken%d", s.refreshTokens) {
+				writeError("invalid_grant")
+				return
+			}
+
+		case "urn:ietf:params:oauth:grant-type:jwt-bearer":
+			if r.FormValue("requested_token_use") != "on_behalf_of" || r.FormValue("assertion") == "" {
+				writeError("invalid_request")
+				return
+			}
+			if r.FormValue("client_secret") == "" && r.FormValue("client_assertion") == "" {
+				writeError("invalid_client")
+				return
+			}
+			s.assertions = append(s.assertions, r.FormValue("assertion"))
+
+		default:
+			writeError("unsupported_grant_type")
+			return
+		}
+
+		s.refreshTokens++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  test.DummyAccessToken,
+			"expires_in":    3599,
+			"refresh_token": fmt.Sprintf("refreshtoken%d", s.refreshTokens),
+			"token_type":    "Bearer",
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
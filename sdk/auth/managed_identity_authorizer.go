@@ -7,14 +7,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
-	"net/url"
+	"os"
 	"strconv"
 	"time"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"golang.org/x/oauth2"
 )
 
@@ -22,12 +21,27 @@ type ManagedIdentityAuthorizerOptions struct {
 	// Api describes the Azure API being used
 	Api environments.Api
 
-	// ClientId is the client ID used when authenticating
+	// ClientId is the client ID of a user-assigned managed identity to authenticate with. Only one of ClientId,
+	// ObjectId or ResourceId should be specified.
 	ClientId string
 
+	// ObjectId is the object (principal) ID of a user-assigned managed identity to authenticate with
+	ObjectId string
+
+	// ResourceId is the Resource Manager ID of a user-assigned managed identity to authenticate with
+	ResourceId string
+
 	// CustomManagedIdentityEndpoint is an optional endpoint from which to obtain an access
 	// token. When blank, the default is used.
 	CustomManagedIdentityEndpoint string
+
+	// Source optionally specifies the hosting environment which provides the managed identity. When blank, this is
+	// detected from the environment variables set by the host, falling back to the Instance Metadata Service.
+	Source ManagedIdentitySource
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
 }
 
 // NewManagedIdentityAuthorizer returns an authorizer using a Managed Identity for authentication.
@@ -36,7 +50,7 @@ func NewManagedIdentityAuthorizer(ctx context.Context, options ManagedIdentityAu
 	if err != nil {
 		return nil, fmt.Errorf("determining resource for api %q: %+v", options.Api.Name(), err)
 	}
-	conf, err := newManagedIdentityConfig(*resource, options.ClientId, options.CustomManagedIdentityEndpoint)
+	conf, err := newManagedIdentityConfig(*resource, options)
 	if err != nil {
 		return nil, err
 	}
@@ -55,26 +69,26 @@ type ManagedIdentityAuthorizer struct {
 	conf *managedIdentityConfig
 }
 
-// Token returns an access token acquired from the metadata endpoint.
+// Token returns an access token acquired from the managed identity endpoint for the hosting environment.
 func (a *ManagedIdentityAuthorizer) Token(ctx context.Context, _ *http.Request) (*oauth2.Token, error) {
 	if a.conf == nil {
 		return nil, fmt.Errorf("could not request token: conf is nil")
 	}
 
-	query := url.Values{
-		"api-version": []string{a.conf.MsiApiVersion},
-		"resource":    []string{a.conf.Resource},
-	}
-
-	if a.conf.ClientID != "" {
-		query["client_id"] = []string{a.conf.ClientID}
+	var body []byte
+	var err error
+	switch a.conf.Source {
+	case ManagedIdentitySourceAppService:
+		body, err = appServiceToken(ctx, a.conf)
+	case ManagedIdentitySourceAzureArc:
+		body, err = azureArcToken(ctx, a.conf)
+	case ManagedIdentitySourceServiceFabric:
+		body, err = serviceFabricToken(ctx, a.conf)
+	default:
+		body, err = instanceMetadataServiceToken(ctx, a.conf)
 	}
-
-	url := fmt.Sprintf("%s?%s", a.conf.MsiEndpoint, query.Encode())
-
-	body, err := azureMetadata(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("ManagedIdentityAuthorizer: failed to request token from metadata endpoint: %v", err)
+		return nil, fmt.Errorf("ManagedIdentityAuthorizer: failed to request token from %s endpoint: %v", a.conf.Source, err)
 	}
 
 	var tokenRes struct {
@@ -107,6 +121,19 @@ func (a *ManagedIdentityAuthorizer) Token(ctx context.Context, _ *http.Request)
 	}
 	if secs > 0 {
 		token.Expiry = time.Now().Add(secs * time.Second)
+	} else {
+		// App Service and Service Fabric only return a timestamp
+		var expiresOn int64
+		if exp, ok := tokenRes.ExpiresOn.(string); ok && exp != "" {
+			if v, err := strconv.ParseInt(exp, 10, 64); err == nil {
+				expiresOn = v
+			}
+		} else if exp, ok := tokenRes.ExpiresOn.(float64); ok {
+			expiresOn = int64(exp)
+		}
+		if expiresOn > 0 {
+			token.Expiry = time.Unix(expiresOn, 0)
+		}
 	}
 
 	return token, nil
@@ -118,69 +145,144 @@ func (a *ManagedIdentityAuthorizer) AuxiliaryTokens(_ context.Context, _ *http.R
 	return []*oauth2.Token{}, nil
 }
 
+// tokenCacheKey identifies the tokens obtained for the managed identity, so that they can be persisted in a TokenCache
+func (a *ManagedIdentityAuthorizer) tokenCacheKey() (TokenCacheKey, bool) {
+	if a.conf == nil {
+		return TokenCacheKey{}, false
+	}
+	identity := a.conf.ClientID
+	if a.conf.ObjectID != "" {
+		identity = a.conf.ObjectID
+	} else if a.conf.ResourceID != "" {
+		identity = a.conf.ResourceID
+	}
+	return TokenCacheKey{
+		Authority: fmt.Sprintf("%s %s", a.conf.Source, a.conf.MsiEndpoint),
+		ClientId:  identity,
+		Scope:     a.conf.Resource,
+	}, true
+}
+
 // managedIdentityConfig configures an ManagedIdentityAuthorizer.
 type managedIdentityConfig struct {
+	// Source is the hosting environment which provides the managed identity
+	Source ManagedIdentitySource
+
 	// ClientID is optionally used to determine which application to assume when a resource has multiple managed identities
 	ClientID string
 
+	// ObjectID is optionally used to determine which identity to assume when a resource has multiple managed identities
+	ObjectID string
+
+	// ResourceID is optionally used to determine which identity to assume when a resource has multiple managed identities
+	ResourceID string
+
 	// MsiApiVersion is the API version to use when requesting a token from the metadata service
 	MsiApiVersion string
 
 	// MsiEndpoint is the endpoint where the metadata service can be found
 	MsiEndpoint string
 
+	// IdentityHeader is the secret sent to the App Service and Service Fabric endpoints
+	IdentityHeader string
+
+	// HttpClient is used to request tokens from Service Fabric, which requires the server certificate to be pinned.
+	// MetadataClient is used when nil.
+	HttpClient HTTPClient
+
 	// Resource is the service for which to request an access token
 	Resource string
+
+	// Logger is used when obtaining tokens
+	Logger logging.Logger
 }
 
-// newManagedIdentityConfig returns a new managedIdentityConfig with a configured metadata endpoint and resource.
-// clientId and objectId can be left blank when a single managed identity is available
-func newManagedIdentityConfig(resource, clientId, customManagedIdentityEndpoint string) (*managedIdentityConfig, error) {
-	endpoint := msiDefaultEndpoint
-	if customManagedIdentityEndpoint != "" {
-		endpoint = customManagedIdentityEndpoint
+// newManagedIdentityConfig returns a new managedIdentityConfig with a configured endpoint and resource, detecting
+// the hosting environment when no source is specified. The user-assigned identity can be left blank when a single
+// managed identity is available
+func newManagedIdentityConfig(resource string, options ManagedIdentityAuthorizerOptions) (*managedIdentityConfig, error) {
+	selected := 0
+	for _, v := range []string{options.ClientId, options.ObjectId, options.ResourceId} {
+		if v != "" {
+			selected++
+		}
+	}
+	if selected > 1 {
+		return nil, fmt.Errorf("only one of ClientId, ObjectId or ResourceId can be specified")
 	}
 
-	return &managedIdentityConfig{
-		ClientID:      clientId,
-		Resource:      resource,
-		MsiApiVersion: msiDefaultApiVersion,
-		MsiEndpoint:   endpoint,
-	}, nil
-}
-
-// TokenSource provides a source for obtaining access tokens using ManagedIdentityAuthorizer.
-func (c *managedIdentityConfig) TokenSource(_ context.Context) (Authorizer, error) {
-	return NewCachedAuthorizer(&ManagedIdentityAuthorizer{
-		conf: c,
-	})
-}
+	source := options.Source
+	if source == "" {
+		source = ManagedIdentitySourceInstanceMetadataService
+		if options.CustomManagedIdentityEndpoint == "" {
+			source = detectManagedIdentitySource()
+		}
+	}
+	loggerOrDefault(options.Logger).Debug("using managed identity source", "source", source)
 
-func azureMetadata(ctx context.Context, url string) (body []byte, err error) {
-	var req *http.Request
-	req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return
+	conf := &managedIdentityConfig{
+		Source:     source,
+		ClientID:   options.ClientId,
+		ObjectID:   options.ObjectId,
+		ResourceID: options.ResourceId,
+		Resource:   resource,
+		Logger:     options.Logger,
 	}
-	req.Header = http.Header{
-		"Metadata": []string{"true"},
+
+	switch source {
+	case ManagedIdentitySourceInstanceMetadataService:
+		conf.MsiApiVersion = msiDefaultApiVersion
+		conf.MsiEndpoint = msiDefaultEndpoint
+		if options.CustomManagedIdentityEndpoint != "" {
+			conf.MsiEndpoint = options.CustomManagedIdentityEndpoint
+		}
+		return conf, nil
+
+	case ManagedIdentitySourceAppService:
+		conf.MsiApiVersion = appServiceApiVersion
+		conf.MsiEndpoint = os.Getenv(identityEndpointEnvVar)
+		conf.IdentityHeader = os.Getenv(identityHeaderEnvVar)
+
+	case ManagedIdentitySourceAzureArc:
+		conf.MsiApiVersion = azureArcApiVersion
+		conf.MsiEndpoint = os.Getenv(identityEndpointEnvVar)
+
+	case ManagedIdentitySourceServiceFabric:
+		conf.MsiApiVersion = serviceFabricApiVersion
+		conf.MsiEndpoint = os.Getenv(identityEndpointEnvVar)
+		conf.IdentityHeader = os.Getenv(identityHeaderEnvVar)
+		thumbprint := os.Getenv(identityServerThumbprintEnvVar)
+		if thumbprint == "" {
+			return nil, fmt.Errorf("the %s environment variable must be set when using %s", identityServerThumbprintEnvVar, source)
+		}
+		params := defaultHttpClientParams()
+		params.serverCertificateThumbprint = thumbprint
+		params.useProxy = false
+		conf.HttpClient = httpClient(params)
+
+	default:
+		return nil, fmt.Errorf("unsupported managed identity source %q", source)
 	}
 
-	var resp *http.Response
-	log.Printf("[DEBUG] Performing %s Request to %q", req.Method, url)
-	resp, err = MetadataClient.Do(req)
-	if err != nil {
-		return
+	if options.CustomManagedIdentityEndpoint != "" {
+		conf.MsiEndpoint = options.CustomManagedIdentityEndpoint
 	}
-	log.Printf("[DEBUG] Reading Body from %s %q", req.Method, url)
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return
+	if conf.MsiEndpoint == "" {
+		return nil, fmt.Errorf("the %s environment variable must be set when using %s", identityEndpointEnvVar, source)
 	}
-	defer resp.Body.Close()
-	if c := resp.StatusCode; c < 200 || c > 299 {
-		err = fmt.Errorf("received HTTP status %d with body: %s", resp.StatusCode, body)
-		return
+	if selected > 0 && (source == ManagedIdentitySourceAzureArc || source == ManagedIdentitySourceServiceFabric) {
+		return nil, fmt.Errorf("%s does not support selecting a user-assigned managed identity at runtime", source)
 	}
-	return
+
+	return conf, nil
+}
+
+// TokenSource provides a source for obtaining access tokens using ManagedIdentityAuthorizer.
+func (c *managedIdentityConfig) TokenSource(_ context.Context) (Authorizer, error) {
+	return &CachedAuthorizer{
+		Source: &ManagedIdentityAuthorizer{
+			conf: c,
+		},
+		Logger: c.Logger,
+	}, nil
 }
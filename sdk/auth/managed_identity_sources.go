@@ -0,0 +1,243 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ManagedIdentitySource is the hosting environment which provides a managed identity, which determines the protocol
+// used to obtain access tokens
+type ManagedIdentitySource string
+
+const (
+	// ManagedIdentitySourceAppService obtains tokens from the endpoint exposed to Azure App Service and Azure Functions
+	ManagedIdentitySourceAppService ManagedIdentitySource = "AppService"
+
+	// ManagedIdentitySourceAzureArc obtains tokens from the Azure Connected Machine agent on an Azure Arc-enabled server
+	ManagedIdentitySourceAzureArc ManagedIdentitySource = "AzureArc"
+
+	// ManagedIdentitySourceInstanceMetadataService obtains tokens from the Instance Metadata Service, e.g. on a Virtual Machine
+	ManagedIdentitySourceInstanceMetadataService ManagedIdentitySource = "InstanceMetadataService"
+
+	// ManagedIdentitySourceServiceFabric obtains tokens from the Service Fabric managed identity token service
+	ManagedIdentitySourceServiceFabric ManagedIdentitySource = "ServiceFabric"
+)
+
+const (
+	appServiceApiVersion    = "2019-08-01"
+	azureArcApiVersion      = "2019-11-01"
+	serviceFabricApiVersion = "2019-07-01-preview"
+
+	identityEndpointEnvVar         = "IDENTITY_ENDPOINT"
+	identityHeaderEnvVar           = "IDENTITY_HEADER"
+	identityServerThumbprintEnvVar = "IDENTITY_SERVER_THUMBPRINT"
+	imdsEndpointEnvVar             = "IMDS_ENDPOINT"
+
+	// azureArcMaxKeySize is the maximum size of the challenge key file written by the Azure Connected Machine agent
+	azureArcMaxKeySize = 4096
+)
+
+// azureArcTokenDirectory returns the directory in which the Azure Connected Machine agent writes challenge key files,
+// which is the only location from which a key file will be read.
+var azureArcTokenDirectory = func() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return "/var/opt/azcmagent/tokens", nil
+	case "windows":
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			return "", fmt.Errorf("the ProgramData environment variable was not set")
+		}
+		return filepath.Join(programData, "AzureConnectedMachineAgent", "Tokens"), nil
+	}
+	return "", fmt.Errorf("Azure Arc is not supported on %s", runtime.GOOS)
+}
+
+// detectManagedIdentitySource determines the hosting environment from the environment variables set by each host
+func detectManagedIdentitySource() ManagedIdentitySource {
+	if os.Getenv(identityEndpointEnvVar) != "" {
+		if os.Getenv(identityHeaderEnvVar) != "" {
+			if os.Getenv(identityServerThumbprintEnvVar) != "" {
+				return ManagedIdentitySourceServiceFabric
+			}
+			return ManagedIdentitySourceAppService
+		}
+		if os.Getenv(imdsEndpointEnvVar) != "" {
+			return ManagedIdentitySourceAzureArc
+		}
+	}
+	return ManagedIdentitySourceInstanceMetadataService
+}
+
+// instanceMetadataServiceToken requests a token from the Instance Metadata Service
+func instanceMetadataServiceToken(ctx context.Context, conf *managedIdentityConfig) ([]byte, error) {
+	query := url.Values{
+		"api-version": []string{conf.MsiApiVersion},
+		"resource":    []string{conf.Resource},
+	}
+	switch {
+	case conf.ClientID != "":
+		query.Set("client_id", conf.ClientID)
+	case conf.ObjectID != "":
+		query.Set("object_id", conf.ObjectID)
+	case conf.ResourceID != "":
+		query.Set("msi_res_id", conf.ResourceID)
+	}
+
+	header := http.Header{
+		"Metadata": []string{"true"},
+	}
+	return managedIdentityToken(ctx, MetadataClient, conf.MsiEndpoint, query, header)
+}
+
+// appServiceToken requests a token from the endpoint exposed to Azure App Service and Azure Functions
+func appServiceToken(ctx context.Context, conf *managedIdentityConfig) ([]byte, error) {
+	query := url.Values{
+		"api-version": []string{conf.MsiApiVersion},
+		"resource":    []string{conf.Resource},
+	}
+	switch {
+	case conf.ClientID != "":
+		query.Set("client_id", conf.ClientID)
+	case conf.ObjectID != "":
+		query.Set("principal_id", conf.ObjectID)
+	case conf.ResourceID != "":
+		query.Set("mi_res_id", conf.ResourceID)
+	}
+
+	header := http.Header{
+		"X-Identity-Header": []string{conf.IdentityHeader},
+	}
+	return managedIdentityToken(ctx, MetadataClient, conf.MsiEndpoint, query, header)
+}
+
+// serviceFabricToken requests a token from the Service Fabric managed identity token service, whose certificate is
+// pinned by the HttpClient
+func serviceFabricToken(ctx context.Context, conf *managedIdentityConfig) ([]byte, error) {
+	query := url.Values{
+		"api-version": []string{conf.MsiApiVersion},
+		"resource":    []string{conf.Resource},
+	}
+
+	header := http.Header{
+		"Secret": []string{conf.IdentityHeader},
+	}
+	client := conf.HttpClient
+	if client == nil {
+		client = MetadataClient
+	}
+	return managedIdentityToken(ctx, client, conf.MsiEndpoint, query, header)
+}
+
+// azureArcToken requests a token from the Azure Connected Machine agent. The agent first responds with a challenge
+// specifying the path to a key file, which is only readable by privileged users, and the contents of this file are
+// then sent to prove that the caller is authorized to obtain a token.
+func azureArcToken(ctx context.Context, conf *managedIdentityConfig) ([]byte, error) {
+	query := url.Values{
+		"api-version": []string{conf.MsiApiVersion},
+		"resource":    []string{conf.Resource},
+	}
+
+	header := http.Header{
+		"Metadata": []string{"true"},
+	}
+	resp, body, err := managedIdentityRequest(ctx, MetadataClient, conf.MsiEndpoint, query, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil, fmt.Errorf("expected a challenge but received HTTP status %d with body: %s", resp.StatusCode, body)
+	}
+
+	key, err := azureArcChallengeKey(resp.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, err
+	}
+
+	header.Set("Authorization", fmt.Sprintf("Basic %s", key))
+	return managedIdentityToken(ctx, MetadataClient, conf.MsiEndpoint, query, header)
+}
+
+// azureArcChallengeKey reads the key file specified in a challenge from the Azure Connected Machine agent, after
+// validating that the path is a key file in the expected directory
+func azureArcChallengeKey(challenge string) (string, error) {
+	scheme, realm, ok := strings.Cut(challenge, " ")
+	if !ok || !strings.EqualFold(scheme, "Basic") {
+		return "", fmt.Errorf("unexpected challenge %q", challenge)
+	}
+	path, ok := strings.CutPrefix(strings.TrimSpace(realm), "realm=")
+	if !ok || path == "" {
+		return "", fmt.Errorf("challenge did not specify a key file: %q", challenge)
+	}
+
+	directory, err := azureArcTokenDirectory()
+	if err != nil {
+		return "", err
+	}
+	if filepath.Dir(filepath.Clean(path)) != filepath.Clean(directory) {
+		return "", fmt.Errorf("the key file %q is not in the expected directory %q", path, directory)
+	}
+	if filepath.Ext(path) != ".key" {
+		return "", fmt.Errorf("the key file %q does not have the expected extension", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("reading key file: %v", err)
+	}
+	if info.Size() > azureArcMaxKeySize {
+		return "", fmt.Errorf("the key file %q is larger than the expected %d bytes", path, azureArcMaxKeySize)
+	}
+
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading key file: %v", err)
+	}
+	return string(key), nil
+}
+
+// managedIdentityToken requests a token from a managed identity endpoint, returning an error for an unsuccessful response
+func managedIdentityToken(ctx context.Context, client HTTPClient, endpoint string, query url.Values, header http.Header) ([]byte, error) {
+	resp, body, err := managedIdentityRequest(ctx, client, endpoint, query, header)
+	if err != nil {
+		return nil, err
+	}
+	if c := resp.StatusCode; c < 200 || c > 299 {
+		return nil, fmt.Errorf("received HTTP status %d with body: %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// managedIdentityRequest sends a GET request to a managed identity endpoint, returning the response and its body
+func managedIdentityRequest(ctx context.Context, client HTTPClient, endpoint string, query url.Values, header http.Header) (*http.Response, []byte, error) {
+	u := fmt.Sprintf("%s?%s", endpoint, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = header.Clone()
+
+	loggerFromContext(ctx).Debug("performing managed identity request", "method", req.Method, "url", u)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	loggerFromContext(ctx).Debug("reading managed identity response body", "method", req.Method, "url", u)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,198 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"golang.org/x/oauth2"
+)
+
+func TestDetectManagedIdentitySource(t *testing.T) {
+	testCases := []struct {
+		env      map[string]string
+		expected ManagedIdentitySource
+	}{
+		{
+			env:      map[string]string{},
+			expected: ManagedIdentitySourceInstanceMetadataService,
+		},
+		{
+			env: map[string]string{
+				identityEndpointEnvVar: "http://localhost:8081/msi/token",
+				identityHeaderEnvVar:   "secret",
+			},
+			expected: ManagedIdentitySourceAppService,
+		},
+		{
+			env: map[string]string{
+				identityEndpointEnvVar: "http://localhost:40342/metadata/identity/oauth2/token",
+				imdsEndpointEnvVar:     "http://localhost:40342",
+			},
+			expected: ManagedIdentitySourceAzureArc,
+		},
+		{
+			env: map[string]string{
+				identityEndpointEnvVar:         "https://localhost:2377/metadata/identity/oauth2/token",
+				identityHeaderEnvVar:           "secret",
+				identityServerThumbprintEnvVar: "0123456789abcdef",
+			},
+			expected: ManagedIdentitySourceServiceFabric,
+		},
+	}
+
+	for i, v := range testCases {
+		for _, name := range []string{identityEndpointEnvVar, identityHeaderEnvVar, identityServerThumbprintEnvVar, imdsEndpointEnvVar} {
+			t.Setenv(name, v.env[name])
+		}
+		if actual := detectManagedIdentitySource(); actual != v.expected {
+			t.Fatalf("Test Case #%d: expected %q but got %q", i, v.expected, actual)
+		}
+	}
+}
+
+func TestManagedIdentityAuthorizer_AppService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Identity-Header") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("api-version") != appServiceApiVersion || query.Get("resource") == "" || query.Get("mi_res_id") != "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/example/providers/Microsoft.ManagedIdentity/userAssignedIdentities/example" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"app-service-token","expires_on":"%d","resource":"%s","token_type":"Bearer"}`, time.Now().Add(time.Hour).Unix(), query.Get("resource"))
+	}))
+	defer server.Close()
+
+	t.Setenv(identityEndpointEnvVar, server.URL)
+	t.Setenv(identityHeaderEnvVar, "secret")
+	t.Setenv(identityServerThumbprintEnvVar, "")
+	withMetadataClient(t, &http.Client{})
+
+	token := testManagedIdentityToken(t, ManagedIdentityAuthorizerOptions{
+		ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/example/providers/Microsoft.ManagedIdentity/userAssignedIdentities/example",
+	})
+	if token.AccessToken != "app-service-token" {
+		t.Fatalf("unexpected access token %q", token.AccessToken)
+	}
+	if token.Expiry.Before(time.Now().Add(30 * time.Minute)) {
+		t.Fatalf("expected the expiry to be parsed from expires_on, got %s", token.Expiry)
+	}
+}
+
+func TestManagedIdentityAuthorizer_AzureArc(t *testing.T) {
+	directory := t.TempDir()
+	keyPath := filepath.Join(directory, "challenge.key")
+	if err := os.WriteFile(keyPath, []byte("challenge-secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	original := azureArcTokenDirectory
+	azureArcTokenDirectory = func() (string, error) {
+		return directory, nil
+	}
+	t.Cleanup(func() {
+		azureArcTokenDirectory = original
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("api-version") != azureArcApiVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Basic challenge-secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%s", keyPath))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"arc-token","expires_in":"3599","token_type":"Bearer"}`)
+	}))
+	defer server.Close()
+
+	t.Setenv(identityEndpointEnvVar, server.URL)
+	t.Setenv(identityHeaderEnvVar, "")
+	t.Setenv(imdsEndpointEnvVar, server.URL)
+	withMetadataClient(t, &http.Client{})
+
+	token := testManagedIdentityToken(t, ManagedIdentityAuthorizerOptions{})
+	if token.AccessToken != "arc-token" {
+		t.Fatalf("unexpected access token %q", token.AccessToken)
+	}
+
+	if _, err := azureArcChallengeKey("Basic realm=/etc/passwd.key"); err == nil {
+		t.Fatal("expected an error for a key file outside of the token directory")
+	}
+	if _, err := newManagedIdentityConfig("https://management.azure.com/", ManagedIdentityAuthorizerOptions{ClientId: "11111111-0000-0000-0000-000000000000"}); err == nil {
+		t.Fatal("expected an error when selecting a user-assigned identity with Azure Arc")
+	}
+}
+
+func TestManagedIdentityAuthorizer_ServiceFabric(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Secret") != "secret" || r.URL.Query().Get("api-version") != serviceFabricApiVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"service-fabric-token","expires_on":%d,"token_type":"Bearer"}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	thumbprint := sha1.Sum(server.Certificate().Raw)
+
+	t.Setenv(identityEndpointEnvVar, server.URL)
+	t.Setenv(identityHeaderEnvVar, "secret")
+	t.Setenv(identityServerThumbprintEnvVar, hex.EncodeToString(thumbprint[:]))
+
+	token := testManagedIdentityToken(t, ManagedIdentityAuthorizerOptions{})
+	if token.AccessToken != "service-fabric-token" {
+		t.Fatalf("unexpected access token %q", token.AccessToken)
+	}
+
+	t.Setenv(identityServerThumbprintEnvVar, "0000000000000000000000000000000000000000")
+	authorizer, err := NewManagedIdentityAuthorizer(context.Background(), ManagedIdentityAuthorizerOptions{
+		Api: environments.AzurePublic().ResourceManager,
+	})
+	if err != nil {
+		t.Fatalf("NewManagedIdentityAuthorizer(): %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err = authorizer.Token(ctx, nil); err == nil {
+		t.Fatal("expected an error when the server certificate thumbprint does not match")
+	}
+}
+
+func testManagedIdentityToken(t *testing.T, options ManagedIdentityAuthorizerOptions) *oauth2.Token {
+	options.Api = environments.AzurePublic().ResourceManager
+	authorizer, err := NewManagedIdentityAuthorizer(context.Background(), options)
+	if err != nil {
+		t.Fatalf("NewManagedIdentityAuthorizer(): %v", err)
+	}
+
+	token, err := authorizer.Token(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("authorizer.Token(): %v", err)
+	}
+	return token
+}
+
+func withMetadataClient(t *testing.T, client HTTPClient) {
+	original := MetadataClient
+	MetadataClient = client
+	t.Cleanup(func() {
+		MetadataClient = original
+	})
+}
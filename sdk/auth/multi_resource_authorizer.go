@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+	"golang.org/x/oauth2"
+)
+
+type MultiResourceAuthorizerOptions struct {
+	// Environment is the Azure environment/cloud being targeted, which is used to determine the Api for each request
+	Environment environments.Environment
+
+	// DefaultApi is used when the Api cannot be determined from the request, e.g. when no request is provided or
+	// the host is not known in the Environment. When nil, an error is returned in these cases.
+	DefaultApi environments.Api
+
+	// NewAuthorizer returns an Authorizer for the specified Api, and is called once for each scope. The returned
+	// Authorizer should cache its tokens, as do those returned by the New*Authorizer functions in this package.
+	NewAuthorizer func(ctx context.Context, api environments.Api) (Authorizer, error)
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
+}
+
+// NewMultiResourceAuthorizer returns an Authorizer which obtains tokens for the Api serving each request, as determined
+// from the request host using the domain suffixes and endpoints in the Environment. An Authorizer is built for each
+// scope as needed and retained, so that tokens are cached per scope.
+func NewMultiResourceAuthorizer(_ context.Context, options MultiResourceAuthorizerOptions) (CachingAuthorizer, error) {
+	if options.NewAuthorizer == nil {
+		return nil, fmt.Errorf("NewAuthorizer must be specified")
+	}
+	return &MultiResourceAuthorizer{
+		environment:   options.Environment,
+		defaultApi:    options.DefaultApi,
+		newAuthorizer: options.NewAuthorizer,
+		authorizers:   map[string]Authorizer{},
+		logger:        options.Logger,
+	}, nil
+}
+
+// NewMultiResourceAuthorizerFromCredentials returns a MultiResourceAuthorizer which builds an Authorizer for each scope
+// using NewAuthorizerFromCredentials
+func NewMultiResourceAuthorizerFromCredentials(ctx context.Context, c Credentials, defaultApi environments.Api) (CachingAuthorizer, error) {
+	return NewMultiResourceAuthorizer(ctx, MultiResourceAuthorizerOptions{
+		Environment: c.Environment,
+		DefaultApi:  defaultApi,
+		Logger:      c.Logger,
+		NewAuthorizer: func(ctx context.Context, api environments.Api) (Authorizer, error) {
+			return NewAuthorizerFromCredentials(ctx, c, api)
+		},
+	})
+}
+
+var _ CachingAuthorizer = &MultiResourceAuthorizer{}
+
+// MultiResourceAuthorizer is an Authorizer which obtains tokens for different Apis depending on the request
+type MultiResourceAuthorizer struct {
+	environment   environments.Environment
+	defaultApi    environments.Api
+	newAuthorizer func(ctx context.Context, api environments.Api) (Authorizer, error)
+	logger        logging.Logger
+
+	mutex       sync.Mutex
+	authorizers map[string]Authorizer
+}
+
+// Token returns an access token for the Api serving the request
+func (a *MultiResourceAuthorizer) Token(ctx context.Context, req *http.Request) (*oauth2.Token, error) {
+	ctx = withLogger(ctx, a.logger)
+	authorizer, err := a.authorizerForRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.Token(ctx, req)
+}
+
+// AuxiliaryTokens returns additional tokens for auxiliary tenant IDs for the Api serving the request, for use in
+// multi-tenant scenarios
+func (a *MultiResourceAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Request) ([]*oauth2.Token, error) {
+	ctx = withLogger(ctx, a.logger)
+	authorizer, err := a.authorizerForRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.AuxiliaryTokens(ctx, req)
+}
+
+// InvalidateCachedTokens invalidates the cached tokens for every scope
+func (a *MultiResourceAuthorizer) InvalidateCachedTokens() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for scope, authorizer := range a.authorizers {
+		if cache, ok := authorizer.(CachingAuthorizer); ok {
+			if err := cache.InvalidateCachedTokens(); err != nil {
+				return fmt.Errorf("invalidating cached tokens for scope %q: %v", scope, err)
+			}
+		}
+	}
+	return nil
+}
+
+// authorizerForRequest returns the Authorizer for the scope of the Api serving the request, building it if needed
+func (a *MultiResourceAuthorizer) authorizerForRequest(ctx context.Context, req *http.Request) (Authorizer, error) {
+	api := a.defaultApi
+	if req != nil && req.URL != nil {
+		if v, ok := a.environment.ApiForHost(req.URL.Host); ok {
+			api = v
+		}
+	}
+	if api == nil {
+		if req == nil || req.URL == nil {
+			return nil, fmt.Errorf("MultiResourceAuthorizer: could not determine the Api, as no request was provided and no DefaultApi is configured")
+		}
+		return nil, fmt.Errorf("MultiResourceAuthorizer: could not determine the Api for the host %q in the environment %q", req.URL.Host, a.environment.Name)
+	}
+
+	scope, err := environments.Scope(api)
+	if err != nil {
+		return nil, fmt.Errorf("MultiResourceAuthorizer: determining scope for %q: %v", api.Name(), err)
+	}
+
+	a.mutex.Lock()
+	authorizer, ok := a.authorizers[*scope]
+	a.mutex.Unlock()
+	if ok {
+		return authorizer, nil
+	}
+
+	// the Authorizer is built without holding the lock, so that requests for other scopes are not blocked
+	loggerFromContext(ctx).Debug("building Authorizer for scope", "api", api.Name(), "scope", *scope)
+	authorizer, err = a.newAuthorizer(ctx, api)
+	if err != nil {
+		return nil, fmt.Errorf("MultiResourceAuthorizer: building Authorizer for %q: %v", api.Name(), err)
+	}
+	if authorizer == nil {
+		return nil, fmt.Errorf("MultiResourceAuthorizer: no Authorizer was returned for %q", api.Name())
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// another request may have built an Authorizer for the same scope in the meantime, which is used in preference
+	// so that tokens are cached once per scope
+	if existing, ok := a.authorizers[*scope]; ok {
+		return existing, nil
+	}
+	a.authorizers[*scope] = authorizer
+	return authorizer, nil
+}
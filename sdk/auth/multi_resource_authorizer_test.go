@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/internal/test"
+)
+
+func TestMultiResourceAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	env := environments.AzurePublic()
+
+	var built []string
+	authorizer, err := auth.NewMultiResourceAuthorizer(ctx, auth.MultiResourceAuthorizerOptions{
+		Environment: *env,
+		DefaultApi:  env.ResourceManager,
+		NewAuthorizer: func(_ context.Context, api environments.Api) (auth.Authorizer, error) {
+			built = append(built, api.Name())
+			return &test.TestAuthorizer{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMultiResourceAuthorizer(): %v", err)
+	}
+
+	for _, u := range []string{
+		"https://management.azure.com/subscriptions?api-version=2020-01-01",
+		"https://myvault.vault.azure.net/secrets/example",
+		"https://graph.microsoft.com/v1.0/me",
+		"https://othervault.vault.azure.net/keys/example",
+		"https://myaccount.blob.core.windows.net/container",
+		"https://management.azure.com/providers?api-version=2020-01-01",
+	} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+		if err != nil {
+			t.Fatalf("building request: %v", err)
+		}
+		if err = auth.SetAuthHeader(ctx, req, authorizer); err != nil {
+			t.Fatalf("SetAuthHeader() for %q: %v", u, err)
+		}
+	}
+
+	// the default Api is used when no request is provided
+	if _, err = authorizer.Token(ctx, nil); err != nil {
+		t.Fatalf("Token(): %v", err)
+	}
+
+	expected := []string{"ResourceManager", "AzureKeyVault", "MicrosoftGraph", "AzureStorage"}
+	if strings.Join(built, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected an Authorizer to be built for each of %v, got %v", expected, built)
+	}
+
+	if err = authorizer.InvalidateCachedTokens(); err != nil {
+		t.Fatalf("InvalidateCachedTokens(): %v", err)
+	}
+}
+
+func TestMultiResourceAuthorizer_ConcurrentScopes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	env := environments.AzurePublic()
+
+	building, release := make(chan struct{}), make(chan struct{})
+	authorizer, err := auth.NewMultiResourceAuthorizer(ctx, auth.MultiResourceAuthorizerOptions{
+		Environment: *env,
+		NewAuthorizer: func(_ context.Context, api environments.Api) (auth.Authorizer, error) {
+			if api.Name() == env.KeyVault.Name() {
+				close(building)
+				<-release
+			}
+			return &test.TestAuthorizer{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMultiResourceAuthorizer(): %v", err)
+	}
+
+	token := func(u string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+		if err != nil {
+			return err
+		}
+		_, err = authorizer.Token(ctx, req)
+		return err
+	}
+
+	// building the Authorizer for one scope should not block obtaining tokens for other scopes
+	done := make(chan error)
+	go func() {
+		done <- token("https://myvault.vault.azure.net/secrets/example")
+	}()
+	<-building
+	if err = token("https://management.azure.com/subscriptions?api-version=2020-01-01"); err != nil {
+		t.Fatalf("obtaining token for Resource Manager: %v", err)
+	}
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatalf("obtaining token for Key Vault: %v", err)
+	}
+}
+
+func TestMultiResourceAuthorizer_UnknownHost(t *testing.T) {
+	ctx := context.Background()
+	env := environments.AzurePublic()
+
+	authorizer, err := auth.NewMultiResourceAuthorizer(ctx, auth.MultiResourceAuthorizerOptions{
+		Environment: *env,
+		NewAuthorizer: func(_ context.Context, api environments.Api) (auth.Authorizer, error) {
+			return &test.TestAuthorizer{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMultiResourceAuthorizer(): %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", http.NoBody)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	if _, err = authorizer.Token(ctx, req); err == nil {
+		t.Fatal("expected an error for an unknown host without a DefaultApi")
+	}
+}
+
+func TestMultiResourceAuthorizerFromCredentials(t *testing.T) {
+	ctx := context.Background()
+	env := environments.AzurePublic()
+
+	auth.Client = &test.AzureADAccessTokenMockClient{
+		Authorization: *env.Authorization,
+	}
+
+	authorizer, err := auth.NewMultiResourceAuthorizerFromCredentials(ctx, auth.Credentials{
+		Environment:                           *env,
+		TenantID:                              "00000000-1111-0000-0000-000000000000",
+		ClientID:                              "11111111-0000-0000-0000-000000000000",
+		ClientSecret:                          "supersecret",
+		EnableAuthenticatingUsingClientSecret: true,
+	}, env.MicrosoftGraph)
+	if err != nil {
+		t.Fatalf("NewMultiResourceAuthorizerFromCredentials(): %v", err)
+	}
+
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 )
 
 type OIDCAuthorizerOptions struct {
@@ -30,6 +31,18 @@ type OIDCAuthorizerOptions struct {
 
 	// FederatedAssertion is the client assertion dispensed by the OIDC provider used to verify identity during authentication
 	FederatedAssertion string
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
 }
 
 // NewOIDCAuthorizer returns an authorizer which uses OIDC authentication (federated client credentials)
@@ -45,6 +58,8 @@ func NewOIDCAuthorizer(ctx context.Context, options OIDCAuthorizerOptions) (Auth
 		AuxiliaryTenantIDs: options.AuxiliaryTenantIds,
 		ClientID:           options.ClientId,
 		FederatedAssertion: options.FederatedAssertion,
+		Logger:             options.Logger,
+		ClientCapabilities: clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities),
 		Scopes: []string{
 			*scope,
 		},
@@ -0,0 +1,416 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+	"golang.org/x/oauth2"
+)
+
+// onBehalfOfDefaultRetention is how long tokens are retained for a user assertion with no discernible expiry
+const onBehalfOfDefaultRetention = time.Hour
+
+// onBehalfOfEvictionInterval is how often the tokens retained for expired user assertions are evicted
+const onBehalfOfEvictionInterval = 5 * time.Minute
+
+type OnBehalfOfAuthorizerOptions struct {
+	// Environment is the Azure environment/cloud being targeted
+	Environment environments.Environment
+
+	// Api describes the Azure API being used
+	Api environments.Api
+
+	// TenantId is the tenant to authenticate against
+	TenantId string
+
+	// AuxTenantIds lists additional tenants to authenticate against, currently only
+	// used for Resource Manager when auxiliary tenants are needed.
+	// e.g. https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/authenticate-multi-tenant
+	AuxTenantIds []string
+
+	// ClientId is the client ID of the application receiving the user assertion
+	ClientId string
+
+	// ClientSecret is the client secret used to authenticate the application
+	ClientSecret string
+
+	// ClientCertificateData is a PKCS#12 archive, or a PEM or DER encoded certificate, used to authenticate the
+	// application when no ClientSecret is specified
+	ClientCertificateData []byte
+
+	// ClientCertificatePath is a path to a PKCS#12 archive, or a PEM or DER encoded certificate, on the filesystem
+	ClientCertificatePath string
+
+	// ClientCertificatePassword is the password for the PKCS#12 archive or the encrypted private key
+	ClientCertificatePassword string
+
+	// ClientCertificateKeyData is the PEM or DER encoded private key, when not included with the certificate
+	ClientCertificateKeyData []byte
+
+	// ClientCertificateKeyPath is a path to a PEM or DER encoded private key on the filesystem
+	ClientCertificateKeyPath string
+
+	// ClientCertificateSendChain specifies whether to send the certificate chain in the `x5c` header of the client
+	// assertion, which is required for subject name/issuer (SN+I) authentication
+	ClientCertificateSendChain bool
+
+	// FederatedAssertion is a client assertion dispensed by an OIDC provider, used to authenticate the application
+	// when neither a ClientSecret nor a client certificate is specified
+	FederatedAssertion string
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
+}
+
+// NewOnBehalfOfAuthorizer returns an authorizer which exchanges the user assertion received by an API for tokens to
+// call other APIs on behalf of that user, using the OAuth 2.0 On-Behalf-Of flow. The user assertion is specified for
+// each request using WithUserAssertion, and tokens are cached separately for each user assertion.
+//
+// The application is authenticated using a client secret, a client certificate or a federated assertion, in that
+// order of precedence.
+//
+// For more information see:
+// https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-on-behalf-of-flow
+func NewOnBehalfOfAuthorizer(_ context.Context, options OnBehalfOfAuthorizerOptions) (CachingAuthorizer, error) {
+	scope, err := environments.Scope(options.Api)
+	if err != nil {
+		return nil, fmt.Errorf("determining scope for %q: %+v", options.Api.Name(), err)
+	}
+
+	conf := clientCredentialsConfig{
+		Environment:        options.Environment,
+		TenantID:           options.TenantId,
+		AuxiliaryTenantIDs: options.AuxTenantIds,
+		ClientID:           options.ClientId,
+		Logger:             options.Logger,
+		ClientCapabilities: clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities),
+		Scopes: []string{
+			*scope,
+		},
+	}
+
+	switch {
+	case options.ClientSecret != "":
+		conf.ClientSecret = options.ClientSecret
+
+	case len(options.ClientCertificateData) > 0 || options.ClientCertificatePath != "":
+		opts := ClientCertificateAuthorizerOptions{
+			PrivateKeyData: options.ClientCertificateKeyData,
+			PrivateKeyPath: options.ClientCertificateKeyPath,
+		}
+		if err = opts.setCertificate(options.ClientCertificateData, options.ClientCertificatePath, options.ClientCertificatePassword); err != nil {
+			return nil, fmt.Errorf("OnBehalfOfAuthorizer: %v", err)
+		}
+		key, chain, err := opts.loadCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("OnBehalfOfAuthorizer: %v", err)
+		}
+		conf.PrivateKey = key
+		conf.Certificate = chain[0]
+		conf.CertificateChain = chain
+		conf.SendCertificateChain = options.ClientCertificateSendChain
+
+	case options.FederatedAssertion != "":
+		conf.FederatedAssertion = options.FederatedAssertion
+
+	default:
+		return nil, fmt.Errorf("OnBehalfOfAuthorizer: a client secret, client certificate or federated assertion must be specified")
+	}
+
+	return &OnBehalfOfAuthorizer{
+		conf:    &conf,
+		entries: map[string]*onBehalfOfCacheEntry{},
+	}, nil
+}
+
+// NewOnBehalfOfAuthorizerFromCredentials returns an OnBehalfOfAuthorizer which authenticates the application using the
+// client secret or client certificate configured in Credentials
+func NewOnBehalfOfAuthorizerFromCredentials(ctx context.Context, c Credentials, api environments.Api) (CachingAuthorizer, error) {
+	opts := OnBehalfOfAuthorizerOptions{
+		Environment:               c.Environment,
+		Api:                       api,
+		TenantId:                  c.TenantID,
+		AuxTenantIds:              c.AuxiliaryTenantIDs,
+		ClientId:                  c.ClientID,
+		Logger:                    c.Logger,
+		ClientCapabilities:        c.ClientCapabilities,
+		DisableClientCapabilities: c.DisableClientCapabilities,
+	}
+	if c.EnableAuthenticatingUsingClientSecret {
+		opts.ClientSecret = c.ClientSecret
+	}
+	if c.EnableAuthenticatingUsingClientCertificate {
+		opts.ClientCertificateData = c.ClientCertificateData
+		opts.ClientCertificatePath = c.ClientCertificatePath
+		opts.ClientCertificatePassword = c.ClientCertificatePassword
+		opts.ClientCertificateKeyData = c.ClientCertificateKeyData
+		opts.ClientCertificateKeyPath = c.ClientCertificateKeyPath
+		opts.ClientCertificateSendChain = c.ClientCertificateSendChain
+	}
+	return NewOnBehalfOfAuthorizer(ctx, opts)
+}
+
+type userAssertionContextKey struct{}
+
+// WithUserAssertion returns a context which specifies the user assertion, typically the access token received by an
+// API, to be exchanged by an OnBehalfOfAuthorizer
+func WithUserAssertion(ctx context.Context, assertion string) context.Context {
+	return context.WithValue(ctx, userAssertionContextKey{}, assertion)
+}
+
+// UserAssertionFromContext returns the user assertion specified using WithUserAssertion, if any
+func UserAssertionFromContext(ctx context.Context) (string, bool) {
+	assertion, ok := ctx.Value(userAssertionContextKey{}).(string)
+	return assertion, ok && assertion != ""
+}
+
+var _ CachingAuthorizer = &OnBehalfOfAuthorizer{}
+
+// OnBehalfOfAuthorizer is an Authorizer which obtains tokens on behalf of the user identified by the user assertion
+// in the request context. Tokens are cached for each user assertion until that assertion expires.
+type OnBehalfOfAuthorizer struct {
+	conf *clientCredentialsConfig
+
+	mutex        sync.Mutex
+	entries      map[string]*onBehalfOfCacheEntry
+	nextEviction time.Time
+}
+
+type onBehalfOfCacheEntry struct {
+	authorizer *CachedAuthorizer
+	expiry     time.Time
+}
+
+// Token returns an access token for the user identified by the user assertion in the context
+func (a *OnBehalfOfAuthorizer) Token(ctx context.Context, req *http.Request) (*oauth2.Token, error) {
+	authorizer, err := a.authorizerForUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.Token(ctx, req)
+}
+
+// AuxiliaryTokens returns additional tokens for auxiliary tenant IDs for the user identified by the user assertion in
+// the context, for use in multi-tenant scenarios
+func (a *OnBehalfOfAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Request) ([]*oauth2.Token, error) {
+	authorizer, err := a.authorizerForUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.AuxiliaryTokens(ctx, req)
+}
+
+// InvalidateCachedTokens invalidates the cached tokens for every user. To invalidate the tokens for a single user, use
+// InvalidateCachedTokensForUser.
+func (a *OnBehalfOfAuthorizer) InvalidateCachedTokens() error {
+	a.mutex.Lock()
+	entries := make([]*onBehalfOfCacheEntry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		entries = append(entries, entry)
+	}
+	a.mutex.Unlock()
+
+	for _, entry := range entries {
+		if err := entry.authorizer.InvalidateCachedTokens(); err != nil {
+			return fmt.Errorf("OnBehalfOfAuthorizer: %v", err)
+		}
+	}
+	return nil
+}
+
+// InvalidateCachedTokensForUser invalidates the cached tokens for the user identified by the user assertion in the
+// context, leaving the tokens cached for other users intact
+func (a *OnBehalfOfAuthorizer) InvalidateCachedTokensForUser(ctx context.Context) error {
+	_, key, err := userAssertionKey(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	entry, ok := a.entries[key]
+	a.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+	if err = entry.authorizer.InvalidateCachedTokens(); err != nil {
+		return fmt.Errorf("OnBehalfOfAuthorizer: %v", err)
+	}
+	return nil
+}
+
+// authorizerForUser returns the cached Authorizer for the user assertion in the context, building it if needed
+func (a *OnBehalfOfAuthorizer) authorizerForUser(ctx context.Context, req *http.Request) (Authorizer, error) {
+	assertion, key, err := userAssertionKey(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	if now.After(a.nextEviction) {
+		for k, entry := range a.entries {
+			if now.After(entry.expiry) {
+				delete(a.entries, k)
+			}
+		}
+		a.nextEviction = now.Add(onBehalfOfEvictionInterval)
+	}
+
+	if entry, ok := a.entries[key]; ok && !now.After(entry.expiry) {
+		return entry.authorizer, nil
+	}
+
+	entry := &onBehalfOfCacheEntry{
+		authorizer: &CachedAuthorizer{
+			Source: &onBehalfOfTokenSource{
+				conf:          a.conf,
+				userAssertion: assertion,
+			},
+			Logger: a.conf.Logger,
+		},
+		expiry: userAssertionExpiry(assertion, now),
+	}
+	a.entries[key] = entry
+	return entry.authorizer, nil
+}
+
+// userAssertionKey returns the user assertion in the context, along with the key for the tokens cached for it. The
+// context of the request is also consulted, so that a user assertion attached to an incoming request can be used.
+func userAssertionKey(ctx context.Context, req *http.Request) (string, string, error) {
+	assertion, ok := UserAssertionFromContext(ctx)
+	if !ok && req != nil {
+		assertion, ok = UserAssertionFromContext(req.Context())
+	}
+	if !ok {
+		return "", "", fmt.Errorf("OnBehalfOfAuthorizer: no user assertion was found in the context, please specify one using WithUserAssertion")
+	}
+
+	// user assertions are bearer tokens, so they are hashed rather than retained as keys
+	sum := sha256.Sum256([]byte(assertion))
+	return assertion, hex.EncodeToString(sum[:]), nil
+}
+
+// userAssertionExpiry returns the expiry of a JWT user assertion, after which it can no longer be exchanged. The
+// assertion is not validated, as this is only used to determine how long tokens obtained using it are retained.
+func userAssertionExpiry(assertion string, now time.Time) time.Time {
+	segments := strings.Split(assertion, ".")
+	if len(segments) != 3 {
+		return now.Add(onBehalfOfDefaultRetention)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segments[1], "="))
+	if err != nil {
+		return now.Add(onBehalfOfDefaultRetention)
+	}
+	var claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Expiry == 0 {
+		return now.Add(onBehalfOfDefaultRetention)
+	}
+	return time.Unix(claims.Expiry, 0)
+}
+
+var _ Authorizer = &onBehalfOfTokenSource{}
+
+// onBehalfOfTokenSource exchanges a single user assertion for tokens
+type onBehalfOfTokenSource struct {
+	conf          *clientCredentialsConfig
+	userAssertion string
+}
+
+func (s *onBehalfOfTokenSource) token(ctx context.Context, tokenUrl string) (*oauth2.Token, error) {
+	v := url.Values{
+		"assertion":           {s.userAssertion},
+		"client_id":           {s.conf.ClientID},
+		"grant_type":          {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"requested_token_use": {"on_behalf_of"},
+		"scope": []string{
+			strings.Join(s.conf.Scopes, " "),
+		},
+	}
+
+	if s.conf.ClientSecret != "" {
+		v.Set("client_secret", s.conf.ClientSecret)
+	} else {
+		assertion := s.conf.FederatedAssertion
+		if assertion == "" {
+			a, err := (&ClientAssertionAuthorizer{conf: s.conf}).assertion(tokenUrl)
+			if err != nil {
+				return nil, err
+			}
+			if a == nil {
+				return nil, fmt.Errorf("OnBehalfOfAuthorizer: assertion was nil")
+			}
+			assertion = *a
+		}
+		v.Set("client_assertion", assertion)
+		v.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	}
+
+	return clientCredentialsToken(ctx, tokenUrl, &v, s.conf.ClientCapabilities)
+}
+
+func (s *onBehalfOfTokenSource) tokenUrl(tenantId string) (string, error) {
+	if s.conf.TokenURL != "" {
+		return s.conf.TokenURL, nil
+	}
+	if s.conf.Environment.Authorization == nil {
+		return "", fmt.Errorf("no `authorization` configuration was found for this environment")
+	}
+	return tokenEndpoint(*s.conf.Environment.Authorization, tenantId), nil
+}
+
+func (s *onBehalfOfTokenSource) Token(ctx context.Context, _ *http.Request) (*oauth2.Token, error) {
+	tokenUrl, err := s.tokenUrl(s.conf.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.token(ctx, tokenUrl)
+}
+
+// AuxiliaryTokens returns additional tokens for auxiliary tenant IDs, for use in multi-tenant scenarios
+func (s *onBehalfOfTokenSource) AuxiliaryTokens(ctx context.Context, _ *http.Request) ([]*oauth2.Token, error) {
+	tokens := make([]*oauth2.Token, 0)
+
+	for _, tenantId := range s.conf.AuxiliaryTenantIDs {
+		tokenUrl, err := s.tokenUrl(tenantId)
+		if err != nil {
+			return tokens, err
+		}
+
+		token, err := s.token(ctx, tokenUrl)
+		if err != nil {
+			return tokens, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
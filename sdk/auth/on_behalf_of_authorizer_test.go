@@ -0,0 +1,139 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+)
+
+// testUserAssertion returns an unsigned JWT for the user, expiring at the specified time
+func testUserAssertion(user string, expiry time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"oid":%q,"exp":%d}`, user, expiry.Unix())))
+	return fmt.Sprintf("%s.%s.", header, payload)
+}
+
+func TestOnBehalfOfAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	authorizer, err := auth.NewOnBehalfOfAuthorizer(ctx, auth.OnBehalfOfAuthorizerOptions{
+		Environment:  env,
+		Api:          env.ResourceManager,
+		TenantId:     "00000000-1111-0000-0000-000000000000",
+		AuxTenantIds: []string{"00000000-2222-0000-0000-000000000000"},
+		ClientId:     "11111111-0000-0000-0000-000000000000",
+		ClientSecret: "supersecret",
+	})
+	if err != nil {
+		t.Fatalf("NewOnBehalfOfAuthorizer(): %v", err)
+	}
+
+	if _, err = authorizer.Token(ctx, nil); err == nil {
+		t.Fatal("expected an error when no user assertion is specified")
+	}
+
+	alice := testUserAssertion("alice", time.Now().Add(time.Hour))
+	bob := testUserAssertion("bob", time.Now().Add(time.Hour))
+
+	for _, assertion := range []string{alice, bob, alice, bob} {
+		if _, err = testObtainAccessToken(auth.WithUserAssertion(ctx, assertion), authorizer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the user assertion may instead be attached to the request
+	req, err := http.NewRequestWithContext(auth.WithUserAssertion(ctx, alice), http.MethodGet, "https://management.azure.com", http.NoBody)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	auxTokens, err := authorizer.AuxiliaryTokens(ctx, req)
+	if err != nil {
+		t.Fatalf("authorizer.AuxiliaryTokens(): %v", err)
+	}
+	if len(auxTokens) != 1 {
+		t.Fatalf("expected 1 auxiliary token, got %d", len(auxTokens))
+	}
+
+	// tokens are cached per user, so each assertion should only have been exchanged once, plus once for the auxiliary tenant
+	expected := []string{alice, bob, alice}
+	if strings.Join(server.assertions, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the assertions to be exchanged in the order alice, bob, alice, got %d exchanges", len(server.assertions))
+	}
+	if server.tenants[2] != "00000000-2222-0000-0000-000000000000" {
+		t.Fatalf("expected the auxiliary token to be requested from the auxiliary tenant, got %q", server.tenants[2])
+	}
+
+	// invalidating the tokens for one user leaves those of other users intact
+	if err = authorizer.(*auth.OnBehalfOfAuthorizer).InvalidateCachedTokensForUser(auth.WithUserAssertion(ctx, alice)); err != nil {
+		t.Fatalf("InvalidateCachedTokensForUser(): %v", err)
+	}
+	for _, assertion := range []string{alice, bob} {
+		if _, err = testObtainAccessToken(auth.WithUserAssertion(ctx, assertion), authorizer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected = append(expected, alice)
+	if strings.Join(server.assertions, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected a new token to be obtained only for alice after invalidating the tokens for alice, got %d exchanges", len(server.assertions))
+	}
+
+	if err = authorizer.InvalidateCachedTokens(); err != nil {
+		t.Fatalf("InvalidateCachedTokens(): %v", err)
+	}
+	if _, err = testObtainAccessToken(auth.WithUserAssertion(ctx, bob), authorizer); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.assertions) != 5 {
+		t.Fatalf("expected a new token to be obtained after invalidating cached tokens, got %d exchanges", len(server.assertions))
+	}
+}
+
+func TestOnBehalfOfAuthorizer_ClientCertificate(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	authorizer, err := auth.NewOnBehalfOfAuthorizer(ctx, auth.OnBehalfOfAuthorizerOptions{
+		Environment:               env,
+		Api:                       env.MicrosoftGraph,
+		TenantId:                  "00000000-1111-0000-0000-000000000000",
+		ClientId:                  "11111111-0000-0000-0000-000000000000",
+		ClientCertificateData:     []byte(dummyEcdsaCertificate),
+		ClientCertificateKeyData:  []byte(dummyEncryptedEcdsaKey),
+		ClientCertificatePassword: "keypassword",
+	})
+	if err != nil {
+		t.Fatalf("NewOnBehalfOfAuthorizer(): %v", err)
+	}
+
+	ctx = auth.WithUserAssertion(ctx, testUserAssertion("alice", time.Now().Add(time.Hour)))
+	if _, err = testObtainAccessToken(ctx, authorizer); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOnBehalfOfAuthorizer_NoClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	if _, err := auth.NewOnBehalfOfAuthorizer(ctx, auth.OnBehalfOfAuthorizerOptions{
+		Environment: env,
+		Api:         env.MicrosoftGraph,
+		TenantId:    "00000000-1111-0000-0000-000000000000",
+		ClientId:    "11111111-0000-0000-0000-000000000000",
+	}); err == nil {
+		t.Fatal("expected an error when no client credentials are specified")
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"golang.org/x/oauth2"
+)
+
+// publicClientConfig is the configuration for authenticating a user with a public client application, such as
+// with the device code flow or the authorization code flow
+type publicClientConfig struct {
+	// Environment is the national cloud environment to use
+	Environment environments.Environment
+
+	// TenantID is the tenant ID for the primary token, which defaults to `organizations`
+	TenantID string
+
+	// AuxiliaryTenantIDs is an optional list of tenant IDs for which to obtain additional tokens
+	AuxiliaryTenantIDs []string
+
+	// ClientID is the public client application's ID
+	ClientID string
+
+	// Scopes specifies a list of requested permission scopes (used for v2 tokens)
+	Scopes []string
+
+	// ClientCapabilities are advertised to the authorization service when requesting tokens
+	ClientCapabilities []string
+}
+
+// newPublicClientConfig returns a publicClientConfig, defaulting the tenant and the client to the Azure CLI application
+func newPublicClientConfig(environment environments.Environment, api environments.Api, tenantId string, auxTenantIds []string, clientId string) (*publicClientConfig, error) {
+	scope, err := environments.Scope(api)
+	if err != nil {
+		return nil, fmt.Errorf("determining scope for %q: %+v", api.Name(), err)
+	}
+	if environment.Authorization == nil {
+		return nil, fmt.Errorf("no `authorization` configuration was found for this environment")
+	}
+	if tenantId == "" {
+		tenantId = "organizations"
+	}
+	if clientId == "" {
+		clientId = environments.PublishedApis["MicrosoftAzureCli"]
+	}
+
+	return &publicClientConfig{
+		Environment:        environment,
+		TenantID:           tenantId,
+		AuxiliaryTenantIDs: auxTenantIds,
+		ClientID:           clientId,
+		Scopes: []string{
+			*scope,
+			// a refresh token is needed to obtain new access tokens without further user interaction
+			"offline_access",
+		},
+	}, nil
+}
+
+// endpoint returns the URL for the specified OAuth 2.0 endpoint, e.g. `token` or `devicecode`, in the specified tenant
+func (c *publicClientConfig) endpoint(tenantId, endpoint string) string {
+	return fmt.Sprintf("%s/%s/oauth2/v2.0/%s", c.Environment.Authorization.LoginEndpoint, tenantId, endpoint)
+}
+
+func (c *publicClientConfig) scope() string {
+	return strings.Join(c.Scopes, " ")
+}
+
+// publicClientError is an error response from the token endpoint
+type publicClientError struct {
+	StatusCode       int
+	Code             string `json:"error"`
+	Description      string `json:"error_description"`
+	CorrelationId    string `json:"correlation_id"`
+	ErrorCodes       []int  `json:"error_codes"`
+	TraceId          string `json:"trace_id"`
+	AdditionalClaims string `json:"claims"`
+}
+
+func (e *publicClientError) Error() string {
+	return fmt.Sprintf("received HTTP status %d with error %q: %s", e.StatusCode, e.Code, e.Description)
+}
+
+// publicClientRequest posts a form to the specified endpoint, unmarshaling a successful response into result
+func publicClientRequest(ctx context.Context, endpoint string, params url.Values, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBufferString(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("building request: %+v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("reading response: %v", err)
+	}
+
+	if c := resp.StatusCode; c < 200 || c > 299 {
+		e := &publicClientError{}
+		if err := json.Unmarshal(body, e); err != nil || e.Code == "" {
+			return fmt.Errorf("received HTTP status %d with response: %s", resp.StatusCode, body)
+		}
+		e.StatusCode = resp.StatusCode
+		return e
+	}
+
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("unmarshaling response: %v", err)
+	}
+	return nil
+}
+
+// publicClientToken requests a token from the token endpoint for the specified tenant, retaining any refresh token
+func (c *publicClientConfig) publicClientToken(ctx context.Context, tenantId string, params url.Values) (*oauth2.Token, error) {
+	params.Set("client_id", c.ClientID)
+	params.Set("scope", c.scope())
+	claims, err := tokenRequestClaims(ctx, c.ClientCapabilities)
+	if err != nil {
+		return nil, err
+	}
+	if claims != "" {
+		params.Set("claims", claims)
+	}
+
+	var tokenRes struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := publicClientRequest(ctx, c.endpoint(tenantId, "token"), params, &tokenRes); err != nil {
+		return nil, err
+	}
+
+	token := &oauth2.Token{
+		AccessToken:  tokenRes.AccessToken,
+		TokenType:    tokenRes.TokenType,
+		RefreshToken: tokenRes.RefreshToken,
+	}
+	if tokenRes.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tokenRes.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// refreshableTokenSource obtains tokens for a user, initially using an interactive flow and subsequently by
+// redeeming the most recent refresh token, so that the user is only prompted again if the refresh token is rejected
+type refreshableTokenSource struct {
+	conf *publicClientConfig
+
+	// acquire obtains a token by prompting the user
+	acquire func(ctx context.Context) (*oauth2.Token, error)
+
+	mutex        sync.Mutex
+	refreshToken string
+}
+
+func (s *refreshableTokenSource) Token(ctx context.Context, _ *http.Request) (*oauth2.Token, error) {
+	if s.conf == nil || s.acquire == nil {
+		return nil, fmt.Errorf("internal-error: token source not configured")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.refreshToken != "" {
+		token, err := s.refresh(ctx, s.conf.TenantID)
+		if err == nil {
+			return token, nil
+		}
+		loggerFromContext(ctx).Debug("could not redeem refresh token, the user will be prompted", "error", err)
+	}
+
+	token, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken != "" {
+		s.refreshToken = token.RefreshToken
+	}
+	return token, nil
+}
+
+// tokenCacheKey identifies the tokens obtained for the user, so that they can be persisted in a TokenCache
+func (s *refreshableTokenSource) tokenCacheKey() (TokenCacheKey, bool) {
+	if s.conf == nil || s.conf.Environment.Authorization == nil {
+		return TokenCacheKey{}, false
+	}
+	return TokenCacheKey{
+		Authority: s.conf.Environment.Authorization.LoginEndpoint,
+		TenantId:  s.conf.TenantID,
+		ClientId:  s.conf.ClientID,
+		Scope:     s.conf.scope(),
+	}, true
+}
+
+// seedRefreshToken sets the refresh token to be redeemed, unless the user has already been authenticated
+func (s *refreshableTokenSource) seedRefreshToken(refreshToken string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.refreshToken == "" {
+		s.refreshToken = refreshToken
+	}
+}
+
+// AuxiliaryTokens redeems the refresh token for the user in each of the auxiliary tenants
+func (s *refreshableTokenSource) AuxiliaryTokens(ctx context.Context, req *http.Request) ([]*oauth2.Token, error) {
+	if s.conf == nil {
+		return nil, fmt.Errorf("internal-error: token source not configured")
+	}
+
+	tokens := make([]*oauth2.Token, 0)
+	if len(s.conf.AuxiliaryTenantIDs) == 0 {
+		return tokens, nil
+	}
+
+	s.mutex.Lock()
+	hasRefreshToken := s.refreshToken != ""
+	s.mutex.Unlock()
+	if !hasRefreshToken {
+		if _, err := s.Token(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, tenantId := range s.conf.AuxiliaryTenantIDs {
+		token, err := s.refresh(ctx, tenantId)
+		if err != nil {
+			return tokens, fmt.Errorf("obtaining token for auxiliary tenant %q: %v", tenantId, err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// refresh redeems the refresh token for an access token in the specified tenant, retaining the new refresh token.
+// The caller must hold the mutex.
+func (s *refreshableTokenSource) refresh(ctx context.Context, tenantId string) (*oauth2.Token, error) {
+	token, err := s.conf.publicClientToken(ctx, tenantId, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken != "" {
+		s.refreshToken = token.RefreshToken
+	}
+	return token, nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenCache stores access tokens (and any refresh tokens) so that they can be reused until they expire, including
+// between Authorizers and, depending on the implementation, between processes.
+type TokenCache interface {
+	// Get returns the token stored for the key, and whether a token was found. Expired tokens may be returned, since
+	// they can include a refresh token.
+	Get(ctx context.Context, key TokenCacheKey) (*oauth2.Token, bool, error)
+
+	// Set stores the token for the key, replacing any existing token
+	Set(ctx context.Context, key TokenCacheKey, token *oauth2.Token) error
+
+	// Delete removes any token stored for the key
+	Delete(ctx context.Context, key TokenCacheKey) error
+}
+
+// TokenCacheKey identifies the tokens obtained by an Authorizer
+type TokenCacheKey struct {
+	// Authority is the authorization service which issued the token, e.g. the login endpoint for the environment
+	Authority string
+
+	// TenantId is the tenant for which the token was issued
+	TenantId string
+
+	// ClientId identifies the application or identity for which the token was issued
+	ClientId string
+
+	// Scope is the scope for which the token was issued
+	Scope string
+}
+
+// String returns a normalized representation of the key
+func (k TokenCacheKey) String() string {
+	return strings.ToLower(strings.Join([]string{
+		strings.TrimSuffix(k.Authority, "/"),
+		k.TenantId,
+		k.ClientId,
+		k.Scope,
+	}, "|"))
+}
+
+// tokenCacheKeyer is implemented by Authorizers which can describe the tokens they obtain, so that these can be
+// persisted in a TokenCache
+type tokenCacheKeyer interface {
+	tokenCacheKey() (TokenCacheKey, bool)
+}
+
+// refreshTokenSeeder is implemented by Authorizers which can redeem a refresh token retrieved from a TokenCache
+type refreshTokenSeeder interface {
+	seedRefreshToken(refreshToken string)
+}
+
+var _ TokenCache = &InMemoryTokenCache{}
+
+// InMemoryTokenCache is a TokenCache which stores tokens in memory, allowing them to be shared between Authorizers
+// within a process
+type InMemoryTokenCache struct {
+	mutex  sync.RWMutex
+	tokens map[string]oauth2.Token
+}
+
+// NewInMemoryTokenCache returns a TokenCache which stores tokens in memory
+func NewInMemoryTokenCache() TokenCache {
+	return &InMemoryTokenCache{
+		tokens: map[string]oauth2.Token{},
+	}
+}
+
+func (c *InMemoryTokenCache) Get(_ context.Context, key TokenCacheKey) (*oauth2.Token, bool, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	token, ok := c.tokens[key.String()]
+	if !ok {
+		return nil, false, nil
+	}
+	return &token, true, nil
+}
+
+func (c *InMemoryTokenCache) Set(_ context.Context, key TokenCacheKey, token *oauth2.Token) error {
+	if token == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]oauth2.Token{}
+	}
+	c.tokens[key.String()] = *token
+	return nil
+}
+
+func (c *InMemoryTokenCache) Delete(_ context.Context, key TokenCacheKey) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.tokens, key.String())
+	return nil
+}
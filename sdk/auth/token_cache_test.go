@@ -0,0 +1,264 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"golang.org/x/oauth2"
+)
+
+func testTokenCache(t *testing.T, cache auth.TokenCache) {
+	ctx := context.Background()
+	key := auth.TokenCacheKey{
+		Authority: "https://login.microsoftonline.com/",
+		TenantId:  "00000000-1111-0000-0000-000000000000",
+		ClientId:  "11111111-0000-0000-0000-000000000000",
+		Scope:     "https://management.azure.com/.default",
+	}
+
+	if _, found, err := cache.Get(ctx, key); err != nil || found {
+		t.Fatalf("expected no token to be found, got found=%t, err=%v", found, err)
+	}
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	if err := cache.Set(ctx, key, &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: expiry}); err != nil {
+		t.Fatalf("Set(): %v", err)
+	}
+
+	// keys are normalized
+	normalized := key
+	normalized.Authority = "https://LOGIN.microsoftonline.com"
+	token, found, err := cache.Get(ctx, normalized)
+	if err != nil || !found {
+		t.Fatalf("expected token to be found, got found=%t, err=%v", found, err)
+	}
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" || token.TokenType != "Bearer" || !token.Expiry.Equal(expiry) {
+		t.Fatalf("unexpected token returned: %+v", token)
+	}
+
+	other := key
+	other.Scope = "https://graph.microsoft.com/.default"
+	if _, found, err = cache.Get(ctx, other); err != nil || found {
+		t.Fatalf("expected no token to be found for another scope, got found=%t, err=%v", found, err)
+	}
+
+	if err = cache.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete(): %v", err)
+	}
+	if _, found, err = cache.Get(ctx, key); err != nil || found {
+		t.Fatalf("expected no token to be found after deletion, got found=%t, err=%v", found, err)
+	}
+}
+
+func TestInMemoryTokenCache(t *testing.T) {
+	testTokenCache(t, auth.NewInMemoryTokenCache())
+}
+
+func TestFileTokenCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "tokens.bin")
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	cache, err := auth.NewFileTokenCache(path, key)
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+	testTokenCache(t, cache)
+
+	if _, err = auth.NewFileTokenCache(path, []byte("too short")); err == nil {
+		t.Fatal("expected an error for an invalid key")
+	}
+}
+
+func TestFileTokenCache_SharedBetweenInstances(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tokens.bin")
+	key := bytes.Repeat([]byte{0x42}, 32)
+	cacheKey := auth.TokenCacheKey{TenantId: "tenant", ClientId: "client", Scope: "scope"}
+
+	first, err := auth.NewFileTokenCache(path, key)
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+	if err = first.Set(ctx, cacheKey, &oauth2.Token{AccessToken: "secret-access-token"}); err != nil {
+		t.Fatalf("Set(): %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading cache file: %v", err)
+	}
+	if bytes.Contains(b, []byte("secret-access-token")) {
+		t.Fatal("expected the cache file to be encrypted")
+	}
+
+	second, err := auth.NewFileTokenCache(path, key)
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+	token, found, err := second.Get(ctx, cacheKey)
+	if err != nil || !found || token.AccessToken != "secret-access-token" {
+		t.Fatalf("expected token to be found by another instance, got token=%+v, found=%t, err=%v", token, found, err)
+	}
+
+	wrongKey, err := auth.NewFileTokenCache(path, bytes.Repeat([]byte{0x24}, 32))
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+	if _, _, err = wrongKey.Get(ctx, cacheKey); err == nil {
+		t.Fatal("expected an error when decrypting with the wrong key")
+	}
+}
+
+func TestFileTokenCache_ConcurrentWriters(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "tokens.bin")
+	key := bytes.Repeat([]byte{0x42}, 16)
+
+	// separate instances don't share a mutex, so are serialized only by the lock file, as separate processes would be
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cache, err := auth.NewFileTokenCache(path, key)
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- cache.Set(ctx, auth.TokenCacheKey{Scope: fmt.Sprintf("scope%d", i)}, &oauth2.Token{AccessToken: fmt.Sprintf("token%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Set(): %v", err)
+		}
+	}
+
+	cache, err := auth.NewFileTokenCache(path, key)
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		token, found, err := cache.Get(ctx, auth.TokenCacheKey{Scope: fmt.Sprintf("scope%d", i)})
+		if err != nil || !found || token.AccessToken != fmt.Sprintf("token%d", i) {
+			t.Fatalf("expected token%d to be found, got token=%+v, found=%t, err=%v", i, token, found, err)
+		}
+	}
+	if _, err = os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock file to be removed, got %v", err)
+	}
+}
+
+func TestFileTokenCache_StaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.bin")
+	cache, err := auth.NewFileTokenCache(path, bytes.Repeat([]byte{0x42}, 16))
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+
+	// a lock file which is held by another process is waited for
+	if err = os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err = cache.Set(ctx, auth.TokenCacheKey{Scope: "scope"}, &oauth2.Token{AccessToken: "token"}); err == nil {
+		t.Fatal("expected an error whilst the lock file is held")
+	}
+	if _, err = os.Stat(path + ".lock"); err != nil {
+		t.Fatalf("expected the lock file held by another process to remain, got %v", err)
+	}
+
+	// an abandoned lock file is taken over
+	stale := time.Now().Add(-time.Hour)
+	if err = os.Chtimes(path+".lock", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = cache.Set(ctx, auth.TokenCacheKey{Scope: "scope"}, &oauth2.Token{AccessToken: "token"}); err != nil {
+		t.Fatalf("Set(): %v", err)
+	}
+
+	matches, err := filepath.Glob(path + ".lock*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Fatalf("expected no lock files to remain, got %v", matches)
+	}
+}
+
+func TestCachedAuthorizer_TokenCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := newFakeLoginServer(t)
+	env := server.environment()
+
+	cache, err := auth.NewFileTokenCache(filepath.Join(t.TempDir(), "tokens.bin"), bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatalf("NewFileTokenCache(): %v", err)
+	}
+
+	prompted := 0
+	newAuthorizer := func() auth.CachingAuthorizer {
+		authorizer, err := auth.NewDeviceCodeAuthorizer(ctx, auth.DeviceCodeAuthorizerOptions{
+			Environment: env,
+			Api:         env.ResourceManager,
+			TenantId:    "00000000-1111-0000-0000-000000000000",
+			Prompt: func(context.Context, auth.DeviceCode) error {
+				prompted++
+				return nil
+			},
+			TokenCache: cache,
+		})
+		if err != nil {
+			t.Fatalf("NewDeviceCodeAuthorizer(): %v", err)
+		}
+		return authorizer.(auth.CachingAuthorizer)
+	}
+
+	first := newAuthorizer()
+	if _, err = testObtainAccessToken(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	// a valid token in the cache is used without requesting a new token
+	if _, err = testObtainAccessToken(ctx, newAuthorizer()); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.grants) != 2 {
+		t.Fatalf("expected the cached token to be reused, got the grants %v", server.grants)
+	}
+
+	// once expired, the cached refresh token is redeemed without prompting the user
+	if err = first.InvalidateCachedTokens(); err != nil {
+		t.Fatalf("InvalidateCachedTokens(): %v", err)
+	}
+	if _, err = testObtainAccessToken(ctx, newAuthorizer()); err != nil {
+		t.Fatal(err)
+	}
+	if prompted != 1 {
+		t.Fatalf("expected the user to be prompted once, but was prompted %d times", prompted)
+	}
+	if grants := strings.Join(server.grants, ","); !strings.HasSuffix(grants, ",refresh_token") {
+		t.Fatalf("expected the cached refresh token to be redeemed, got the grants %v", server.grants)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// workloadIdentityAuthorityHostEnvVar is the environment variable which specifies the login endpoint, as set by the AKS workload identity webhook
+	workloadIdentityAuthorityHostEnvVar = "AZURE_AUTHORITY_HOST"
+
+	// workloadIdentityClientIdEnvVar is the environment variable which specifies the client ID, as set by the AKS workload identity webhook
+	workloadIdentityClientIdEnvVar = "AZURE_CLIENT_ID"
+
+	// workloadIdentityFederatedTokenFileEnvVar is the environment variable which specifies the path to the federated token file, as set by the AKS workload identity webhook
+	workloadIdentityFederatedTokenFileEnvVar = "AZURE_FEDERATED_TOKEN_FILE"
+
+	// workloadIdentityTenantIdEnvVar is the environment variable which specifies the tenant ID, as set by the AKS workload identity webhook
+	workloadIdentityTenantIdEnvVar = "AZURE_TENANT_ID"
+)
+
+type WorkloadIdentityAuthorizerOptions struct {
+	// Environment is the Azure environment/cloud being targeted
+	Environment environments.Environment
+
+	// Api describes the Azure API being used
+	Api environments.Api
+
+	// TenantId is the tenant to authenticate against. Defaults to the value of the AZURE_TENANT_ID environment variable.
+	TenantId string
+
+	// AuxiliaryTenantIds lists additional tenants to authenticate against, currently only
+	// used for Resource Manager when auxiliary tenants are needed.
+	// e.g. https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/authenticate-multi-tenant
+	AuxiliaryTenantIds []string
+
+	// ClientId is the client ID used when authenticating. Defaults to the value of the AZURE_CLIENT_ID environment variable.
+	ClientId string
+
+	// FederatedTokenFilePath is the path to a file containing the client assertion, which is read each time a token is
+	// acquired so that the assertion can be rotated. Defaults to the value of the AZURE_FEDERATED_TOKEN_FILE environment variable.
+	FederatedTokenFilePath string
+
+	// AuthorityHost optionally overrides the login endpoint for the Environment. Defaults to the value of the
+	// AZURE_AUTHORITY_HOST environment variable.
+	AuthorityHost string
+
+	// ClientCapabilities lists the capabilities advertised to the authorization service when requesting tokens. Defaults
+	// to ClientCapabilityCP1, which indicates that claims challenges can be handled, as they are by client.Client.
+	ClientCapabilities []string
+
+	// DisableClientCapabilities prevents any client capabilities from being advertised, e.g. when tokens are used by
+	// clients which cannot handle claims challenges
+	DisableClientCapabilities bool
+
+	// Logger is an optional Logger used when obtaining tokens, which defaults to logging.Default(). Sensitive values
+	// are always redacted.
+	Logger logging.Logger
+}
+
+// withDefaults returns a copy of the options, populating any unset fields from the workload identity environment variables
+func (o WorkloadIdentityAuthorizerOptions) withDefaults() WorkloadIdentityAuthorizerOptions {
+	if strings.TrimSpace(o.TenantId) == "" {
+		o.TenantId = os.Getenv(workloadIdentityTenantIdEnvVar)
+	}
+	if strings.TrimSpace(o.ClientId) == "" {
+		o.ClientId = os.Getenv(workloadIdentityClientIdEnvVar)
+	}
+	if strings.TrimSpace(o.FederatedTokenFilePath) == "" {
+		o.FederatedTokenFilePath = os.Getenv(workloadIdentityFederatedTokenFileEnvVar)
+	}
+	if strings.TrimSpace(o.AuthorityHost) == "" {
+		o.AuthorityHost = os.Getenv(workloadIdentityAuthorityHostEnvVar)
+	}
+	return o
+}
+
+// NewWorkloadIdentityAuthorizer returns an authorizer which uses workload identity authentication (federated client credentials),
+// reading the client assertion from a file which is re-read whenever it changes, such as when projected by Kubernetes.
+func NewWorkloadIdentityAuthorizer(ctx context.Context, options WorkloadIdentityAuthorizerOptions) (Authorizer, error) {
+	scope, err := environments.Scope(options.Api)
+	if err != nil {
+		return nil, fmt.Errorf("determining scope for %q: %+v", options.Api.Name(), err)
+	}
+
+	options = options.withDefaults()
+	if options.TenantId == "" {
+		return nil, fmt.Errorf("a TenantId must be specified, or the %s environment variable set", workloadIdentityTenantIdEnvVar)
+	}
+	if options.ClientId == "" {
+		return nil, fmt.Errorf("a ClientId must be specified, or the %s environment variable set", workloadIdentityClientIdEnvVar)
+	}
+	if options.FederatedTokenFilePath == "" {
+		return nil, fmt.Errorf("a FederatedTokenFilePath must be specified, or the %s environment variable set", workloadIdentityFederatedTokenFileEnvVar)
+	}
+
+	environment := options.Environment
+	if options.AuthorityHost != "" {
+		authorization := environments.Authorization{}
+		if environment.Authorization != nil {
+			authorization = *environment.Authorization
+		}
+		authorization.LoginEndpoint = strings.TrimSuffix(options.AuthorityHost, "/")
+		environment.Authorization = &authorization
+	}
+
+	conf := workloadIdentityConfig{
+		Environment:            environment,
+		TenantID:               options.TenantId,
+		AuxiliaryTenantIDs:     options.AuxiliaryTenantIds,
+		ClientID:               options.ClientId,
+		FederatedTokenFilePath: options.FederatedTokenFilePath,
+		Logger:                 options.Logger,
+		ClientCapabilities:     clientCapabilities(options.ClientCapabilities, options.DisableClientCapabilities),
+		Scopes: []string{
+			*scope,
+		},
+	}
+
+	return conf.TokenSource(ctx)
+}
+
+var _ Authorizer = &WorkloadIdentityAuthorizer{}
+
+type WorkloadIdentityAuthorizer struct {
+	conf *workloadIdentityConfig
+
+	// mutex protects the cached assertion and the file metadata used to detect when it has changed
+	mutex     sync.Mutex
+	assertion string
+	modTime   time.Time
+	size      int64
+}
+
+// federatedAssertion returns the client assertion from the federated token file, which is only re-read when the
+// file has been modified since it was last read
+func (a *WorkloadIdentityAuthorizer) federatedAssertion(ctx context.Context) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	info, err := os.Stat(a.conf.FederatedTokenFilePath)
+	if err != nil {
+		return "", fmt.Errorf("WorkloadIdentityAuthorizer: reading federated token file: %v", err)
+	}
+	if a.assertion != "" && info.ModTime().Equal(a.modTime) && info.Size() == a.size {
+		return a.assertion, nil
+	}
+
+	b, err := os.ReadFile(a.conf.FederatedTokenFilePath)
+	if err != nil {
+		return "", fmt.Errorf("WorkloadIdentityAuthorizer: reading federated token file: %v", err)
+	}
+	assertion := strings.TrimSpace(string(b))
+	if assertion == "" {
+		return "", fmt.Errorf("WorkloadIdentityAuthorizer: federated token file %q was empty", a.conf.FederatedTokenFilePath)
+	}
+
+	loggerFromContext(ctx).Debug("read federated token file", "path", a.conf.FederatedTokenFilePath, "modified", info.ModTime())
+	a.assertion = assertion
+	a.modTime = info.ModTime()
+	a.size = info.Size()
+
+	return a.assertion, nil
+}
+
+func (a *WorkloadIdentityAuthorizer) tokenSource(ctx context.Context) (Authorizer, error) {
+	if a.conf == nil {
+		return nil, fmt.Errorf("internal-error: WorkloadIdentityAuthorizer not configured")
+	}
+
+	assertion, err := a.federatedAssertion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientAssertionAuthorizer{
+		conf: &clientCredentialsConfig{
+			Environment:        a.conf.Environment,
+			TenantID:           a.conf.TenantID,
+			AuxiliaryTenantIDs: a.conf.AuxiliaryTenantIDs,
+			ClientID:           a.conf.ClientID,
+			FederatedAssertion: assertion,
+			Scopes:             a.conf.Scopes,
+			ClientCapabilities: a.conf.ClientCapabilities,
+		},
+	}, nil
+}
+
+func (a *WorkloadIdentityAuthorizer) Token(ctx context.Context, req *http.Request) (*oauth2.Token, error) {
+	source, err := a.tokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return source.Token(ctx, req)
+}
+
+// AuxiliaryTokens returns additional tokens for auxiliary tenant IDs, for use in multi-tenant scenarios
+func (a *WorkloadIdentityAuthorizer) AuxiliaryTokens(ctx context.Context, req *http.Request) ([]*oauth2.Token, error) {
+	source, err := a.tokenSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return source.AuxiliaryTokens(ctx, req)
+}
+
+func (a *WorkloadIdentityAuthorizer) tokenCacheKey() (TokenCacheKey, bool) {
+	if a.conf == nil {
+		return TokenCacheKey{}, false
+	}
+	conf := clientCredentialsConfig{
+		Environment: a.conf.Environment,
+		TenantID:    a.conf.TenantID,
+		ClientID:    a.conf.ClientID,
+		Scopes:      a.conf.Scopes,
+	}
+	return conf.tokenCacheKey()
+}
+
+type workloadIdentityConfig struct {
+	// Environment is the national cloud environment to use
+	Environment environments.Environment
+
+	// TenantID is the required tenant ID for the primary token
+	TenantID string
+
+	// AuxiliaryTenantIDs is an optional list of tenant IDs for which to obtain additional tokens
+	AuxiliaryTenantIDs []string
+
+	// ClientID is the application's ID.
+	ClientID string
+
+	// FederatedTokenFilePath is the path to the file containing the client assertion
+	FederatedTokenFilePath string
+
+	// Scopes specifies a list of requested permission scopes (used for v2 tokens)
+	Scopes []string
+
+	// ClientCapabilities are advertised to the authorization service when requesting tokens
+	ClientCapabilities []string
+
+	// Logger is used when obtaining tokens
+	Logger logging.Logger
+}
+
+func (c *workloadIdentityConfig) TokenSource(_ context.Context) (Authorizer, error) {
+	return &CachedAuthorizer{
+		Source: &WorkloadIdentityAuthorizer{
+			conf: c,
+		},
+		Logger: c.Logger,
+	}, nil
+}
@@ -11,18 +11,16 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
-	"math"
 	"net"
 	"net/http"
 	"net/url"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/auth"
 	"github.com/hashicorp/go-azure-sdk/sdk/internal/accept"
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"github.com/hashicorp/go-azure-sdk/sdk/odata"
 	"github.com/hashicorp/go-retryablehttp"
 )
@@ -74,12 +72,41 @@ func RetryableErrorHandler(resp *http.Response, err error, _ int) (*http.Respons
 	return resp, err
 }
 
+// DefaultTransport is the connection-pooled http.RoundTripper which is shared by all Clients that don't specify a
+// Transport, so that connections are reused across requests from all API clients.
+var DefaultTransport http.RoundTripper = defaultTransport()
+
+func defaultTransport() *http.Transport {
+	tlsConfig := tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			d := &net.Dialer{Resolver: &net.Resolver{}}
+			return d.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig:       &tlsConfig,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
+	}
+}
+
 // Request embeds *http.Request and adds useful metadata
 type Request struct {
 	RetryFunc        RequestRetryFunc
+	RetryPolicy      *RetryPolicy
 	ValidStatusCodes []int
 	ValidStatusFunc  ValidStatusFunc
 
+	// MaxConsistencyRetries optionally limits the number of times the request is retried because RetryFunc matched
+	// the response, each time the request is executed. When zero, these retries are only limited by the RetryPolicy.
+	MaxConsistencyRetries int
+
 	Client BaseClient
 	Pager  odata.CustomPager
 
@@ -136,6 +163,44 @@ func (r *Request) ExecutePaged(ctx context.Context) (*Response, error) {
 	return r.Client.ExecutePaged(ctx, r)
 }
 
+// ValidateResponse determines whether the status of a response is valid for the Request, returning a *ResponseError
+// when it is not. The OData of the response should already have been extracted, as is done by Execute.
+func (r *Request) ValidateResponse(resp *Response) error {
+	if containsStatusCode(r.ValidStatusCodes, resp.StatusCode) {
+		return nil
+	}
+
+	// The status code didn't match, but we also need to check the ValidStatusFUnc, if provided
+	// Note that the odata argument here is a best-effort and may be nil
+	if f := r.ValidStatusFunc; f != nil && f(resp.Response, resp.OData) {
+		return nil
+	}
+
+	// Parse the error from the response, so that it can be inspected by the caller using errors.As
+	respErr, err := newResponseError(resp)
+	if err != nil {
+		return err
+	}
+	return respErr
+}
+
+// clone returns a copy of the Request which can be sent independently, so that the Request itself is not mutated
+// and can be sent again. Any request body is buffered so that it can be read by both.
+func (r *Request) clone(ctx context.Context) (*Request, error) {
+	out := *r
+	out.Request = r.Request.Clone(ctx)
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %v", err)
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return &out, nil
+}
+
 // IsIdempotent determines whether a Request can be safely retried when encountering a connection failure
 func (r *Request) IsIdempotent() bool {
 	switch strings.ToUpper(r.Method) {
@@ -270,11 +335,39 @@ type Client struct {
 	// This does not impact handling of retries related to rate limiting, which are always performed.
 	DisableRetries bool
 
+	// RetryPolicy optionally configures how failed requests are retried, and can be overridden for an individual
+	// Request. When nil, DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+
 	// RequestMiddlewares is a slice of functions that are called in order before a request is sent
 	RequestMiddlewares *[]RequestMiddleware
 
 	// ResponseMiddlewares is a slice of functions that are called in order before a response is parsed and returned
 	ResponseMiddlewares *[]ResponseMiddleware
+
+	// Transport is an optional http.RoundTripper used to send requests, for example to configure proxy authentication,
+	// custom certificate authorities or test doubles. When nil, the shared DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Tracer is an optional Tracer used to start a span for each HTTP attempt
+	Tracer Tracer
+
+	// Metrics is an optional Metrics used to record retries, throttling and latency for each HTTP attempt
+	Metrics Metrics
+
+	// Logger is an optional Logger used to log requests and retries. Sensitive values are always redacted.
+	// When nil, logging.Default() is used.
+	Logger logging.Logger
+
+	// DisablePollingDelays causes pollers for long-running operations to poll again immediately, rather than waiting
+	// for the poll interval. This is intended for replaying recorded responses in tests.
+	DisablePollingDelays bool
+
+	// serviceName is the name of the API service, used when instrumenting requests
+	serviceName string
+
+	// apiVersion is the default API version for this client, used when instrumenting requests
+	apiVersion string
 }
 
 // NewClient returns a new Client configured with sensible defaults
@@ -284,8 +377,10 @@ func NewClient(baseUri string, serviceName, apiVersion string) *Client {
 		fmt.Sprintf("%s/%s", serviceName, apiVersion),
 	}
 	return &Client{
-		BaseUri:   baseUri,
-		UserAgent: fmt.Sprintf("HashiCorp/go-azure-sdk (%s)", strings.Join(segments, " ")),
+		BaseUri:     baseUri,
+		UserAgent:   fmt.Sprintf("HashiCorp/go-azure-sdk (%s)", strings.Join(segments, " ")),
+		serviceName: serviceName,
+		apiVersion:  apiVersion,
 	}
 }
 
@@ -321,6 +416,7 @@ func (c *Client) NewRequest(ctx context.Context, input RequestOptions) (*Request
 		Client:           c,
 		Request:          req,
 		Pager:            input.Pager,
+		RetryFunc:        input.RetryFunc,
 		ValidStatusCodes: input.ExpectedStatusCodes,
 	}
 
@@ -334,14 +430,8 @@ func (c *Client) Execute(ctx context.Context, req *Request) (*Response, error) {
 	}
 
 	// Authorize the request
-	if c.AuthorizeRequest != nil {
-		if err := c.AuthorizeRequest(ctx, req.Request, c.Authorizer); err != nil {
-			return nil, fmt.Errorf("authorizing request: %+v", err)
-		}
-	} else if c.Authorizer != nil {
-		if err := auth.SetAuthHeader(ctx, req.Request, c.Authorizer); err != nil {
-			return nil, fmt.Errorf("authorizing request: %+v", err)
-		}
+	if err := c.authorize(ctx, req.Request); err != nil {
+		return nil, err
 	}
 
 	var err error
@@ -356,8 +446,17 @@ func (c *Client) Execute(ctx context.Context, req *Request) (*Response, error) {
 		req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
 	}
 
+	// Determine the retry policy for this request
+	retryPolicy := DefaultRetryPolicy()
+	if req.RetryPolicy != nil {
+		retryPolicy = req.RetryPolicy.WithDefaults()
+	} else if c.RetryPolicy != nil {
+		retryPolicy = c.RetryPolicy.WithDefaults()
+	}
+
 	// Instantiate a RetryableHttp client and configure its CheckRetry func
-	r := c.retryableClient(func(ctx context.Context, r *http.Response, err error) (bool, error) {
+	consistencyRetries := 0
+	r := c.retryableClient(retryPolicy, func(ctx context.Context, r *http.Response, err error) (bool, error) {
 		// First check for badly malformed responses
 		if r == nil {
 			if req.IsIdempotent() {
@@ -368,12 +467,7 @@ func (c *Client) Execute(ctx context.Context, req *Request) (*Response, error) {
 
 		// Eventual consistency checks
 		if !c.DisableRetries {
-			if r.StatusCode == http.StatusFailedDependency {
-				return true, nil
-			}
-
-			// Some APIs don't return a response in time
-			if r.StatusCode == http.StatusRequestTimeout {
+			if containsStatusCode(retryPolicy.RetryableStatusCodes, r.StatusCode) {
 				return true, nil
 			}
 
@@ -383,9 +477,13 @@ func (c *Client) Execute(ctx context.Context, req *Request) (*Response, error) {
 
 			if f := req.RetryFunc; f != nil {
 				shouldRetry, err := f(r, o)
-				if err != nil || shouldRetry {
+				if err != nil {
 					return shouldRetry, err
 				}
+				if shouldRetry && (req.MaxConsistencyRetries <= 0 || consistencyRetries < req.MaxConsistencyRetries) {
+					consistencyRetries++
+					return true, nil
+				}
 			}
 		}
 
@@ -417,6 +515,32 @@ func (c *Client) Execute(ctx context.Context, req *Request) (*Response, error) {
 		return resp, fmt.Errorf("HTTP response was nil; connection may have been reset")
 	}
 
+	// Handle a claims challenge, e.g. when the token has been revoked by Continuous Access Evaluation, by obtaining a
+	// new token with the requested claims and replaying the request once. Cached tokens are replaced by the
+	// Authorizer when claims are requested, so only the token used for this request is affected.
+	if claims, ok := auth.ClaimsChallenge(resp.Response); ok && c.Authorizer != nil {
+		if _, replayed := auth.ClaimsFromContext(ctx); !replayed {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+			resp.Body.Close()
+
+			if err = c.authorize(auth.WithClaims(ctx, claims), req.Request); err != nil {
+				return resp, fmt.Errorf("handling claims challenge: %+v", err)
+			}
+			if reqBody != nil {
+				req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+			}
+
+			c.RedactingLogger().Debug("replaying request with claims challenge", "method", req.Method, "url", req.URL.String())
+			resp.Response, err = client.Do(req.Request)
+			if err != nil {
+				return resp, err
+			}
+			if resp.Response == nil {
+				return resp, fmt.Errorf("HTTP response was nil; connection may have been reset")
+			}
+		}
+	}
+
 	// Configure any ResponseMiddlewares
 	if c.ResponseMiddlewares != nil {
 		for _, m := range *c.ResponseMiddlewares {
@@ -433,37 +557,25 @@ func (c *Client) Execute(ctx context.Context, req *Request) (*Response, error) {
 	resp.OData, _ = odata.FromResponse(resp.Response)
 
 	// Determine whether response status is valid
-	if !containsStatusCode(req.ValidStatusCodes, resp.StatusCode) {
-		// The status code didn't match, but we also need to check the ValidStatusFUnc, if provided
-		// Note that the odata argument here is a best-effort and may be nil
-		if f := req.ValidStatusFunc; f != nil && f(resp.Response, resp.OData) {
-			return resp, nil
-		}
-
-		// Determine suitable error text
-		var errText string
-		switch {
-		case resp.OData != nil && resp.OData.Error != nil && resp.OData.Error.String() != "":
-			errText = fmt.Sprintf("error: %s", resp.OData.Error)
-
-		default:
-			defer resp.Body.Close()
+	if err = req.ValidateResponse(resp); err != nil {
+		return resp, err
+	}
 
-			respBody, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return resp, fmt.Errorf("unexpected status %d, could not read response body", resp.StatusCode)
-			}
-			if len(respBody) == 0 {
-				return resp, fmt.Errorf("unexpected status %d received with no body", resp.StatusCode)
-			}
+	return resp, nil
+}
 
-			errText = fmt.Sprintf("response: %s", respBody)
+// authorize sets the Authorization header for the request, using AuthorizeRequest when configured
+func (c *Client) authorize(ctx context.Context, req *http.Request) error {
+	if c.AuthorizeRequest != nil {
+		if err := c.AuthorizeRequest(ctx, req, c.Authorizer); err != nil {
+			return fmt.Errorf("authorizing request: %+v", err)
+		}
+	} else if c.Authorizer != nil {
+		if err := auth.SetAuthHeader(ctx, req, c.Authorizer); err != nil {
+			return fmt.Errorf("authorizing request: %+v", err)
 		}
-
-		return resp, fmt.Errorf("unexpected status %d with %s", resp.StatusCode, errText)
 	}
-
-	return resp, nil
+	return nil
 }
 
 // ExecutePaged automatically pages through the results of Execute
@@ -544,6 +656,11 @@ func (c *Client) ExecutePaged(ctx context.Context, req *Request) (*Response, err
 		nextOdata.Value = &value
 	}
 
+	// The total count is only returned with the first page
+	if nextOdata.Count == nil {
+		nextOdata.Count = firstOdata.Count
+	}
+
 	// Marshal the entire result, along with fields from the final page
 	newJson, err := json.Marshal(nextOdata)
 	if err != nil {
@@ -556,56 +673,62 @@ func (c *Client) ExecutePaged(ctx context.Context, req *Request) (*Response, err
 	return resp, nil
 }
 
-// retryableClient instantiates a new *retryablehttp.Client having the provided checkRetry func
-func (c *Client) retryableClient(checkRetry retryablehttp.CheckRetry) (r *retryablehttp.Client) {
+// retryableClient instantiates a new *retryablehttp.Client having the provided retry policy and checkRetry func
+func (c *Client) retryableClient(retryPolicy RetryPolicy, checkRetry retryablehttp.CheckRetry) (r *retryablehttp.Client) {
 	r = retryablehttp.NewClient()
 
-	r.Backoff = func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
-		if resp != nil {
-			// Always look for Retry-After header
-			if s, ok := resp.Header["Retry-After"]; ok {
-				if sleep, err := strconv.ParseInt(s[0], 10, 64); err == nil {
-					return time.Second * time.Duration(sleep)
-				}
-			}
-		}
-
-		// Default exponential backoff
-		mult := math.Pow(2, float64(attemptNum)) * float64(min)
-		sleep := time.Duration(mult)
-		if float64(sleep) != mult || sleep > max {
-			sleep = max
-		}
-		return sleep
+	r.Backoff = func(_, _ time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		return retryPolicy.Backoff(attemptNum, resp)
 	}
+	r.RetryMax = retryPolicy.MaxAttempts - 1
+	r.RetryWaitMin = retryPolicy.MinBackoff
+	r.RetryWaitMax = retryPolicy.MaxBackoff
 
 	r.CheckRetry = checkRetry
 	r.ErrorHandler = RetryableErrorHandler
-	r.Logger = log.Default()
-
-	tlsConfig := tls.Config{
-		MinVersion: tls.VersionTLS12,
+	r.Logger = c.RedactingLogger()
+
+	transport := c.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+	if c.Tracer != nil || c.Metrics != nil {
+		transport = &instrumentedTransport{
+			apiVersion:  c.apiVersion,
+			metrics:     c.Metrics,
+			serviceName: c.serviceName,
+			tracer:      c.Tracer,
+			transport:   transport,
+		}
 	}
 	r.HTTPClient = &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				d := &net.Dialer{Resolver: &net.Resolver{}}
-				return d.DialContext(ctx, network, addr)
-			},
-			TLSClientConfig:       &tlsConfig,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
-		},
+		Transport: transport,
+	}
+
+	if c.Metrics != nil {
+		r.RequestLogHook = func(_ retryablehttp.Logger, req *http.Request, attemptNum int) {
+			if attemptNum > 0 {
+				c.Metrics.AddCounter(req.Context(), MetricRequestRetries, 1,
+					Attribute{Key: AttributeHttpMethod, Value: req.Method},
+					Attribute{Key: AttributeServerAddress, Value: req.URL.Host},
+				)
+			}
+		}
 	}
 
 	return
 }
 
+// RedactingLogger returns a redacting Logger which wraps the configured Logger, or the default Logger when none is
+// configured, for logging by clients built on this Client
+func (c *Client) RedactingLogger() logging.Logger {
+	logger := c.Logger
+	if logger == nil {
+		logger = logging.Default()
+	}
+	return logging.NewRedactingLogger(logger)
+}
+
 // containsStatusCode determines whether the returned status code is in the []int of expected status codes.
 func containsStatusCode(expected []int, actual int) bool {
 	for _, v := range expected {
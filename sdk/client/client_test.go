@@ -15,10 +15,13 @@ import (
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
 	"github.com/hashicorp/go-azure-sdk/sdk/internal/test"
 	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+	"golang.org/x/oauth2"
 )
 
 var _ BaseClient = &testClient{}
@@ -478,3 +481,203 @@ func unmarshalResponse(body io.ReadCloser, unmarshal func(in []byte) error) erro
 
 	return unmarshal(respBody)
 }
+
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body:    io.NopCloser(bytes.NewBufferString(`{}`)),
+		Request: req,
+	}, nil
+}
+
+func TestClientTransport(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	transport := &countingTransport{}
+	c := &testClient{
+		Client: NewClient("https://example.local", "example", "2020-01-01"),
+	}
+	c.Transport = transport
+
+	for i := 0; i < 2; i++ {
+		req, err := c.NewRequest(ctx, RequestOptions{
+			ContentType: "application/json",
+			ExpectedStatusCodes: []int{
+				http.StatusOK,
+			},
+			HttpMethod: http.MethodGet,
+			Path:       "/things",
+		})
+		if err != nil {
+			t.Fatalf("building request: %+v", err)
+		}
+		if _, err = req.Execute(ctx); err != nil {
+			t.Fatalf("executing request: %+v", err)
+		}
+	}
+
+	if transport.requests != 2 {
+		t.Fatalf("expected 2 requests to be sent using the custom transport but got %d", transport.requests)
+	}
+}
+
+func TestClientDefaultTransportIsShared(t *testing.T) {
+	first := NewClient("https://first.local", "first", "2020-01-01")
+	second := NewClient("https://second.local", "second", "2020-01-01")
+
+	if first.retryableClient(DefaultRetryPolicy(), nil).HTTPClient.Transport != DefaultTransport {
+		t.Fatalf("expected the first client to use the DefaultTransport")
+	}
+	if second.retryableClient(DefaultRetryPolicy(), nil).HTTPClient.Transport != DefaultTransport {
+		t.Fatalf("expected the second client to use the DefaultTransport")
+	}
+}
+
+// claimsChallengeAuthorizer returns a different token when claims are requested
+type claimsChallengeAuthorizer struct {
+	claims      []string
+	invalidated int
+}
+
+func (a *claimsChallengeAuthorizer) Token(ctx context.Context, _ *http.Request) (*oauth2.Token, error) {
+	if claims, ok := auth.ClaimsFromContext(ctx); ok {
+		a.claims = append(a.claims, claims)
+		return &oauth2.Token{AccessToken: "token-with-claims", TokenType: "Bearer"}, nil
+	}
+	return &oauth2.Token{AccessToken: "token", TokenType: "Bearer"}, nil
+}
+
+func (a *claimsChallengeAuthorizer) AuxiliaryTokens(_ context.Context, _ *http.Request) ([]*oauth2.Token, error) {
+	return nil, nil
+}
+
+func (a *claimsChallengeAuthorizer) InvalidateCachedTokens() error {
+	a.invalidated++
+	return nil
+}
+
+// claimsChallengeTransport issues a claims challenge unless the request is authorized with a token including claims
+type claimsChallengeTransport struct {
+	bodies []string
+}
+
+func (t *claimsChallengeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+	}
+	t.bodies = append(t.bodies, string(body))
+
+	resp := &http.Response{
+		Body:    io.NopCloser(bytes.NewBufferString("{}")),
+		Header:  http.Header{"Content-Type": []string{"application/json"}},
+		Request: req,
+	}
+	if req.Header.Get("Authorization") == "Bearer token-with-claims" {
+		resp.StatusCode = http.StatusOK
+		return resp, nil
+	}
+
+	resp.StatusCode = http.StatusUnauthorized
+	resp.Header.Set("WWW-Authenticate", `Bearer realm="", authorization_uri="https://login.microsoftonline.com/common/oauth2/authorize", error="insufficient_claims", claims="eyJhY2Nlc3NfdG9rZW4iOnsibmJmIjp7ImVzc2VudGlhbCI6dHJ1ZSwidmFsdWUiOiIxNzI2MDc3NTk1In19fQ=="`)
+	return resp, nil
+}
+
+func TestClientClaimsChallenge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	transport := &claimsChallengeTransport{}
+	authorizer := &claimsChallengeAuthorizer{}
+	c := &testClient{
+		Client: NewClient("https://example.local", "example", "2020-01-01"),
+	}
+	c.Authorizer = authorizer
+	c.Transport = transport
+	middlewareCalls := 0
+	c.RequestMiddlewares = &[]RequestMiddleware{
+		func(r *http.Request) (*http.Request, error) {
+			middlewareCalls++
+			return r, nil
+		},
+	}
+
+	req, err := c.NewRequest(ctx, RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodPost,
+		Path:       "/things",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	if err = req.Marshal(map[string]string{"name": "example"}); err != nil {
+		t.Fatalf("marshaling request: %+v", err)
+	}
+	if _, err = req.Execute(ctx); err != nil {
+		t.Fatalf("executing request: %+v", err)
+	}
+
+	expectedClaims := `{"access_token":{"nbf":{"essential":true,"value":"1726077595"}}}`
+	if len(authorizer.claims) != 1 || authorizer.claims[0] != expectedClaims {
+		t.Fatalf("expected a token to be requested once with the claims %q, got %q", expectedClaims, authorizer.claims)
+	}
+	if authorizer.invalidated != 0 {
+		t.Fatalf("expected the cached tokens for other requests not to be invalidated, got %d invalidations", authorizer.invalidated)
+	}
+	if middlewareCalls != 1 {
+		t.Fatalf("expected the request middlewares to be called once, got %d", middlewareCalls)
+	}
+	if len(transport.bodies) != 2 || transport.bodies[0] != transport.bodies[1] || transport.bodies[1] == "" {
+		t.Fatalf("expected the request to be replayed once with the same body, got %q", transport.bodies)
+	}
+}
+
+func TestClientClaimsChallengeReplayedOnce(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	transport := &claimsChallengeTransport{}
+	c := &testClient{
+		Client: NewClient("https://example.local", "example", "2020-01-01"),
+	}
+	// the authorizer never satisfies the challenge
+	c.Authorizer = &test.TestAuthorizer{}
+	c.Transport = transport
+
+	req, err := c.NewRequest(ctx, RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodGet,
+		Path:       "/things",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	resp, err := req.Execute(ctx)
+	if err == nil {
+		t.Fatal("expected an error when the claims challenge is not satisfied")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 response to be returned")
+	}
+	if len(transport.bodies) != 2 {
+		t.Fatalf("expected the request to be sent twice, got %d", len(transport.bodies))
+	}
+}
@@ -65,7 +65,7 @@ func (c *BaseClient) NewRequest(ctx context.Context, input client.RequestOptions
 	}
 
 	req.URL.RawQuery = query.Encode()
-	req.RetryFunc = client.RequestRetryAny(storageDefaultRetryFunctions...)
+	req.RetryFunc = client.RequestRetryAny(client.RequestRetryAny(storageDefaultRetryFunctions...), input.RetryFunc)
 	req.ValidStatusCodes = input.ExpectedStatusCodes
 
 	return req, nil
@@ -0,0 +1,167 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Attribute keys used when instrumenting requests and polling operations
+const (
+	AttributeApiVersion           = "azure.api_version"
+	AttributeCorrelationRequestId = "azure.correlation_request_id"
+	AttributeHttpMethod           = "http.request.method"
+	AttributeHttpStatusCode       = "http.response.status_code"
+	AttributePollingStatus        = "azure.polling.status"
+	AttributeRequestId            = "azure.request_id"
+	AttributeServerAddress        = "server.address"
+	AttributeServiceName          = "azure.service_name"
+	AttributeUrl                  = "url.full"
+)
+
+// Metric names recorded when instrumenting requests and polling operations
+const (
+	// MetricPollDuration is a histogram of the duration of each poll of a long-running operation
+	MetricPollDuration = "azure.sdk.poll.duration"
+
+	// MetricRequestDuration is a histogram of the duration of each HTTP attempt
+	MetricRequestDuration = "azure.sdk.request.duration"
+
+	// MetricRequestRetries is a counter of HTTP attempts which were retries of an earlier attempt
+	MetricRequestRetries = "azure.sdk.request.retries"
+
+	// MetricRequestThrottles is a counter of HTTP attempts which were throttled by the API (i.e. returned a 429)
+	MetricRequestThrottles = "azure.sdk.request.throttles"
+)
+
+// Attribute is a key-value pair describing a span or a metric
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts spans for requests and polling operations. This can be implemented using OpenTelemetry (or any other
+// tracing library) without this SDK depending on a specific version of it.
+type Tracer interface {
+	// Start starts a new span with the provided name and attributes, returning a context containing the new span
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+
+	// Inject propagates the span contained within ctx into the headers of an outgoing request, for example
+	// as a W3C `traceparent` header
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a single unit of work started by a Tracer
+type Span interface {
+	// SetAttributes adds or updates attributes for the span
+	SetAttributes(attributes ...Attribute)
+
+	// RecordError records that the unit of work described by the span failed
+	RecordError(err error)
+
+	// End completes the span
+	End()
+}
+
+// Metrics records counters and histograms for requests and polling operations. This can be implemented using
+// OpenTelemetry (or any other metrics library) without this SDK depending on a specific version of it.
+type Metrics interface {
+	// AddCounter adds the provided value to the named counter
+	AddCounter(ctx context.Context, name string, value int64, attributes ...Attribute)
+
+	// RecordDuration records the provided duration in the named histogram
+	RecordDuration(ctx context.Context, name string, duration time.Duration, attributes ...Attribute)
+}
+
+var _ http.RoundTripper = &instrumentedTransport{}
+
+// instrumentedTransport wraps a http.RoundTripper, starting a span and recording metrics for each HTTP attempt
+type instrumentedTransport struct {
+	apiVersion  string
+	metrics     Metrics
+	serviceName string
+	tracer      Tracer
+	transport   http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	apiVersion := t.apiVersion
+	if req.URL != nil {
+		if v := req.URL.Query().Get("api-version"); v != "" {
+			apiVersion = v
+		}
+	}
+
+	// metrics are only recorded with attributes having a bounded set of values, so that the cardinality of each metric
+	// remains low regardless of the number of requests
+	metricAttributes := []Attribute{
+		{Key: AttributeHttpMethod, Value: req.Method},
+		{Key: AttributeServerAddress, Value: req.URL.Host},
+	}
+
+	attributes := []Attribute{
+		{Key: AttributeServiceName, Value: t.serviceName},
+		{Key: AttributeApiVersion, Value: apiVersion},
+		{Key: AttributeHttpMethod, Value: req.Method},
+		{Key: AttributeServerAddress, Value: req.URL.Host},
+		{Key: AttributeUrl, Value: spanUrl(req.URL)},
+	}
+
+	var span Span
+	if t.tracer != nil {
+		ctx, span = t.tracer.Start(ctx, "HTTP "+req.Method, attributes...)
+		defer span.End()
+
+		// a RoundTripper should not modify the original request
+		req = req.Clone(ctx)
+		t.tracer.Inject(ctx, req.Header)
+	}
+
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+
+	if resp != nil {
+		attributes = append(attributes, Attribute{Key: AttributeHttpStatusCode, Value: resp.StatusCode})
+		metricAttributes = append(metricAttributes, Attribute{Key: AttributeHttpStatusCode, Value: resp.StatusCode})
+		if v := resp.Header.Get("X-Ms-Request-Id"); v != "" {
+			attributes = append(attributes, Attribute{Key: AttributeRequestId, Value: v})
+		}
+		if v := resp.Header.Get("X-Ms-Correlation-Request-Id"); v != "" {
+			attributes = append(attributes, Attribute{Key: AttributeCorrelationRequestId, Value: v})
+		}
+	}
+
+	if span != nil {
+		span.SetAttributes(attributes...)
+		if err != nil {
+			span.RecordError(err)
+		}
+	}
+
+	if t.metrics != nil {
+		t.metrics.RecordDuration(ctx, MetricRequestDuration, time.Since(start), metricAttributes...)
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			t.metrics.AddCounter(ctx, MetricRequestThrottles, 1, metricAttributes...)
+		}
+	}
+
+	return resp, err
+}
+
+// spanUrl returns the URL of a request for use as a span attribute. The query string is removed since it can contain
+// credentials, such as the signature of a SAS token.
+func spanUrl(u *url.URL) string {
+	out := *u
+	out.User = nil
+	out.RawQuery = ""
+	out.ForceQuery = false
+	out.Fragment = ""
+	out.RawFragment = ""
+	return out.String()
+}
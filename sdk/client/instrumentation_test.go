@@ -0,0 +1,187 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+type testSpanContextKey struct{}
+
+func (t *testTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &testSpan{
+		name:       name,
+		attributes: map[string]interface{}{},
+	}
+	span.SetAttributes(attributes...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanContextKey{}, span), span
+}
+
+func (t *testTracer) Inject(ctx context.Context, header http.Header) {
+	if _, ok := ctx.Value(testSpanContextKey{}).(*testSpan); ok {
+		header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	}
+}
+
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttributes(attributes ...Attribute) {
+	for _, a := range attributes {
+		s.attributes[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+type testMetrics struct {
+	mu            sync.Mutex
+	counters      map[string]int64
+	durations     map[string]int
+	attributeKeys map[string]bool
+}
+
+func (m *testMetrics) AddCounter(_ context.Context, name string, value int64, attributes ...Attribute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name] += value
+	m.recordAttributeKeys(attributes)
+}
+
+func (m *testMetrics) RecordDuration(_ context.Context, name string, _ time.Duration, attributes ...Attribute) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations[name]++
+	m.recordAttributeKeys(attributes)
+}
+
+func (m *testMetrics) recordAttributeKeys(attributes []Attribute) {
+	for _, a := range attributes {
+		m.attributeKeys[a.Key] = true
+	}
+}
+
+func TestClientInstrumentation(t *testing.T) {
+	attempts := 0
+	traceParents := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		traceParents = append(traceParents, r.Header.Get("Traceparent"))
+		w.Header().Set("X-Ms-Request-Id", "11111111-1111-1111-1111-111111111111")
+		w.Header().Set("X-Ms-Correlation-Request-Id", "22222222-2222-2222-2222-222222222222")
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tracer := &testTracer{}
+	metrics := &testMetrics{
+		counters:      map[string]int64{},
+		durations:     map[string]int{},
+		attributeKeys: map[string]bool{},
+	}
+	c := &testClient{
+		Client: NewClient(server.URL, "example", "2020-01-01"),
+	}
+	c.Tracer = tracer
+	c.Metrics = metrics
+
+	req, err := c.NewRequest(ctx, RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodGet,
+		Path:       "/things?sv=2022-11-02&sig=c2VjcmV0",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	if _, err = req.Execute(ctx); err != nil {
+		t.Fatalf("executing request: %+v", err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected a span for each of the 2 attempts but got %d", len(tracer.spans))
+	}
+	for i, span := range tracer.spans {
+		if !span.ended {
+			t.Fatalf("expected span %d to have ended", i)
+		}
+		if v := span.attributes[AttributeServiceName]; v != "example" {
+			t.Fatalf("expected span %d to have the service name %q but got %v", i, "example", v)
+		}
+		if v := span.attributes[AttributeApiVersion]; v != "2020-01-01" {
+			t.Fatalf("expected span %d to have the api version %q but got %v", i, "2020-01-01", v)
+		}
+		if v := span.attributes[AttributeRequestId]; v != "11111111-1111-1111-1111-111111111111" {
+			t.Fatalf("expected span %d to have the request ID attribute but got %v", i, v)
+		}
+		if v := span.attributes[AttributeCorrelationRequestId]; v != "22222222-2222-2222-2222-222222222222" {
+			t.Fatalf("expected span %d to have the correlation request ID attribute but got %v", i, v)
+		}
+		if v := span.attributes[AttributeUrl]; v != server.URL+"/things" {
+			t.Fatalf("expected span %d to have the URL %q without the query string but got %v", i, server.URL+"/things", v)
+		}
+	}
+	if v := tracer.spans[0].attributes[AttributeHttpStatusCode]; v != http.StatusTooManyRequests {
+		t.Fatalf("expected the first span to have the status code 429 but got %v", v)
+	}
+	if v := tracer.spans[1].attributes[AttributeHttpStatusCode]; v != http.StatusOK {
+		t.Fatalf("expected the second span to have the status code 200 but got %v", v)
+	}
+
+	for i, v := range traceParents {
+		if v == "" {
+			t.Fatalf("expected a traceparent header to be sent with attempt %d", i)
+		}
+	}
+
+	if v := metrics.counters[MetricRequestThrottles]; v != 1 {
+		t.Fatalf("expected 1 throttled request but got %d", v)
+	}
+	if v := metrics.counters[MetricRequestRetries]; v != 1 {
+		t.Fatalf("expected 1 retry but got %d", v)
+	}
+	if v := metrics.durations[MetricRequestDuration]; v != 2 {
+		t.Fatalf("expected 2 request durations to be recorded but got %d", v)
+	}
+	for key := range metrics.attributeKeys {
+		switch key {
+		case AttributeHttpMethod, AttributeHttpStatusCode, AttributeServerAddress:
+		default:
+			t.Fatalf("expected metrics to only be recorded with the method, host and status but got the attribute %q", key)
+		}
+	}
+}
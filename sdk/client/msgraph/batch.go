@@ -0,0 +1,393 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+// maxBatchSize is the maximum number of requests which can be sent in a single JSON batch
+const maxBatchSize = 20
+
+// batchRequestExcludedHeaders are not forwarded for individual requests, as they apply to the batch request
+var batchRequestExcludedHeaders = map[string]bool{
+	"Authorization":               true,
+	"Content-Length":              true,
+	"User-Agent":                  true,
+	"X-Ms-Correlation-Request-Id": true,
+}
+
+// BatchRequest is an individual request to be sent in a JSON batch
+type BatchRequest struct {
+	// Id uniquely identifies the request within the batch. When empty, the (one-based) position of the request is used.
+	Id string
+
+	// Request is the request to be sent, as returned by NewRequest
+	Request *client.Request
+
+	// DependsOn lists the Ids of earlier requests which must succeed before this request is sent. When a dependency
+	// fails, this request is not sent and fails with a `424 Failed Dependency` status.
+	DependsOn []string
+}
+
+// BatchResponse is the result of an individual request sent in a JSON batch
+type BatchResponse struct {
+	// Id identifies the request within the batch
+	Id string
+
+	// Response is the response to the request, which is nil when the request was not sent
+	Response *client.Response
+
+	// Error is a *client.ResponseError when the response has an unexpected status, which includes any OData error
+	// returned by the API, or another error when the request could not be sent
+	Error error
+}
+
+type batchRequestItem struct {
+	Id        string            `json:"id"`
+	Method    string            `json:"method"`
+	Url       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	DependsOn []string          `json:"dependsOn,omitempty"`
+}
+
+type batchResponseItem struct {
+	Id      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// ExecuteBatch sends the requests using JSON batching, in batches of up to 20 requests, and returns a BatchResponse
+// for each request in the same order. Requests are executed in any order within a batch unless DependsOn is
+// specified. Individual requests which are throttled are retried after the duration specified by the API, up to the
+// MaxAttempts of the RetryPolicy. Note that other retries, such as for eventual consistency, are not performed for
+// individual requests.
+//
+// An error is returned when the requests are invalid or a batch could not be sent, otherwise the outcome of each
+// request is indicated by the Error of its BatchResponse.
+//
+// For more information see:
+// https://learn.microsoft.com/en-us/graph/json-batching
+func (c *Client) ExecuteBatch(ctx context.Context, requests []BatchRequest) ([]BatchResponse, error) {
+	batch := &batchExecution{
+		client:    c,
+		requests:  requests,
+		responses: make([]BatchResponse, len(requests)),
+		index:     map[string]int{},
+		bodies:    make([][]byte, len(requests)),
+	}
+	if err := batch.prepare(); err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(requests); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(requests) {
+			end = len(requests)
+		}
+		if err := batch.execute(ctx, start, end); err != nil {
+			return nil, err
+		}
+	}
+
+	return batch.responses, nil
+}
+
+type batchExecution struct {
+	client    *Client
+	requests  []BatchRequest
+	responses []BatchResponse
+
+	// index maps the Id of each request to its position
+	index map[string]int
+
+	// bodies contains the body of each request, so that requests can be retried
+	bodies [][]byte
+}
+
+// prepare validates the requests, assigns any missing Ids and reads the request bodies
+func (b *batchExecution) prepare() error {
+	for i, r := range b.requests {
+		if r.Request == nil || r.Request.Request == nil {
+			return fmt.Errorf("batch request %d: Request was nil", i)
+		}
+
+		id := r.Id
+		if id == "" {
+			id = strconv.Itoa(i + 1)
+		}
+		if _, exists := b.index[id]; exists {
+			return fmt.Errorf("batch request %d: the Id %q is not unique", i, id)
+		}
+		for _, dependency := range r.DependsOn {
+			if _, ok := b.index[dependency]; !ok {
+				return fmt.Errorf("batch request %q: the dependency %q must be an earlier request in the batch", id, dependency)
+			}
+		}
+		b.index[id] = i
+		b.responses[i].Id = id
+
+		if r.Request.Body != nil {
+			body, err := io.ReadAll(r.Request.Body)
+			if err != nil {
+				return fmt.Errorf("batch request %q: reading request body: %v", id, err)
+			}
+			r.Request.Body.Close()
+			r.Request.Body = io.NopCloser(bytes.NewReader(body))
+			b.bodies[i] = body
+		}
+	}
+	return nil
+}
+
+// execute sends the requests between start and end in a single batch, retrying any throttled requests
+func (b *batchExecution) execute(ctx context.Context, start, end int) error {
+	policy := client.DefaultRetryPolicy()
+	if b.client.RetryPolicy != nil {
+		policy = b.client.RetryPolicy.WithDefaults()
+	}
+
+	pending := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		pending = append(pending, i)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if err := b.send(ctx, pending); err != nil {
+			return err
+		}
+
+		// retry throttled requests, along with any requests which depended on them
+		retry := make([]int, 0)
+		retrying := map[string]bool{}
+		var delay time.Duration
+		for _, i := range pending {
+			resp := b.responses[i].Response
+			if resp == nil {
+				continue
+			}
+			throttled := resp.StatusCode == http.StatusTooManyRequests
+			if !throttled && resp.StatusCode == http.StatusFailedDependency {
+				for _, dependency := range b.requests[i].DependsOn {
+					throttled = throttled || retrying[dependency]
+				}
+			}
+			if !throttled {
+				continue
+			}
+			retry = append(retry, i)
+			retrying[b.responses[i].Id] = true
+			if resp.StatusCode != http.StatusTooManyRequests {
+				continue
+			}
+			if d := policy.Backoff(attempt, resp.Response); d > delay {
+				delay = d
+			}
+		}
+
+		if len(retry) == 0 || attempt >= policy.MaxAttempts {
+			return nil
+		}
+
+		b.client.RedactingLogger().Debug("retrying throttled batch requests", "count", len(retry), "delay", delay.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		pending = retry
+	}
+
+	return nil
+}
+
+// send sends the pending requests in a single batch and populates their responses. Requests depending on a request
+// outside the batch are only sent when that request succeeded.
+func (b *batchExecution) send(ctx context.Context, pending []int) error {
+	inBatch := map[string]bool{}
+	for _, i := range pending {
+		inBatch[b.responses[i].Id] = true
+	}
+
+	items := make([]batchRequestItem, 0, len(pending))
+	sent := make([]int, 0, len(pending))
+	for _, i := range pending {
+		id := b.responses[i].Id
+		b.responses[i].Response = nil
+		b.responses[i].Error = nil
+
+		item, err := b.item(i)
+		if err != nil {
+			b.responses[i].Error = err
+			inBatch[id] = false
+			continue
+		}
+
+		for _, dependency := range b.requests[i].DependsOn {
+			if inBatch[dependency] {
+				item.DependsOn = append(item.DependsOn, dependency)
+				continue
+			}
+			if err = b.responses[b.index[dependency]].Error; err != nil {
+				b.responses[i].Error = fmt.Errorf("the dependency %q failed: %w", dependency, err)
+				break
+			}
+		}
+		if b.responses[i].Error != nil {
+			inBatch[id] = false
+			continue
+		}
+
+		items = append(items, *item)
+		sent = append(sent, i)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	req, err := b.client.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodPost,
+		Path:       "/$batch",
+	})
+	if err != nil {
+		return fmt.Errorf("building batch request: %+v", err)
+	}
+	// the individual requests are retried rather than the batch request
+	req.RetryFunc = nil
+
+	if err = req.Marshal(map[string]interface{}{"requests": items}); err != nil {
+		return fmt.Errorf("marshaling batch request: %+v", err)
+	}
+
+	resp, err := req.Execute(ctx)
+	if err != nil {
+		return fmt.Errorf("executing batch request: %+v", err)
+	}
+
+	var result struct {
+		Responses []batchResponseItem `json:"responses"`
+	}
+	if err = resp.Unmarshal(&result); err != nil {
+		return fmt.Errorf("unmarshaling batch response: %+v", err)
+	}
+
+	received := map[string]batchResponseItem{}
+	for _, v := range result.Responses {
+		received[v.Id] = v
+	}
+	for _, i := range sent {
+		v, ok := received[b.responses[i].Id]
+		if !ok {
+			b.responses[i].Error = fmt.Errorf("no response was returned for the batch request %q", b.responses[i].Id)
+			continue
+		}
+		b.responses[i].Response, b.responses[i].Error = b.response(i, v)
+	}
+
+	return nil
+}
+
+// item builds the batch request item for the request at position i
+func (b *batchExecution) item(i int) (*batchRequestItem, error) {
+	req := b.requests[i].Request
+
+	u := req.URL.String()
+	if !strings.HasPrefix(u, b.client.BaseUri) {
+		return nil, fmt.Errorf("the URL %q is not relative to the base URI %q of the client", u, b.client.BaseUri)
+	}
+	u = "/" + strings.TrimPrefix(strings.TrimPrefix(u, b.client.BaseUri), "/")
+
+	item := batchRequestItem{
+		Id:     b.responses[i].Id,
+		Method: req.Method,
+		Url:    u,
+	}
+
+	for k, v := range req.Header {
+		if batchRequestExcludedHeaders[http.CanonicalHeaderKey(k)] || len(v) == 0 {
+			continue
+		}
+		if item.Headers == nil {
+			item.Headers = map[string]string{}
+		}
+		item.Headers[k] = strings.Join(v, ", ")
+	}
+
+	if body := b.bodies[i]; len(body) > 0 {
+		if json.Valid(body) {
+			item.Body = body
+		} else {
+			// non-JSON bodies are sent base64 encoded
+			encoded, err := json.Marshal(base64.StdEncoding.EncodeToString(body))
+			if err != nil {
+				return nil, err
+			}
+			item.Body = encoded
+		}
+	}
+
+	return &item, nil
+}
+
+// response builds the Response for the request at position i from the batch response item
+func (b *batchExecution) response(i int, item batchResponseItem) (*client.Response, error) {
+	header := http.Header{}
+	for k, v := range item.Headers {
+		header.Set(k, v)
+	}
+
+	body := []byte(item.Body)
+	if bytes.Equal(body, []byte("null")) {
+		body = nil
+	}
+	if contentType := strings.ToLower(header.Get("Content-Type")); len(body) > 0 && body[0] == '"' && !strings.Contains(contentType, "json") {
+		// non-JSON bodies are returned base64 encoded
+		var encoded string
+		if err := json.Unmarshal(body, &encoded); err == nil {
+			if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				body = decoded
+			}
+		}
+	} else if contentType == "" && len(body) > 0 && body[0] == '{' {
+		header.Set("Content-Type", "application/json")
+	}
+
+	resp := &client.Response{
+		Response: &http.Response{
+			Status:        fmt.Sprintf("%d %s", item.Status, http.StatusText(item.Status)),
+			StatusCode:    item.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       b.requests[i].Request.Request,
+		},
+	}
+
+	// Extract OData from response, intentionally ignoring any errors as it's not crucial to extract
+	// valid OData at this point (valid json can still error here, such as any non-object literal)
+	resp.OData, _ = odata.FromResponse(resp.Response)
+
+	return resp, b.requests[i].Request.ValidateResponse(resp)
+}
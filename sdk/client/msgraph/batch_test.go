@@ -0,0 +1,294 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/msgraph"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+type testBatchRequest struct {
+	Id        string            `json:"id"`
+	Method    string            `json:"method"`
+	Url       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      json.RawMessage   `json:"body"`
+	DependsOn []string          `json:"dependsOn"`
+}
+
+// batchServer implements the $batch endpoint, responding to each request using handle
+type batchServer struct {
+	*testServer
+
+	batches [][]testBatchRequest
+	handle  func(attempt int, r testBatchRequest) (int, map[string]string, string)
+}
+
+func newBatchServer(t *testing.T, handle func(attempt int, r testBatchRequest) (int, map[string]string, string)) *batchServer {
+	s := &batchServer{
+		handle: handle,
+	}
+	s.testServer = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1.0/$batch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Requests []testBatchRequest `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Requests) > 20 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.batches = append(s.batches, body.Requests)
+
+		responses := make([]map[string]interface{}, 0)
+		for _, v := range body.Requests {
+			status, headers, respBody := s.handle(len(s.batches), v)
+			if headers == nil {
+				headers = map[string]string{}
+			}
+			response := map[string]interface{}{
+				"id":      v.Id,
+				"status":  status,
+				"headers": headers,
+			}
+			if respBody != "" {
+				headers["Content-Type"] = "application/json"
+				response["body"] = json.RawMessage(respBody)
+			}
+			responses = append(responses, response)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"responses": responses})
+	})
+	return s
+}
+
+func testBatchRequestFor(ctx context.Context, t *testing.T, c *msgraph.Client, method, path string, payload interface{}) *client.Request {
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+			http.StatusCreated,
+			http.StatusNoContent,
+		},
+		HttpMethod:    method,
+		OptionsObject: options{},
+		Path:          path,
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	if payload != nil {
+		if err = req.Marshal(payload); err != nil {
+			t.Fatalf("marshaling payload: %+v", err)
+		}
+	}
+	return req
+}
+
+func TestExecuteBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newBatchServer(t, func(_ int, r testBatchRequest) (int, map[string]string, string) {
+		switch {
+		case r.Method == http.MethodPost && r.Url == "/groups":
+			if !strings.Contains(string(r.Body), `"displayName":"example"`) || !strings.HasPrefix(r.Headers["Content-Type"], "application/json") {
+				return http.StatusBadRequest, nil, `{"error":{"code":"BadRequest","message":"invalid body"}}`
+			}
+			return http.StatusCreated, nil, `{"id":"11111111-0000-0000-0000-000000000000"}`
+		case r.Method == http.MethodGet && r.Url == "/groups/11111111-0000-0000-0000-000000000000":
+			return http.StatusOK, nil, `{"id":"11111111-0000-0000-0000-000000000000","displayName":"example"}`
+		}
+		return http.StatusNotFound, nil, `{"error":{"code":"Request_ResourceNotFound","message":"Resource '22222222-0000-0000-0000-000000000000' does not exist or one of its queried reference-property objects are not present."}}`
+	})
+	c := s.client(t)
+
+	responses, err := c.ExecuteBatch(ctx, []msgraph.BatchRequest{
+		{
+			Id:      "create",
+			Request: testBatchRequestFor(ctx, t, c, http.MethodPost, "/groups", map[string]string{"displayName": "example"}),
+		},
+		{
+			Request:   testBatchRequestFor(ctx, t, c, http.MethodGet, "/groups/11111111-0000-0000-0000-000000000000", nil),
+			DependsOn: []string{"create"},
+		},
+		{
+			Request: testBatchRequestFor(ctx, t, c, http.MethodGet, "/groups/22222222-0000-0000-0000-000000000000", nil),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteBatch(): %+v", err)
+	}
+
+	if len(s.batches) != 1 || len(s.batches[0]) != 3 {
+		t.Fatalf("expected 1 batch of 3 requests, got %+v", s.batches)
+	}
+	if deps := s.batches[0][1].DependsOn; len(deps) != 1 || deps[0] != "create" {
+		t.Fatalf("expected the second request to depend on the first, got %v", deps)
+	}
+	if _, ok := s.batches[0][0].Headers["Authorization"]; ok {
+		t.Fatal("expected the Authorization header not to be sent for individual requests")
+	}
+
+	if len(responses) != 3 || responses[0].Id != "create" || responses[1].Id != "2" || responses[2].Id != "3" {
+		t.Fatalf("expected 3 responses in order, got %+v", responses)
+	}
+	for _, v := range responses[:2] {
+		if v.Error != nil {
+			t.Fatalf("unexpected error for request %q: %+v", v.Id, v.Error)
+		}
+	}
+	var group struct {
+		DisplayName string `json:"displayName"`
+	}
+	if err = responses[1].Response.Unmarshal(&group); err != nil {
+		t.Fatalf("unmarshaling response: %+v", err)
+	}
+	if group.DisplayName != "example" {
+		t.Fatalf("expected the displayName to be %q, got %q", "example", group.DisplayName)
+	}
+
+	var respErr *client.ResponseError
+	if !errors.As(responses[2].Error, &respErr) || respErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 ResponseError for the third request, got %+v", responses[2].Error)
+	}
+	if respErr.OData == nil || !respErr.OData.Match(odata.ErrorResourceDoesNotExist) {
+		t.Fatalf("expected the OData error to be parsed, got %+v", respErr.OData)
+	}
+	if !client.IsNotFound(responses[2].Error) {
+		t.Fatal("expected IsNotFound to be true for the third request")
+	}
+}
+
+func TestExecuteBatch_RetriesThrottledRequests(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newBatchServer(t, func(attempt int, r testBatchRequest) (int, map[string]string, string) {
+		if attempt == 1 {
+			switch r.Id {
+			case "2":
+				return http.StatusTooManyRequests, map[string]string{"Retry-After": "0"}, `{"error":{"code":"TooManyRequests","message":"throttled"}}`
+			case "3":
+				return http.StatusFailedDependency, nil, `{"error":{"code":"FailedDependency","message":"dependency failed"}}`
+			}
+		}
+		return http.StatusNoContent, nil, ""
+	})
+	c := s.client(t)
+
+	requests := make([]msgraph.BatchRequest, 0)
+	for i := 0; i < 4; i++ {
+		requests = append(requests, msgraph.BatchRequest{
+			Request: testBatchRequestFor(ctx, t, c, http.MethodDelete, fmt.Sprintf("/groups/%d", i), nil),
+		})
+	}
+	requests[2].DependsOn = []string{"2"}
+
+	responses, err := c.ExecuteBatch(ctx, requests)
+	if err != nil {
+		t.Fatalf("ExecuteBatch(): %+v", err)
+	}
+	for _, v := range responses {
+		if v.Error != nil {
+			t.Fatalf("unexpected error for request %q: %+v", v.Id, v.Error)
+		}
+		if v.Response.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status 204 for request %q, got %d", v.Id, v.Response.StatusCode)
+		}
+	}
+
+	if len(s.batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(s.batches))
+	}
+	retried := s.batches[1]
+	if len(retried) != 2 || retried[0].Id != "2" || retried[1].Id != "3" {
+		t.Fatalf("expected only the throttled request and its dependent to be retried, got %+v", retried)
+	}
+	if len(retried[1].DependsOn) != 1 || retried[1].DependsOn[0] != "2" {
+		t.Fatalf("expected the dependency to be retained when retrying, got %v", retried[1].DependsOn)
+	}
+}
+
+func TestExecuteBatch_MoreThanMaxBatchSize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newBatchServer(t, func(_ int, r testBatchRequest) (int, map[string]string, string) {
+		if r.Url == "/groups/0" {
+			return http.StatusForbidden, nil, `{"error":{"code":"Authorization_RequestDenied","message":"Insufficient privileges to complete the operation."}}`
+		}
+		return http.StatusNoContent, nil, ""
+	})
+	c := s.client(t)
+
+	requests := make([]msgraph.BatchRequest, 0)
+	for i := 0; i < 25; i++ {
+		requests = append(requests, msgraph.BatchRequest{
+			Id:      fmt.Sprintf("delete-%d", i),
+			Request: testBatchRequestFor(ctx, t, c, http.MethodDelete, fmt.Sprintf("/groups/%d", i), nil),
+		})
+	}
+	// a dependency on a request in an earlier batch
+	requests[24].DependsOn = []string{"delete-0"}
+
+	responses, err := c.ExecuteBatch(ctx, requests)
+	if err != nil {
+		t.Fatalf("ExecuteBatch(): %+v", err)
+	}
+
+	if len(s.batches) != 2 || len(s.batches[0]) != 20 || len(s.batches[1]) != 4 {
+		t.Fatalf("expected batches of 20 and 4 requests, got %d batches", len(s.batches))
+	}
+	if !client.IsAuthorizationFailed(responses[0].Error) {
+		t.Fatalf("expected the first request to fail with an authorization error, got %+v", responses[0].Error)
+	}
+	if responses[24].Error == nil || responses[24].Response != nil {
+		t.Fatalf("expected the last request not to be sent as its dependency failed, got %+v", responses[24])
+	}
+	if !client.IsAuthorizationFailed(responses[24].Error) {
+		t.Fatalf("expected the error of the failed dependency to be wrapped, got %+v", responses[24].Error)
+	}
+}
+
+func TestExecuteBatch_InvalidDependency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newBatchServer(t, func(_ int, _ testBatchRequest) (int, map[string]string, string) {
+		return http.StatusNoContent, nil, ""
+	})
+	c := s.client(t)
+
+	_, err := c.ExecuteBatch(ctx, []msgraph.BatchRequest{
+		{
+			Request:   testBatchRequestFor(ctx, t, c, http.MethodDelete, "/groups/0", nil),
+			DependsOn: []string{"2"},
+		},
+		{
+			Request: testBatchRequestFor(ctx, t, c, http.MethodDelete, "/groups/1", nil),
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a dependency on a later request")
+	}
+	if len(s.batches) != 0 {
+		t.Fatalf("expected no batches to be sent, got %d", len(s.batches))
+	}
+}
@@ -26,8 +26,17 @@ type Client struct {
 
 	// EnableRetries allows reattempting failed requests to work around eventual consistency issues
 	// Note that 429 responses are always handled by the base client regardless of this setting
+	//
+	// When enabled, requests for or referencing objects which have not yet replicated are retried, such as a 404 when
+	// reading an object immediately after creating it. Any RetryFunc specified in the RequestOptions is consulted
+	// regardless of this setting.
 	EnableRetries bool
 
+	// MaxConsistencyRetries is the maximum number of times a request is retried to work around eventual consistency
+	// issues each time it is executed. Defaults to 4 when zero. Retries are also bounded by the MaxAttempts of the
+	// RetryPolicy for the base client.
+	MaxConsistencyRetries int
+
 	// apiVersion specifies the version of the API being used, either "beta" or "v1.0"
 	apiVersion ApiVersion
 
@@ -91,7 +100,14 @@ func (c *Client) NewRequest(ctx context.Context, input client.RequestOptions) (*
 	}
 
 	req.URL.RawQuery = query.Encode()
-	//req.RetryFunc = client.RequestRetryAny(defaultRetryFunctions...)
+	req.RetryFunc = input.RetryFunc
+	if c.EnableRetries {
+		req.RetryFunc = client.RequestRetryAny(client.RequestRetryAny(defaultRetryFunctions...), input.RetryFunc)
+	}
+	req.MaxConsistencyRetries = c.MaxConsistencyRetries
+	if req.MaxConsistencyRetries <= 0 {
+		req.MaxConsistencyRetries = defaultMaxConsistencyRetries
+	}
 	req.ValidStatusCodes = input.ExpectedStatusCodes
 
 	return req, nil
@@ -0,0 +1,273 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+)
+
+type DeltaChangeType string
+
+const (
+	// DeltaItemAdded indicates that the item was returned by an initial sync
+	DeltaItemAdded DeltaChangeType = "Added"
+
+	// DeltaItemChanged indicates that the item was created or changed since the previous sync. Microsoft Graph does
+	// not distinguish between these, so newly created items are also reported as changed.
+	DeltaItemChanged DeltaChangeType = "Changed"
+
+	// DeltaItemRemoved indicates that the item was deleted, or removed from the collection, since the previous sync
+	DeltaItemRemoved DeltaChangeType = "Removed"
+)
+
+// DeltaItem is an item returned by a delta query
+type DeltaItem struct {
+	// Id is the ID of the item
+	Id string
+
+	// ChangeType indicates whether the item was added, changed or removed
+	ChangeType DeltaChangeType
+
+	// RemovedReason is the reason given for a removed item, either `changed` when the item can be restored or
+	// `deleted` when the item was permanently deleted
+	RemovedReason string
+
+	// Data is the JSON representation of the item. For changed items, only the changed properties may be present.
+	Data json.RawMessage
+}
+
+// Unmarshal deserializes the item into the provided model
+func (i DeltaItem) Unmarshal(model interface{}) error {
+	return json.Unmarshal(i.Data, model)
+}
+
+type DeltaSyncInput struct {
+	// Path is the path of the delta function for the collection, e.g. `/users/delta`
+	Path string
+
+	// OptionsObject is used to customize the initial query, e.g. to $select properties or to $filter items. Any
+	// query parameters are retained in the deltaLink, so are not applied to subsequent syncs.
+	OptionsObject client.Options
+
+	// DeltaLink is the deltaLink returned by a previous sync, from which only changes are returned. When empty, an
+	// initial sync is performed. Ignored when a DeltaLinkStore is specified.
+	DeltaLink string
+
+	// DeltaLinkStore optionally persists the deltaLink between syncs, using Key
+	DeltaLinkStore DeltaLinkStore
+
+	// Key optionally identifies the deltaLink in the DeltaLinkStore. Defaults to the Path together with the query
+	// parameters from the OptionsObject, so that syncs of the same collection with different options are persisted
+	// separately. A Key should be specified when the OptionsObject specifies headers which affect the results.
+	Key string
+
+	// HandleItem is called for each item returned by the delta query. The sync is aborted, and the deltaLink is not
+	// updated, when an error is returned.
+	//
+	// When a previous deltaLink has expired, an initial sync is performed before any items are handled. However, when
+	// the sync state expires whilst paging through the results, an error is returned after some items have already
+	// been handled. The deltaLink is not updated in this case, so those items are handled again by the next sync.
+	HandleItem func(ctx context.Context, item DeltaItem) error
+}
+
+type DeltaSyncResult struct {
+	// DeltaLink is the deltaLink with which to retrieve subsequent changes
+	DeltaLink string
+
+	// Resynced indicates that the previous deltaLink had expired, so an initial sync was performed
+	Resynced bool
+
+	// Added, Changed and Removed are the number of items handled with each DeltaChangeType
+	Added   int
+	Changed int
+	Removed int
+}
+
+// DeltaSync performs a delta query for the collection at input.Path, paging through the results and calling
+// input.HandleItem for each item. When a DeltaLink from a previous sync is specified (or retrieved from the
+// DeltaLinkStore), only changes since that sync are returned. When the DeltaLink has expired, an initial sync is
+// performed. The new DeltaLink is returned, and persisted in the DeltaLinkStore, once all items have been handled.
+//
+// For more information see:
+// https://learn.microsoft.com/en-us/graph/delta-query-overview
+func (c *Client) DeltaSync(ctx context.Context, input DeltaSyncInput) (*DeltaSyncResult, error) {
+	if input.Path == "" {
+		return nil, fmt.Errorf("missing `Path`")
+	}
+	if input.HandleItem == nil {
+		return nil, fmt.Errorf("missing `HandleItem`")
+	}
+
+	deltaLink := input.DeltaLink
+	key := input.Key
+	if input.DeltaLinkStore != nil {
+		var err error
+		if key == "" {
+			if key, err = c.deltaLinkKey(ctx, input); err != nil {
+				return nil, err
+			}
+		}
+		if deltaLink, err = input.DeltaLinkStore.GetDeltaLink(ctx, key); err != nil {
+			return nil, fmt.Errorf("retrieving deltaLink for %q: %+v", key, err)
+		}
+	}
+
+	result, err := c.deltaSync(ctx, input, deltaLink)
+	if errors.Is(err, errDeltaLinkExpired) {
+		c.RedactingLogger().Debug("deltaLink has expired, performing an initial sync", "path", input.Path)
+
+		result, err = c.deltaSync(ctx, input, "")
+		if result != nil {
+			result.Resynced = true
+		}
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if input.DeltaLinkStore != nil {
+		if err = input.DeltaLinkStore.SetDeltaLink(ctx, key, result.DeltaLink); err != nil {
+			return result, fmt.Errorf("persisting deltaLink for %q: %+v", key, err)
+		}
+	}
+
+	return result, nil
+}
+
+// errDeltaLinkExpired is returned by deltaSync when the deltaLink it was started from has expired
+var errDeltaLinkExpired = errors.New("the deltaLink has expired")
+
+// deltaLinkKey returns the default key for the deltaLink in the DeltaLinkStore, comprising the Path and the query
+// parameters for the initial request
+func (c *Client) deltaLinkKey(ctx context.Context, input DeltaSyncInput) (string, error) {
+	req, err := c.newDeltaRequest(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	if req.URL.RawQuery == "" {
+		return input.Path, nil
+	}
+	return fmt.Sprintf("%s?%s", input.Path, req.URL.RawQuery), nil
+}
+
+// newDeltaRequest builds the initial request for a delta query
+func (c *Client) newDeltaRequest(ctx context.Context, input DeltaSyncInput) (*client.Request, error) {
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod:    http.MethodGet,
+		OptionsObject: input.OptionsObject,
+		Path:          input.Path,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building delta request: %+v", err)
+	}
+	return req, nil
+}
+
+// deltaSync pages through the results of a delta query, starting from deltaLink when specified. When the deltaLink has
+// expired, errDeltaLinkExpired is returned before any items have been handled.
+func (c *Client) deltaSync(ctx context.Context, input DeltaSyncInput, deltaLink string) (*DeltaSyncResult, error) {
+	result := &DeltaSyncResult{}
+	initial := deltaLink == ""
+
+	link := deltaLink
+	for first := true; ; first = false {
+		req, err := c.newDeltaRequest(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if link != "" {
+			if req.URL, err = url.Parse(link); err != nil {
+				return nil, fmt.Errorf("parsing link %q: %+v", link, err)
+			}
+		}
+
+		resp, err := req.Execute(ctx)
+		if err != nil {
+			if first && !initial && isSyncStateExpired(err) {
+				return nil, errDeltaLinkExpired
+			}
+			return nil, fmt.Errorf("executing delta request: %w", err)
+		}
+
+		var page struct {
+			Value []json.RawMessage `json:"value"`
+		}
+		if err = resp.Unmarshal(&page); err != nil {
+			return nil, fmt.Errorf("unmarshaling delta response: %+v", err)
+		}
+
+		for _, v := range page.Value {
+			item, err := newDeltaItem(v, initial)
+			if err != nil {
+				return nil, err
+			}
+			if err = input.HandleItem(ctx, *item); err != nil {
+				return nil, fmt.Errorf("handling item %q: %w", item.Id, err)
+			}
+			switch item.ChangeType {
+			case DeltaItemAdded:
+				result.Added++
+			case DeltaItemChanged:
+				result.Changed++
+			case DeltaItemRemoved:
+				result.Removed++
+			}
+		}
+
+		switch {
+		case resp.OData != nil && resp.OData.NextLink != nil && *resp.OData.NextLink != "":
+			link = string(*resp.OData.NextLink)
+		case resp.OData != nil && resp.OData.DeltaLink != nil && *resp.OData.DeltaLink != "":
+			result.DeltaLink = string(*resp.OData.DeltaLink)
+			return result, nil
+		default:
+			return nil, fmt.Errorf("the delta response contained neither a nextLink nor a deltaLink")
+		}
+	}
+}
+
+// newDeltaItem parses an item returned by a delta query
+func newDeltaItem(data json.RawMessage, initial bool) (*DeltaItem, error) {
+	var v struct {
+		Id      string `json:"id"`
+		Removed *struct {
+			Reason string `json:"reason"`
+		} `json:"@removed"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("unmarshaling delta item: %+v", err)
+	}
+
+	item := DeltaItem{
+		Id:         v.Id,
+		ChangeType: DeltaItemChanged,
+		Data:       data,
+	}
+	switch {
+	case v.Removed != nil:
+		item.ChangeType = DeltaItemRemoved
+		item.RemovedReason = v.Removed.Reason
+	case initial:
+		item.ChangeType = DeltaItemAdded
+	}
+	return &item, nil
+}
+
+// isSyncStateExpired determines whether err indicates that a deltaLink can no longer be used, in which case an
+// initial sync must be performed
+func isSyncStateExpired(err error) bool {
+	var respErr *client.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusGone
+}
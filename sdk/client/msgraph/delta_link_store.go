@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DeltaLinkStore persists the deltaLinks returned by delta queries, so that subsequent syncs only retrieve changes
+type DeltaLinkStore interface {
+	// GetDeltaLink returns the deltaLink for the key, or an empty string when none has been stored
+	GetDeltaLink(ctx context.Context, key string) (string, error)
+
+	// SetDeltaLink stores the deltaLink for the key
+	SetDeltaLink(ctx context.Context, key, deltaLink string) error
+}
+
+var _ DeltaLinkStore = &FileDeltaLinkStore{}
+
+// FileDeltaLinkStore is a DeltaLinkStore which persists deltaLinks in a JSON file
+type FileDeltaLinkStore struct {
+	path string
+
+	mutex sync.Mutex
+}
+
+// NewFileDeltaLinkStore returns a DeltaLinkStore which persists deltaLinks in the file at path. The file and its
+// directory are created when a deltaLink is first stored.
+func NewFileDeltaLinkStore(path string) (DeltaLinkStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("FileDeltaLinkStore: a path must be specified")
+	}
+	return &FileDeltaLinkStore{
+		path: path,
+	}, nil
+}
+
+func (s *FileDeltaLinkStore) GetDeltaLink(_ context.Context, key string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	links, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	return links[key], nil
+}
+
+func (s *FileDeltaLinkStore) SetDeltaLink(_ context.Context, key, deltaLink string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	links, err := s.read()
+	if err != nil {
+		return err
+	}
+	links[key] = deltaLink
+
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return fmt.Errorf("FileDeltaLinkStore: marshaling deltaLinks: %v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("FileDeltaLinkStore: creating directory: %v", err)
+	}
+
+	// write to a temporary file which is then renamed, so that the file is never partially written
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("FileDeltaLinkStore: creating temporary file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("FileDeltaLinkStore: writing temporary file: %v", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("FileDeltaLinkStore: writing temporary file: %v", err)
+	}
+	if err = os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("FileDeltaLinkStore: replacing file: %v", err)
+	}
+	return nil
+}
+
+// read returns the deltaLinks in the file, which are empty when the file does not exist
+func (s *FileDeltaLinkStore) read() (map[string]string, error) {
+	links := map[string]string{}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return links, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FileDeltaLinkStore: reading %q: %v", s.path, err)
+	}
+	if err = json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("FileDeltaLinkStore: parsing %q: %v", s.path, err)
+	}
+	return links, nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client/msgraph"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+type selectOptions struct {
+	options
+}
+
+func (o selectOptions) ToOData() *odata.Query {
+	return &odata.Query{
+		Select: []string{"id", "displayName"},
+	}
+}
+
+// deltaServer implements the delta function for the users collection
+type deltaServer struct {
+	*testServer
+}
+
+func newDeltaServer(t *testing.T) *deltaServer {
+	s := &deltaServer{}
+	s.testServer = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1.0/users/delta" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query := r.URL.Query()
+
+		link := func(kind, param, token string) string {
+			return fmt.Sprintf(`"@odata.%s":"%s/v1.0/users/delta?%s=%s"`, kind, s.URL, param, token)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case query.Get("$deltatoken") == "expired" || query.Get("$skiptoken") == "expired":
+			w.WriteHeader(http.StatusGone)
+			fmt.Fprint(w, `{"error":{"code":"syncStateNotFound","message":"The sync state has expired."}}`)
+		case query.Get("$deltatoken") == "expiring":
+			fmt.Fprintf(w, `{"value":[{"id":"e","displayName":"Eve"}],%s}`, link("nextLink", "$skiptoken", "expired"))
+		case query.Get("$deltatoken") == "first":
+			fmt.Fprintf(w, `{"value":[{"id":"b","displayName":"Bob Smith"},{"id":"a","@removed":{"reason":"deleted"}},{"id":"d","displayName":"Dave"}],%s}`, link("deltaLink", "$deltatoken", "second"))
+		case query.Get("$skiptoken") == "page2":
+			fmt.Fprintf(w, `{"value":[{"id":"c","displayName":"Carol"}],%s}`, link("deltaLink", "$deltatoken", "first"))
+		case query.Get("$select") == "id,displayName":
+			fmt.Fprintf(w, `{"value":[{"id":"a","displayName":"Alice"},{"id":"b","displayName":"Bob"}],%s}`, link("nextLink", "$skiptoken", "page2"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":"BadRequest","message":"unexpected query"}}`)
+		}
+	})
+	return s
+}
+
+// testDeltaItems records the items handled by DeltaSync as `changeType:id`
+type testDeltaItems []string
+
+func (i *testDeltaItems) handle(_ context.Context, item msgraph.DeltaItem) error {
+	*i = append(*i, fmt.Sprintf("%s:%s", item.ChangeType, item.Id))
+	return nil
+}
+
+func (i testDeltaItems) String() string {
+	sorted := append([]string{}, i...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func TestDeltaSync(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newDeltaServer(t)
+	c := s.client(t)
+	store, err := msgraph.NewFileDeltaLinkStore(filepath.Join(t.TempDir(), "sync", "deltalinks.json"))
+	if err != nil {
+		t.Fatalf("NewFileDeltaLinkStore(): %+v", err)
+	}
+
+	// the deltaLink is persisted using the path and the query parameters of the initial request
+	key := "/users/delta?" + url.Values{"$select": {"id,displayName"}}.Encode()
+
+	// the initial sync should page through all items
+	var items testDeltaItems
+	result, err := c.DeltaSync(ctx, msgraph.DeltaSyncInput{
+		Path:           "/users/delta",
+		OptionsObject:  selectOptions{},
+		DeltaLinkStore: store,
+		HandleItem:     items.handle,
+	})
+	if err != nil {
+		t.Fatalf("DeltaSync(): %+v", err)
+	}
+	if expected := "Added:a,Added:b,Added:c"; items.String() != expected {
+		t.Fatalf("expected the items %s, got %s", expected, items)
+	}
+	if result.Added != 3 || !strings.Contains(result.DeltaLink, "deltatoken=first") {
+		t.Fatalf("unexpected result for the initial sync: %+v", result)
+	}
+	deltaLink, err := store.GetDeltaLink(ctx, key)
+	if err != nil || deltaLink != result.DeltaLink {
+		t.Fatalf("expected the deltaLink to be persisted, got %q (%v)", deltaLink, err)
+	}
+
+	// a subsequent sync should only return changes
+	items = nil
+	result, err = c.DeltaSync(ctx, msgraph.DeltaSyncInput{
+		Path:           "/users/delta",
+		OptionsObject:  selectOptions{},
+		DeltaLinkStore: store,
+		HandleItem: func(ctx context.Context, item msgraph.DeltaItem) error {
+			if item.ChangeType == msgraph.DeltaItemRemoved && item.RemovedReason != "deleted" {
+				return fmt.Errorf("expected the removed reason to be deleted, got %q", item.RemovedReason)
+			}
+			if item.Id == "b" {
+				var user struct {
+					DisplayName string `json:"displayName"`
+				}
+				if err := item.Unmarshal(&user); err != nil || user.DisplayName != "Bob Smith" {
+					return fmt.Errorf("expected the changed displayName, got %q (%v)", user.DisplayName, err)
+				}
+			}
+			return items.handle(ctx, item)
+		},
+	})
+	if err != nil {
+		t.Fatalf("DeltaSync(): %+v", err)
+	}
+	if expected := "Changed:b,Changed:d,Removed:a"; items.String() != expected {
+		t.Fatalf("expected the items %s, got %s", expected, items)
+	}
+	if result.Changed != 2 || result.Removed != 1 || result.Resynced {
+		t.Fatalf("unexpected result for the subsequent sync: %+v", result)
+	}
+	if deltaLink, _ = store.GetDeltaLink(ctx, key); !strings.Contains(deltaLink, "deltatoken=second") {
+		t.Fatalf("expected the new deltaLink to be persisted, got %q", deltaLink)
+	}
+}
+
+func TestDeltaSync_ExpiredDeltaLink(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newDeltaServer(t)
+	c := s.client(t)
+
+	var items testDeltaItems
+	result, err := c.DeltaSync(ctx, msgraph.DeltaSyncInput{
+		Path:          "/users/delta",
+		OptionsObject: selectOptions{},
+		DeltaLink:     fmt.Sprintf("%s/v1.0/users/delta?$deltatoken=expired", s.URL),
+		HandleItem:    items.handle,
+	})
+	if err != nil {
+		t.Fatalf("DeltaSync(): %+v", err)
+	}
+	if !result.Resynced || result.Added != 3 {
+		t.Fatalf("expected an initial sync to be performed, got %+v", result)
+	}
+	if expected := "Added:a,Added:b,Added:c"; items.String() != expected {
+		t.Fatalf("expected the items %s, got %s", expected, items)
+	}
+}
+
+func TestDeltaSync_HandleItemError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newDeltaServer(t)
+	c := s.client(t)
+	store, err := msgraph.NewFileDeltaLinkStore(filepath.Join(t.TempDir(), "deltalinks.json"))
+	if err != nil {
+		t.Fatalf("NewFileDeltaLinkStore(): %+v", err)
+	}
+
+	_, err = c.DeltaSync(ctx, msgraph.DeltaSyncInput{
+		Path:           "/users/delta",
+		OptionsObject:  selectOptions{},
+		DeltaLinkStore: store,
+		Key:            "users",
+		HandleItem: func(_ context.Context, item msgraph.DeltaItem) error {
+			if item.Id == "c" {
+				return fmt.Errorf("failed to handle %q", item.Id)
+			}
+			return nil
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error when HandleItem fails")
+	}
+	if deltaLink, _ := store.GetDeltaLink(ctx, "users"); deltaLink != "" {
+		t.Fatalf("expected no deltaLink to be persisted, got %q", deltaLink)
+	}
+}
+
+func TestDeltaSync_ExpiredNextLink(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newDeltaServer(t)
+	c := s.client(t)
+
+	// the sync state expires after the first page has been handled, so an initial sync is not performed
+	var items testDeltaItems
+	result, err := c.DeltaSync(ctx, msgraph.DeltaSyncInput{
+		Path:          "/users/delta",
+		OptionsObject: selectOptions{},
+		DeltaLink:     fmt.Sprintf("%s/v1.0/users/delta?$deltatoken=expiring", s.URL),
+		HandleItem:    items.handle,
+	})
+	if err == nil {
+		t.Fatalf("expected an error when the sync state expires whilst paging, got %+v", result)
+	}
+	if expected := "Changed:e"; items.String() != expected {
+		t.Fatalf("expected the items %s, got %s", expected, items)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+// defaultMaxConsistencyRetries is the number of times a request is retried due to eventual consistency, when not
+// specified for the Client
+const defaultMaxConsistencyRetries = 4
+
+// defaultRetryFunctions are consulted for every request when EnableRetries is set. These only match errors returned
+// when a request references an object which has not yet replicated, and are bounded by MaxConsistencyRetries.
+var defaultRetryFunctions = []client.RequestRetryFunc{
+	// NOTE: 429 is handled by the base library
+	RetryOnResourceNotFound,
+	retryOnReferencedObjectNotFound,
+	retryOnServicePrincipalInvalidAppId,
+}
+
+// RetryOnResourceNotFound retries a 404 response for an object which has not yet replicated, e.g. when reading an
+// object immediately after creating it. This is consulted by default when EnableRetries is set, and can otherwise be
+// specified as the RetryFunc for a request. Unlike client.RetryOn404ConsistencyFailureFunc, on which it is built, a
+// 404 response is only retried when Microsoft Graph indicates that the object does not exist, so that a request for
+// an unknown path is not retried.
+func RetryOnResourceNotFound(resp *http.Response, o *odata.OData) (bool, error) {
+	if retry, err := client.RetryOn404ConsistencyFailureFunc(resp, o); !retry || err != nil {
+		return false, err
+	}
+	if o == nil || o.Error == nil {
+		return false, nil
+	}
+	if o.Error.Code != nil && strings.EqualFold(*o.Error.Code, "Request_ResourceNotFound") {
+		return true, nil
+	}
+	return o.Error.Match(odata.ErrorResourceDoesNotExist), nil
+}
+
+// retryOnReferencedObjectNotFound retries a 400 response for a request referencing an object which has not yet
+// replicated, e.g. when adding a member to a group immediately after creating it
+func retryOnReferencedObjectNotFound(resp *http.Response, o *odata.OData) (bool, error) {
+	if resp == nil || resp.StatusCode != http.StatusBadRequest || o == nil || o.Error == nil {
+		return false, nil
+	}
+	return o.Error.Match(odata.ErrorResourceDoesNotExist), nil
+}
+
+// retryOnServicePrincipalInvalidAppId retries a 400 response when creating a service principal for an application
+// which has not yet replicated
+func retryOnServicePrincipalInvalidAppId(resp *http.Response, o *odata.OData) (bool, error) {
+	if resp == nil || resp.StatusCode != http.StatusBadRequest || o == nil || o.Error == nil {
+		return false, nil
+	}
+	return o.Error.Match(odata.ErrorServicePrincipalInvalidAppId), nil
+}
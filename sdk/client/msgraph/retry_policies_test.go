@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/msgraph"
+)
+
+// consistencyServer returns 404 Request_ResourceNotFound for the specified number of requests before succeeding
+type consistencyServer struct {
+	*testServer
+
+	failures int
+	requests int
+}
+
+func newConsistencyServer(t *testing.T, failures int) *consistencyServer {
+	s := &consistencyServer{
+		failures: failures,
+	}
+	s.testServer = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		s.requests++
+
+		w.Header().Set("Content-Type", "application/json")
+		if s.requests <= s.failures {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"Request_ResourceNotFound","message":"Resource '11111111-0000-0000-0000-000000000000' does not exist or one of its queried reference-property objects are not present."}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"11111111-0000-0000-0000-000000000000"}`)
+	})
+	return s
+}
+
+func (s *consistencyServer) client(t *testing.T) *msgraph.Client {
+	c := s.testServer.client(t)
+	c.RetryPolicy.MaxAttempts = 10
+	return c
+}
+
+func (s *consistencyServer) get(t *testing.T, c *msgraph.Client, retryFunc client.RequestRetryFunc) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod:    http.MethodGet,
+		OptionsObject: options{},
+		Path:          "/applications/11111111-0000-0000-0000-000000000000",
+		RetryFunc:     retryFunc,
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	_, err = req.Execute(ctx)
+	return err
+}
+
+func TestClientRetriesResourceNotFound(t *testing.T) {
+	s := newConsistencyServer(t, 2)
+	if err := s.get(t, s.client(t), nil); err != nil {
+		t.Fatalf("expected the request to succeed after retrying, got: %+v", err)
+	}
+	if s.requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", s.requests)
+	}
+}
+
+func TestClientRetriesResourceNotFound_UnknownPath(t *testing.T) {
+	requests := 0
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	})
+	c := s.client(t)
+	c.RetryPolicy.MaxAttempts = 10
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod:    http.MethodGet,
+		OptionsObject: options{},
+		Path:          "/unknown",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	if _, err = req.Execute(ctx); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	if requests != 1 {
+		t.Fatalf("expected a 404 response without a Microsoft Graph error not to be retried, got %d requests", requests)
+	}
+}
+
+func TestClientRetriesResourceNotFound_Disabled(t *testing.T) {
+	s := newConsistencyServer(t, 2)
+	c := s.client(t)
+	c.EnableRetries = false
+	if err := s.get(t, c, nil); err == nil {
+		t.Fatal("expected the request to fail when retries are disabled")
+	}
+	if s.requests != 1 {
+		t.Fatalf("expected 1 request, got %d", s.requests)
+	}
+}
+
+func TestClientRetriesResourceNotFound_DisabledWithRetryFunc(t *testing.T) {
+	s := newConsistencyServer(t, 2)
+	c := s.client(t)
+	c.EnableRetries = false
+	if err := s.get(t, c, msgraph.RetryOnResourceNotFound); err != nil {
+		t.Fatalf("expected the request to succeed after retrying using the RetryFunc for the request, got: %+v", err)
+	}
+	if s.requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", s.requests)
+	}
+}
+
+func TestClientRetriesResourceNotFound_Bounded(t *testing.T) {
+	s := newConsistencyServer(t, 5)
+	c := s.client(t)
+	c.MaxConsistencyRetries = 2
+	if err := s.get(t, c, nil); err == nil {
+		t.Fatal("expected the request to fail once the consistency retries are exhausted")
+	}
+	if s.requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", s.requests)
+	}
+}
+
+func TestClientRetriesResourceNotFound_BoundedPerExecution(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := newConsistencyServer(t, 2)
+	c := s.client(t)
+	c.MaxConsistencyRetries = 2
+
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod:    http.MethodGet,
+		OptionsObject: options{},
+		Path:          "/applications/11111111-0000-0000-0000-000000000000",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+
+	// the retries are counted separately each time the request is executed, e.g. for each page of results
+	for i := 0; i < 2; i++ {
+		s.mutex.Lock()
+		s.requests = 0
+		s.mutex.Unlock()
+		if _, err = req.Execute(ctx); err != nil {
+			t.Fatalf("execution %d: expected the request to succeed after retrying, got: %+v", i, err)
+		}
+		if s.requests != 3 {
+			t.Fatalf("execution %d: expected 3 requests, got %d", i, s.requests)
+		}
+	}
+}
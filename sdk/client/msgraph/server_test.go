@@ -0,0 +1,51 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package msgraph_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/msgraph"
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+)
+
+// testServer is a fake Microsoft Graph API which handles one request at a time, so that handlers can record requests
+// without further synchronization
+type testServer struct {
+	*httptest.Server
+
+	mutex sync.Mutex
+}
+
+// newTestServer starts a testServer using handler, which is closed when the test completes
+func newTestServer(t *testing.T, handler http.HandlerFunc) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// client returns a msgraph.Client for the v1.0 API of the testServer, which retries with minimal backoff
+func (s *testServer) client(t *testing.T) *msgraph.Client {
+	api := environments.NewApiEndpoint("Example", s.URL, pointer.To("00000000-0000-0000-0000-000000000000"))
+	c, err := msgraph.NewMsGraphClient(api, "example", msgraph.VersionOnePointZero)
+	if err != nil {
+		t.Fatalf("building client: %+v", err)
+	}
+	c.RetryPolicy = &client.RetryPolicy{
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Millisecond,
+	}
+	return c
+}
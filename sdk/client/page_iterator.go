@@ -0,0 +1,222 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+// PageIterator retrieves the results of a paginated API request one page at a time, rather than retrieving and
+// buffering every page as ExecutePaged does. This allows callers to stop early, and to resume iteration later
+// using a continuation token.
+type PageIterator struct {
+	request *Request
+
+	// nextLink is the URI for the next page of results, when known
+	nextLink *odata.Link
+
+	// started indicates that at least one page has been retrieved (or that iteration was resumed from a token)
+	started bool
+
+	// count is the total number of items in the collection, when returned by the API
+	count *int
+}
+
+// PageIterator returns a *PageIterator which pages through the results of this Request
+func (r *Request) PageIterator() *PageIterator {
+	return &PageIterator{
+		request: r,
+	}
+}
+
+// PageIteratorFromContinuationToken returns a *PageIterator which resumes paging through the results of this Request,
+// starting at the page described by a continuation token previously obtained from PageIterator.ContinuationToken.
+func (r *Request) PageIteratorFromContinuationToken(token string) (*PageIterator, error) {
+	if token == "" {
+		return nil, fmt.Errorf("continuation token was empty")
+	}
+	u, err := url.Parse(token)
+	if err != nil {
+		return nil, fmt.Errorf("parsing continuation token: %+v", err)
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("parsing continuation token: URL was not absolute")
+	}
+
+	nextLink := odata.Link(token)
+	return &PageIterator{
+		request:  r,
+		nextLink: &nextLink,
+		started:  true,
+	}, nil
+}
+
+// HasMore returns true when there are further pages of results to be loaded
+func (p *PageIterator) HasMore() bool {
+	return !p.started || p.nextLink != nil
+}
+
+// ContinuationToken returns an opaque token which can be passed to Request.PageIteratorFromContinuationToken in order
+// to resume paging from the next page of results. An empty string is returned once all pages have been loaded.
+func (p *PageIterator) ContinuationToken() string {
+	if p.nextLink == nil {
+		return ""
+	}
+	return string(*p.nextLink)
+}
+
+// Count returns the total number of items in the collection, as indicated by the `@odata.count` annotation. This is
+// only returned when requested, e.g. with `$count=true`, and is nil until a page containing it has been loaded.
+func (p *PageIterator) Count() *int {
+	return p.count
+}
+
+// LoadMore retrieves the next page of results. When an error is returned, the position of the iterator is unchanged
+// so that the page can be requested again.
+func (p *PageIterator) LoadMore(ctx context.Context) (*Response, error) {
+	if p.request == nil || p.request.Request == nil {
+		return nil, fmt.Errorf("internal-error: request was nil")
+	}
+	if !p.HasMore() {
+		return nil, fmt.Errorf("no more pages to load")
+	}
+
+	// Each page is retrieved using a copy of the request, so that the original Request can be reused
+	req, err := p.request.clone(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.nextLink != nil {
+		u, err := url.Parse(string(*p.nextLink))
+		if err != nil {
+			return nil, fmt.Errorf("parsing next link %q: %+v", string(*p.nextLink), err)
+		}
+		req.URL = u
+		req.Host = u.Host
+	}
+
+	resp, err := req.Execute(ctx)
+	if err != nil {
+		return resp, err
+	}
+
+	// Check for json content before handling pagination
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "application/json") {
+		return resp, fmt.Errorf("unsupported content-type %q received, only application/json is supported for paged results", contentType)
+	}
+
+	// Get a Link for the next results page
+	var nextLink *odata.Link
+	if p.request.Pager == nil {
+		if resp.OData != nil {
+			nextLink = resp.OData.NextLink
+		}
+	} else {
+		nextLink, err = odata.NextLinkFromCustomPager(resp.Response, p.request.Pager)
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	p.started = true
+	p.nextLink = nextLink
+	if resp.OData != nil && resp.OData.Count != nil {
+		p.count = resp.OData.Count
+	}
+
+	return resp, nil
+}
+
+// ListIterator yields the typed items contained in each page of a paginated API request, loading pages as required.
+// Items are read from the `value` field of each page, consistent with the OData 4.0 standard for JSON services.
+type ListIterator[T any] struct {
+	pages *PageIterator
+
+	// items contains the items from the current page which have not yet been yielded
+	items []json.RawMessage
+
+	item T
+	err  error
+}
+
+// NewListIterator returns a *ListIterator which yields items of type T from the provided *PageIterator
+func NewListIterator[T any](pages *PageIterator) *ListIterator[T] {
+	return &ListIterator[T]{
+		pages: pages,
+	}
+}
+
+// Next advances the iterator to the next item, loading the next page of results when the current page is exhausted.
+// It returns false when there are no further items, or when an error occurred, which can be obtained from Err.
+func (i *ListIterator[T]) Next(ctx context.Context) bool {
+	if i.err != nil {
+		return false
+	}
+
+	for len(i.items) == 0 {
+		if i.pages == nil || !i.pages.HasMore() {
+			return false
+		}
+
+		resp, err := i.pages.LoadMore(ctx)
+		if err != nil {
+			i.err = fmt.Errorf("loading results: %+v", err)
+			return false
+		}
+
+		var page struct {
+			Values []json.RawMessage `json:"value"`
+		}
+		if err = resp.Unmarshal(&page); err != nil {
+			i.err = fmt.Errorf("unmarshaling page: %+v", err)
+			return false
+		}
+		i.items = page.Values
+	}
+
+	var item T
+	if err := json.Unmarshal(i.items[0], &item); err != nil {
+		i.err = fmt.Errorf("unmarshaling item: %+v", err)
+		return false
+	}
+	i.items = i.items[1:]
+	i.item = item
+
+	return true
+}
+
+// Item returns the current item, as obtained by the most recent call to Next
+func (i *ListIterator[T]) Item() T {
+	return i.item
+}
+
+// Err returns the error, if any, which was encountered during iteration
+func (i *ListIterator[T]) Err() error {
+	return i.err
+}
+
+// Count returns the total number of items in the collection when requested, e.g. with `$count=true`, or nil when not
+// known. This is available once Next has been called.
+func (i *ListIterator[T]) Count() *int {
+	if i.pages == nil {
+		return nil
+	}
+	return i.pages.Count()
+}
+
+// ContinuationToken returns an opaque token which can be used to resume iteration from the next page of results.
+// Any items remaining in the current page are not included when resuming.
+func (i *ListIterator[T]) ContinuationToken() string {
+	if i.pages == nil {
+		return ""
+	}
+	return i.pages.ContinuationToken()
+}
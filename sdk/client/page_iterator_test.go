@@ -0,0 +1,243 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+func pagedTestServer(t *testing.T, numberOfPages int) (*httptest.Server, *int) {
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page := 0
+		if v := r.URL.Query().Get("page"); v != "" {
+			var err error
+			if page, err = strconv.Atoi(v); err != nil {
+				t.Errorf("parsing page number: %+v", err)
+			}
+		}
+
+		nextLink := ""
+		if page < numberOfPages-1 {
+			nextLink = fmt.Sprintf(`,"@odata.nextLink":"%s/things?page=%d"`, server.URL, page+1)
+		}
+
+		// as with Microsoft Graph, the total count is only returned with the first page
+		count := ""
+		if page == 0 && r.URL.Query().Get("$count") == "true" {
+			count = fmt.Sprintf(`,"@odata.count":%d`, numberOfPages*2)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value":[{"name":"item-%[1]d-0"},{"name":"item-%[1]d-1"}]%[2]s%[3]s}`, page, nextLink, count)
+	}))
+	return server, &requests
+}
+
+func newPagedTestRequest(t *testing.T, ctx context.Context, baseUri string) *Request {
+	return newPagedTestRequestWithOptions(t, ctx, baseUri, nil)
+}
+
+func newPagedTestRequestWithOptions(t *testing.T, ctx context.Context, baseUri string, options Options) *Request {
+	c := &testClient{
+		Client: NewClient(baseUri, "example", "2020-01-01"),
+	}
+	req, err := c.NewRequest(ctx, RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod:    http.MethodGet,
+		OptionsObject: options,
+		Path:          "/things",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	return req
+}
+
+type pagedTestItem struct {
+	Name string `json:"name"`
+}
+
+func TestPageIterator(t *testing.T) {
+	server, requests := pagedTestServer(t, 3)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pages := newPagedTestRequest(t, ctx, server.URL).PageIterator()
+	count := 0
+	for pages.HasMore() {
+		if _, err := pages.LoadMore(ctx); err != nil {
+			t.Fatalf("loading page %d: %+v", count, err)
+		}
+		count++
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 pages but got %d", count)
+	}
+	if *requests != 3 {
+		t.Fatalf("expected 3 requests but got %d", *requests)
+	}
+	if token := pages.ContinuationToken(); token != "" {
+		t.Fatalf("expected an empty continuation token but got %q", token)
+	}
+	if _, err := pages.LoadMore(ctx); err == nil {
+		t.Fatalf("expected an error when loading beyond the last page but didn't get one")
+	}
+}
+
+func TestListIterator(t *testing.T) {
+	server, _ := pagedTestServer(t, 3)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	items := NewListIterator[pagedTestItem](newPagedTestRequest(t, ctx, server.URL).PageIterator())
+	names := make([]string, 0)
+	for items.Next(ctx) {
+		names = append(names, items.Item().Name)
+	}
+	if err := items.Err(); err != nil {
+		t.Fatalf("iterating: %+v", err)
+	}
+	if len(names) != 6 {
+		t.Fatalf("expected 6 items but got %d: %v", len(names), names)
+	}
+	if names[0] != "item-0-0" || names[5] != "item-2-1" {
+		t.Fatalf("unexpected items returned: %v", names)
+	}
+}
+
+func TestListIterator_EarlyTerminationAndResume(t *testing.T) {
+	server, requests := pagedTestServer(t, 5)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	items := NewListIterator[pagedTestItem](newPagedTestRequest(t, ctx, server.URL).PageIterator())
+	for i := 0; i < 2; i++ {
+		if !items.Next(ctx) {
+			t.Fatalf("expected item %d but iteration ended: %+v", i, items.Err())
+		}
+	}
+	if *requests != 1 {
+		t.Fatalf("expected only 1 page to have been requested but got %d", *requests)
+	}
+
+	token := items.ContinuationToken()
+	if token == "" {
+		t.Fatalf("expected a continuation token but got none")
+	}
+
+	pages, err := newPagedTestRequest(t, ctx, server.URL).PageIteratorFromContinuationToken(token)
+	if err != nil {
+		t.Fatalf("resuming from continuation token: %+v", err)
+	}
+	resumed := NewListIterator[pagedTestItem](pages)
+	if !resumed.Next(ctx) {
+		t.Fatalf("expected an item after resuming but iteration ended: %+v", resumed.Err())
+	}
+	if name := resumed.Item().Name; name != "item-1-0" {
+		t.Fatalf("expected the first item after resuming to be %q but got %q", "item-1-0", name)
+	}
+}
+
+func TestListIterator_Count(t *testing.T) {
+	server, _ := pagedTestServer(t, 3)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	items := NewListIterator[pagedTestItem](newPagedTestRequest(t, ctx, server.URL).PageIterator())
+	if !items.Next(ctx) {
+		t.Fatalf("expected an item but iteration ended: %+v", items.Err())
+	}
+	if count := items.Count(); count != nil {
+		t.Fatalf("expected no count when not requested but got %d", *count)
+	}
+
+	items = NewListIterator[pagedTestItem](newPagedTestRequestWithOptions(t, ctx, server.URL, &requestOptions{query: &odata.Query{Count: true}}).PageIterator())
+	for items.Next(ctx) {
+	}
+	if err := items.Err(); err != nil {
+		t.Fatalf("iterating: %+v", err)
+	}
+	if count := items.Count(); count == nil || *count != 6 {
+		t.Fatalf("expected a count of 6 to be retained from the first page but got %v", count)
+	}
+}
+
+func TestExecutePaged_Count(t *testing.T) {
+	server, _ := pagedTestServer(t, 3)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := newPagedTestRequestWithOptions(t, ctx, server.URL, &requestOptions{query: &odata.Query{Count: true}}).ExecutePaged(ctx)
+	if err != nil {
+		t.Fatalf("ExecutePaged(): %v", err)
+	}
+	if resp.OData == nil || resp.OData.Count == nil || *resp.OData.Count != 6 {
+		t.Fatalf("expected the OData count to be 6 but got %+v", resp.OData)
+	}
+
+	var result struct {
+		Count  *int            `json:"@odata.count"`
+		Values []pagedTestItem `json:"value"`
+	}
+	if err = resp.Unmarshal(&result); err != nil {
+		t.Fatalf("unmarshaling result: %v", err)
+	}
+	if len(result.Values) != 6 || result.Count == nil || *result.Count != 6 {
+		t.Fatalf("expected 6 items with a count of 6 but got %d items with count %v", len(result.Values), result.Count)
+	}
+}
+
+func TestPageIterator_RequestIsReusable(t *testing.T) {
+	server, requests := pagedTestServer(t, 3)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req := newPagedTestRequest(t, ctx, server.URL)
+	uri := req.URL.String()
+	for i := 0; i < 2; i++ {
+		items := NewListIterator[pagedTestItem](req.PageIterator())
+		count := 0
+		for items.Next(ctx) {
+			count++
+		}
+		if err := items.Err(); err != nil {
+			t.Fatalf("iterating (attempt %d): %+v", i, err)
+		}
+		if count != 6 {
+			t.Fatalf("expected 6 items (attempt %d) but got %d", i, count)
+		}
+		if req.URL.String() != uri {
+			t.Fatalf("expected the request URL to remain %q but got %q", uri, req.URL.String())
+		}
+	}
+	if *requests != 6 {
+		t.Fatalf("expected 6 requests but got %d", *requests)
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package pollers
+
+import (
+	"context"
+)
+
+type withoutPollingDelaysKey struct{}
+
+// WithoutPollingDelays returns a copy of ctx which causes PollUntilDone to poll again immediately, rather than
+// waiting for the poll interval. This is intended for replaying recorded responses in tests.
+func WithoutPollingDelays(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutPollingDelaysKey{}, true)
+}
+
+// pollingDelaysDisabled determines whether polling delays have been disabled using WithoutPollingDelays
+func pollingDelaysDisabled(ctx context.Context) bool {
+	v, ok := ctx.Value(withoutPollingDelaysKey{}).(bool)
+	return ok && v
+}
@@ -31,6 +31,9 @@ type PollResult struct {
 	// HttpResponse is a copy of the HttpResponse returned from the API in the last request.
 	HttpResponse *client.Response
 
+	// PercentComplete optionally specifies the progress of the operation, when reported by the API.
+	PercentComplete *float64
+
 	// PollInterval specifies the interval until this poller should be called again.
 	PollInterval time.Duration
 
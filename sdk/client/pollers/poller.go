@@ -20,18 +20,56 @@ type Poller struct {
 	// example when a connection is dropped.
 	initialDelayDuration time.Duration
 
+	// maxNumberOfDroppedConnections specifies the maximum number of sequential dropped connections before an error is raised.
+	maxNumberOfDroppedConnections int
+
+	// poller is a reference to the PollerType, for example a LongRunningOperationPoller
+	// which should be polled to determine the latest state.
+	poller PollerType
+
+	// state contains the latest results and any registered callbacks, which can be accessed concurrently
+	// whilst polling. This is a pointer so that the Poller can be safely copied.
+	state *pollerState
+
+	// tracer is an optional client.Tracer used to start a span for each poll
+	tracer client.Tracer
+
+	// metrics is an optional client.Metrics used to record the duration of each poll
+	metrics client.Metrics
+
+	// withoutDelays causes PollUntilDone to poll again immediately, rather than waiting for the poll interval
+	withoutDelays bool
+}
+
+type pollerState struct {
+	sync.Mutex
+
+	// callbacks are invoked after each poll
+	callbacks []func(PollProgress)
+
 	// latestError contains the error returned from the latest poll.
 	latestError error
 
 	// latestResponse contains the polling status from the latest response.
 	latestResponse *PollResult
+}
 
-	// maxNumberOfDroppedConnections specifies the maximum number of sequential dropped connections before an error is raised.
-	maxNumberOfDroppedConnections int
+// PollProgress describes the outcome of a single poll, and is passed to callbacks registered using OnPoll
+type PollProgress struct {
+	// Error is the error returned from this poll, if any
+	Error error
 
-	// poller is a reference to the PollerType, for example a LongRunningOperationPoller
-	// which should be polled to determine the latest state.
-	poller PollerType
+	// HttpResponse is the HTTP Response returned from this poll, when available
+	HttpResponse *client.Response
+
+	// NextPollInterval is the interval until the next poll, when the operation is still in progress
+	NextPollInterval time.Duration
+
+	// PercentComplete is the progress of the operation, when reported by the API
+	PercentComplete *float64
+
+	// Status is the polling status following this poll
+	Status PollingStatus
 }
 
 func NewPoller(pollerType PollerType, initialDelayDuration time.Duration, maxNumberOfDroppedConnections int) Poller {
@@ -39,58 +77,105 @@ func NewPoller(pollerType PollerType, initialDelayDuration time.Duration, maxNum
 		initialDelayDuration:          initialDelayDuration,
 		maxNumberOfDroppedConnections: maxNumberOfDroppedConnections,
 		poller:                        pollerType,
+		state:                         &pollerState{},
+	}
+}
+
+// Instrument configures the Poller to start a span and record metrics for each poll, using the provided
+// client.Tracer and client.Metrics, either of which can be nil.
+func (p *Poller) Instrument(tracer client.Tracer, metrics client.Metrics) {
+	p.tracer = tracer
+	p.metrics = metrics
+}
+
+// DisablePollingDelays configures the Poller to poll again immediately, rather than waiting for the poll interval.
+// This is intended for replaying recorded responses in tests, and has the same effect as WithoutPollingDelays.
+func (p *Poller) DisablePollingDelays() {
+	p.withoutDelays = true
+}
+
+// OnPoll registers a callback which is invoked after each poll performed by PollUntilDone, reporting the status,
+// progress and any error. Callbacks are invoked sequentially from the polling goroutine, so should not block.
+func (p *Poller) OnPoll(callback func(PollProgress)) {
+	if p.state == nil || callback == nil {
+		return
 	}
+	p.state.Lock()
+	defer p.state.Unlock()
+	p.state.callbacks = append(p.state.callbacks, callback)
+}
+
+// ResumeToken returns an opaque token describing the operation being polled, which can be persisted so that polling
+// can be resumed by another process (for example using resourcemanager.PollerFromResumeToken)
+func (p *Poller) ResumeToken() (string, error) {
+	resumable, ok := p.poller.(ResumablePollerType)
+	if !ok {
+		return "", fmt.Errorf("the poller type %T does not support resuming", p.poller)
+	}
+
+	token := resumable.ResumeToken()
+	if latestResponse, _ := p.latest(); latestResponse != nil && latestResponse.PollInterval > 0 {
+		token.PollInterval = latestResponse.PollInterval
+	}
+	return token.Encode()
 }
 
 // LatestResponse returns the latest HTTP Response returned when polling
 func (p *Poller) LatestResponse() *client.Response {
-	if p.latestError != nil {
-		if v, ok := p.latestError.(PollingCancelledError); ok {
+	latestResponse, latestError := p.latest()
+	if latestError != nil {
+		if v, ok := latestError.(PollingCancelledError); ok {
 			return v.HttpResponse
 		}
-		if _, ok := p.latestError.(PollingDroppedConnectionError); ok {
+		if _, ok := latestError.(PollingDroppedConnectionError); ok {
 			return nil
 		}
-		if v, ok := p.latestError.(PollingFailedError); ok {
+		if v, ok := latestError.(PollingFailedError); ok {
 			return v.HttpResponse
 		}
 
-		if p.latestError == context.DeadlineExceeded {
+		if latestError == context.DeadlineExceeded {
 			return nil
 		}
 	}
 
-	if p.latestResponse == nil {
+	if latestResponse == nil {
 		return nil
 	}
 
-	return p.latestResponse.HttpResponse
+	return latestResponse.HttpResponse
 }
 
 // LatestStatus returns the latest status returned when polling
 func (p *Poller) LatestStatus() PollingStatus {
-	if p.latestError != nil {
-		if _, ok := p.latestError.(PollingCancelledError); ok {
+	latestResponse, latestError := p.latest()
+	return pollingStatus(latestResponse, latestError)
+}
+
+// pollingStatus determines the polling status from the result of a poll
+func pollingStatus(result *PollResult, err error) PollingStatus {
+	if err != nil {
+		if _, ok := err.(PollingCancelledError); ok {
 			return PollingStatusCancelled
 		}
-		if _, ok := p.latestError.(PollingDroppedConnectionError); ok {
+		if _, ok := err.(PollingDroppedConnectionError); ok {
 			// we could look to expose a status for this, but we likely wouldn't handle this any differently
 			// to it being unknown, so I (@tombuildsstuff) think this is reasonable for now?
 			return PollingStatusUnknown
 		}
-		if _, ok := p.latestError.(PollingFailedError); ok {
+		if _, ok := err.(PollingFailedError); ok {
 			return PollingStatusFailed
 		}
-		if p.latestError == context.DeadlineExceeded {
+		if err == context.DeadlineExceeded {
 			return PollingStatusUnknown
 		}
 	}
 
-	if p.latestResponse == nil {
+	if result == nil {
 		return PollingStatusUnknown
 	}
 
-	return p.latestResponse.Status
+	return result.Status
 }
 
 // PollUntilDone polls until the poller determines that the operation has been completed
@@ -101,113 +186,217 @@ func (p *Poller) PollUntilDone(ctx context.Context) error {
 	if _, ok := ctx.Deadline(); !ok {
 		return fmt.Errorf("internal-error: `ctx` should have a deadline")
 	}
+	if p.state == nil {
+		p.state = &pollerState{}
+	}
 
-	var wait sync.WaitGroup
-	wait.Add(1)
-
+	waitDone := make(chan error, 1)
 	go func() {
-		connectionDropCounter := 0
-		retryDuration := p.initialDelayDuration
-		for true {
-			// determine the next retry duration / how long to poll for
-			if p.latestResponse != nil {
-				retryDuration = p.latestResponse.PollInterval
-			}
-			endTime := time.Now().Add(retryDuration)
-			select {
-			case <-time.After(time.Until(endTime)):
-				{
-					break
-				}
-			}
+		waitDone <- p.pollUntilDone(ctx)
+	}()
 
-			p.latestResponse, p.latestError = p.poller.Poll(ctx)
+	select {
+	case err := <-waitDone:
+		return err
+	case <-ctx.Done():
+		{
+			p.state.Lock()
+			p.state.latestResponse = nil
+			p.state.latestError = ctx.Err()
+			p.state.Unlock()
+			return ctx.Err()
+		}
+	}
+}
 
-			// first check the connection drop status
-			connectionHasBeenDropped := false
-			if p.latestResponse == nil && p.latestError == nil {
-				// connection drops can either have no response/error (where we have no context)
-				connectionHasBeenDropped = true
-			} else if _, ok := p.latestError.(PollingDroppedConnectionError); ok {
-				// or have an error with more details (e.g. server not found, connection reset etc)
-				connectionHasBeenDropped = true
-			}
-			if connectionHasBeenDropped {
-				connectionDropCounter++
-				if connectionDropCounter < p.maxNumberOfDroppedConnections {
-					continue
-				}
-				if p.latestResponse == nil && p.latestError == nil {
-					// the connection was dropped, but we have no context
-					p.latestError = PollingDroppedConnectionError{}
-					break
-				}
-			} else {
-				connectionDropCounter = 0
-			}
+// pollUntilDone performs the polling loop for PollUntilDone, returning the final error (if any)
+func (p *Poller) pollUntilDone(ctx context.Context) error {
+	connectionDropCounter := 0
+	retryDuration := p.initialDelayDuration
+
+	var latestResponse *PollResult
+	var latestError error
+	for true {
+		// determine the next retry duration / how long to poll for
+		if latestResponse != nil {
+			retryDuration = latestResponse.PollInterval
+		}
+		if p.withoutDelays || pollingDelaysDisabled(ctx) {
+			retryDuration = 0
+		}
+		select {
+		case <-time.After(retryDuration):
+			break
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 
-			if p.latestError != nil {
+		latestResponse, latestError = p.poll(ctx)
+		p.setLatest(ctx, latestResponse, latestError)
+		p.notify(latestResponse, latestError)
+
+		// first check the connection drop status
+		connectionHasBeenDropped := false
+		if latestResponse == nil && latestError == nil {
+			// connection drops can either have no response/error (where we have no context)
+			connectionHasBeenDropped = true
+		} else if _, ok := latestError.(PollingDroppedConnectionError); ok {
+			// or have an error with more details (e.g. server not found, connection reset etc)
+			connectionHasBeenDropped = true
+		}
+		if connectionHasBeenDropped {
+			connectionDropCounter++
+			if connectionDropCounter < p.maxNumberOfDroppedConnections {
+				continue
+			}
+			if latestResponse == nil && latestError == nil {
+				// the connection was dropped, but we have no context
+				latestError = PollingDroppedConnectionError{}
 				break
 			}
+		} else {
+			connectionDropCounter = 0
+		}
 
-			if response := p.latestResponse; response != nil {
-				retryDuration = response.PollInterval
+		if latestError != nil {
+			break
+		}
 
-				done := false
-				switch response.Status {
-				// Cancelled, Dropped Connections and Failed should be raised as errors containing additional info if available
+		if response := latestResponse; response != nil {
+			retryDuration = response.PollInterval
 
-				case PollingStatusCancelled:
-					p.latestError = fmt.Errorf("internal-error: a polling status of `Cancelled` should be surfaced as a PollingCancelledError")
-					done = true
-					break
+			done := false
+			switch response.Status {
+			// Cancelled, Dropped Connections and Failed should be raised as errors containing additional info if available
 
-				case PollingStatusFailed:
-					p.latestError = fmt.Errorf("internal-error: a polling status of `Failed` should be surfaced as a PollingFailedError")
-					done = true
-					break
+			case PollingStatusCancelled:
+				latestError = fmt.Errorf("internal-error: a polling status of `Cancelled` should be surfaced as a PollingCancelledError")
+				done = true
+				break
 
-				case PollingStatusInProgress:
-					continue
+			case PollingStatusFailed:
+				latestError = fmt.Errorf("internal-error: a polling status of `Failed` should be surfaced as a PollingFailedError")
+				done = true
+				break
 
-				case PollingStatusSucceeded:
-					done = true
-					break
+			case PollingStatusInProgress:
+				continue
 
-				default:
-					p.latestError = fmt.Errorf("internal-error: unimplemented polling status %q", string(response.Status))
-					done = true
-					break
-				}
+			case PollingStatusSucceeded:
+				done = true
+				break
 
-				if done {
-					break
-				}
+			default:
+				latestError = fmt.Errorf("internal-error: unimplemented polling status %q", string(response.Status))
+				done = true
+				break
+			}
+
+			if done {
+				break
 			}
 		}
-		wait.Done()
-	}()
+	}
 
-	waitDone := make(chan struct{}, 1)
-	go func() {
-		wait.Wait()
-		waitDone <- struct{}{}
-	}()
+	if latestError != nil {
+		latestResponse = nil
+	}
+	p.setLatest(ctx, latestResponse, latestError)
 
-	select {
-	case <-waitDone:
-		break
-	case <-ctx.Done():
-		{
-			p.latestResponse = nil
-			p.latestError = ctx.Err()
-			return p.latestError
+	return latestError
+}
+
+// latest returns the latest response and error, which are safe to read whilst polling
+func (p *Poller) latest() (*PollResult, error) {
+	if p.state == nil {
+		return nil, nil
+	}
+	p.state.Lock()
+	defer p.state.Unlock()
+	return p.state.latestResponse, p.state.latestError
+}
+
+// setLatest records the latest response and error, unless ctx has been cancelled, in which case PollUntilDone has
+// already recorded the context's error
+func (p *Poller) setLatest(ctx context.Context, latestResponse *PollResult, latestError error) {
+	p.state.Lock()
+	defer p.state.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	p.state.latestResponse = latestResponse
+	p.state.latestError = latestError
+}
+
+// notify invokes any callbacks registered using OnPoll with the outcome of a poll
+func (p *Poller) notify(result *PollResult, err error) {
+	p.state.Lock()
+	callbacks := append([]func(PollProgress){}, p.state.callbacks...)
+	p.state.Unlock()
+	if len(callbacks) == 0 {
+		return
+	}
+
+	progress := PollProgress{
+		Error:  err,
+		Status: pollingStatus(result, err),
+	}
+	if result != nil {
+		progress.HttpResponse = result.HttpResponse
+		progress.PercentComplete = result.PercentComplete
+		if result.Status == PollingStatusInProgress {
+			progress.NextPollInterval = result.PollInterval
+		}
+	}
+	if progress.HttpResponse == nil {
+		switch v := err.(type) {
+		case PollingCancelledError:
+			progress.HttpResponse = v.HttpResponse
+		case PollingFailedError:
+			progress.HttpResponse = v.HttpResponse
 		}
 	}
 
-	if p.latestError != nil {
-		p.latestResponse = nil
+	for _, callback := range callbacks {
+		callback(progress)
+	}
+}
+
+// poll performs a single poll using the PollerType, instrumenting it when a tracer or metrics have been configured
+func (p *Poller) poll(ctx context.Context) (*PollResult, error) {
+	if p.tracer == nil && p.metrics == nil {
+		return p.poller.Poll(ctx)
+	}
+
+	var span client.Span
+	if p.tracer != nil {
+		ctx, span = p.tracer.Start(ctx, "poll")
+		defer span.End()
+	}
+
+	start := time.Now()
+	result, err := p.poller.Poll(ctx)
+
+	status := PollingStatusUnknown
+	if result != nil {
+		status = result.Status
+	}
+	attributes := []client.Attribute{
+		{Key: client.AttributePollingStatus, Value: string(status)},
+	}
+	if result != nil && result.HttpResponse != nil && result.HttpResponse.Response != nil {
+		attributes = append(attributes, client.Attribute{Key: client.AttributeHttpStatusCode, Value: result.HttpResponse.StatusCode})
+	}
+
+	if span != nil {
+		span.SetAttributes(attributes...)
+		if err != nil {
+			span.RecordError(err)
+		}
+	}
+	if p.metrics != nil {
+		p.metrics.RecordDuration(ctx, client.MetricPollDuration, time.Since(start), attributes...)
 	}
 
-	return p.latestError
+	return result, err
 }
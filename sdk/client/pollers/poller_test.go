@@ -6,6 +6,8 @@ package pollers_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
@@ -414,6 +416,135 @@ func TestPoller_InProgress_ShouldRaiseAnErrorWhenTimeoutExceeded(t *testing.T) {
 	}
 }
 
+func TestPoller_Instrumented(t *testing.T) {
+	pollerType := fakePollerWithResults([]pollResult{
+		pollers.PollResult{
+			PollInterval: 1 * time.Millisecond,
+			Status:       pollers.PollingStatusInProgress,
+		},
+		pollers.PollResult{
+			PollInterval: 1 * time.Millisecond,
+			Status:       pollers.PollingStatusSucceeded,
+		},
+	})
+	poller := pollers.NewPoller(pollerType, 10*time.Millisecond, pollers.DefaultNumberOfDroppedConnectionsToAllow)
+	tracer := &fakeTracer{}
+	poller.Instrument(tracer, nil)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer cancel()
+	if err := poller.PollUntilDone(ctx); err != nil {
+		t.Fatalf("polling: %+v", err)
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected a span for each of the 2 polls but got %d", len(tracer.spans))
+	}
+	expected := []string{string(pollers.PollingStatusInProgress), string(pollers.PollingStatusSucceeded)}
+	for i, span := range tracer.spans {
+		if !span.ended {
+			t.Fatalf("expected span %d to have ended", i)
+		}
+		if v := span.attributes[client.AttributePollingStatus]; v != expected[i] {
+			t.Fatalf("expected span %d to have the polling status %q but got %v", i, expected[i], v)
+		}
+	}
+}
+
+func TestPoller_OnPoll(t *testing.T) {
+	percentComplete := 50.0
+	pollerType := fakePollerWithResults([]pollResult{
+		pollers.PollResult{
+			PercentComplete: &percentComplete,
+			PollInterval:    2 * time.Millisecond,
+			Status:          pollers.PollingStatusInProgress,
+		},
+		connectionDroppedResult{},
+		errorResult{
+			Error: pollers.PollingFailedError{
+				Message: "out of capacity",
+			},
+		},
+	})
+	poller := pollers.NewPoller(pollerType, 10*time.Millisecond, pollers.DefaultNumberOfDroppedConnectionsToAllow)
+
+	var mu sync.Mutex
+	progress := make([]pollers.PollProgress, 0)
+	poller.OnPoll(func(p pollers.PollProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress = append(progress, p)
+	})
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(5*time.Second))
+	defer cancel()
+
+	// the latest status should be safe to read whilst polling
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = poller.LatestStatus()
+				_ = poller.LatestResponse()
+			}
+		}
+	}()
+	err := poller.PollUntilDone(ctx)
+	close(done)
+	if err == nil {
+		t.Fatalf("expected an error but didn't get one")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(progress) != 3 {
+		t.Fatalf("expected 3 progress updates but got %d", len(progress))
+	}
+	if progress[0].Status != pollers.PollingStatusInProgress || progress[0].PercentComplete == nil || *progress[0].PercentComplete != 50 || progress[0].NextPollInterval != 2*time.Millisecond {
+		t.Fatalf("unexpected first progress update: %+v", progress[0])
+	}
+	if progress[1].Status != pollers.PollingStatusUnknown || progress[1].Error != nil {
+		t.Fatalf("unexpected progress update for a dropped connection: %+v", progress[1])
+	}
+	if progress[2].Status != pollers.PollingStatusFailed || progress[2].Error == nil {
+		t.Fatalf("unexpected progress update for a failure: %+v", progress[2])
+	}
+}
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, _ string, attributes ...client.Attribute) (context.Context, client.Span) {
+	span := &fakeSpan{
+		attributes: map[string]interface{}{},
+	}
+	span.SetAttributes(attributes...)
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func (t *fakeTracer) Inject(_ context.Context, _ http.Header) {}
+
+type fakeSpan struct {
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *fakeSpan) SetAttributes(attributes ...client.Attribute) {
+	for _, a := range attributes {
+		s.attributes[a.Key] = a.Value
+	}
+}
+
+func (s *fakeSpan) RecordError(_ error) {}
+
+func (s *fakeSpan) End() {
+	s.ended = true
+}
+
 type pollResult interface {
 }
 
@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package pollers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// resumeTokenVersion is the version of the ResumeToken format, which is incremented for incompatible changes
+const resumeTokenVersion = 1
+
+// ResumeToken describes an in-progress long-running operation, so that polling can be resumed by another process
+type ResumeToken struct {
+	// Version is the version of the ResumeToken format
+	Version int `json:"version"`
+
+	// Type is the type of poller, which determines how the remaining fields are interpreted
+	Type string `json:"type"`
+
+	// PollingUrl is the URL which is polled to determine the status of the operation
+	PollingUrl string `json:"pollingUrl,omitempty"`
+
+	// OriginalUrl is the URL of the request which started the operation
+	OriginalUrl string `json:"originalUrl,omitempty"`
+
+	// PollInterval is the interval between polls
+	PollInterval time.Duration `json:"pollInterval,omitempty"`
+}
+
+// ResumablePollerType is implemented by a PollerType which can be resumed by another process using a ResumeToken
+type ResumablePollerType interface {
+	PollerType
+
+	// ResumeToken returns a ResumeToken describing the operation being polled
+	ResumeToken() ResumeToken
+}
+
+// Encode returns the ResumeToken as an opaque string, which can be persisted and later decoded using DecodeResumeToken
+func (t ResumeToken) Encode() (string, error) {
+	t.Version = resumeTokenVersion
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("marshaling resume token: %+v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeResumeToken parses a ResumeToken previously returned by Encode
+func DecodeResumeToken(token string) (*ResumeToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("decoding resume token: %+v", err)
+	}
+
+	var out ResumeToken
+	if err = json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("unmarshaling resume token: %+v", err)
+	}
+	if out.Version != resumeTokenVersion {
+		return nil, fmt.Errorf("unsupported resume token version %d", out.Version)
+	}
+	if out.Type == "" {
+		return nil, fmt.Errorf("resume token did not specify a poller type")
+	}
+
+	return &out, nil
+}
@@ -28,6 +28,11 @@ type RequestOptions struct {
 
 	// Path is the absolute URI for this request, with a leading slash.
 	Path string
+
+	// RetryFunc is an optional function to determine whether a failed request should be retried to work around
+	// eventual consistency issues, e.g. RetryOn404ConsistencyFailureFunc when reading an object immediately after
+	// creating it. This is consulted in addition to any retry functions used by default for the API.
+	RetryFunc RequestRetryFunc
 }
 
 func (ro RequestOptions) Validate() error {
@@ -86,7 +86,7 @@ func (c *Client) NewRequest(ctx context.Context, input client.RequestOptions) (*
 
 	req.URL.RawQuery = query.Encode()
 	req.Pager = input.Pager
-	req.RetryFunc = client.RequestRetryAny(defaultRetryFunctions...)
+	req.RetryFunc = client.RequestRetryAny(client.RequestRetryAny(defaultRetryFunctions...), input.RetryFunc)
 	req.ValidStatusCodes = input.ExpectedStatusCodes
 
 	return req, nil
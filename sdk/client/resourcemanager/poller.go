@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/client"
 	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
@@ -31,7 +32,7 @@ func PollerFromResponse(response *client.Response, client *Client) (poller polle
 			err = lroErr
 			return pollers.Poller{}, fmt.Errorf("building long-running-operation poller: %+v", lroErr)
 		}
-		return pollers.NewPoller(lro, lro.initialRetryDuration, pollers.DefaultNumberOfDroppedConnectionsToAllow), nil
+		return newPoller(lro, lro.initialRetryDuration, client), nil
 	}
 
 	// or we should be polling on the `provisioningState` of the resource
@@ -51,7 +52,7 @@ func PollerFromResponse(response *client.Response, client *Client) (poller polle
 			err = provisioningStateErr
 			return pollers.Poller{}, fmt.Errorf("building provisioningState poller: %+v", provisioningStateErr)
 		}
-		return pollers.NewPoller(provisioningState, provisioningState.initialRetryDuration, pollers.DefaultNumberOfDroppedConnectionsToAllow), nil
+		return newPoller(provisioningState, provisioningState.initialRetryDuration, client), nil
 	}
 
 	// finally, if it was a Delete that returned a 200/204
@@ -62,12 +63,98 @@ func PollerFromResponse(response *client.Response, client *Client) (poller polle
 			err = deletePollerErr
 			return pollers.Poller{}, fmt.Errorf("building delete poller: %+v", deletePollerErr)
 		}
-		return pollers.NewPoller(deletePoller, deletePoller.initialRetryDuration, pollers.DefaultNumberOfDroppedConnectionsToAllow), nil
+		return newPoller(deletePoller, deletePoller.initialRetryDuration, client), nil
 	}
 
 	return pollers.Poller{}, fmt.Errorf("no applicable pollers were found for the response")
 }
 
+// poller types used within a pollers.ResumeToken
+const (
+	pollerTypeDelete               = "ResourceManager/Delete"
+	pollerTypeLongRunningOperation = "ResourceManager/LongRunningOperation"
+	pollerTypeProvisioningState    = "ResourceManager/ProvisioningState"
+)
+
+// PollerFromResumeToken returns a pollers.Poller which resumes polling the operation described by a token
+// previously obtained from pollers.Poller's ResumeToken method, for example in a new process
+func PollerFromResumeToken(token string, client *Client) (pollers.Poller, error) {
+	resumeToken, err := pollers.DecodeResumeToken(token)
+	if err != nil {
+		return pollers.Poller{}, err
+	}
+
+	pollInterval := resumeToken.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollingInterval
+	}
+
+	switch resumeToken.Type {
+	case pollerTypeLongRunningOperation:
+		pollingUrl, err := url.Parse(resumeToken.PollingUrl)
+		if err != nil {
+			return pollers.Poller{}, fmt.Errorf("parsing polling URL %q: %+v", resumeToken.PollingUrl, err)
+		}
+		if !pollingUrl.IsAbs() {
+			return pollers.Poller{}, fmt.Errorf("invalid polling URL %q: URL was not absolute", resumeToken.PollingUrl)
+		}
+		lro := &longRunningOperationPoller{
+			client:               client.Client,
+			initialRetryDuration: pollInterval,
+			pollingUrl:           pollingUrl,
+		}
+		if resumeToken.OriginalUrl != "" {
+			if lro.originalUrl, err = url.Parse(resumeToken.OriginalUrl); err != nil {
+				return pollers.Poller{}, fmt.Errorf("parsing original URL %q: %+v", resumeToken.OriginalUrl, err)
+			}
+		}
+		return newPoller(lro, pollInterval, client), nil
+
+	case pollerTypeDelete, pollerTypeProvisioningState:
+		if resumeToken.PollingUrl == "" {
+			return pollers.Poller{}, fmt.Errorf("resume token did not specify a polling URL")
+		}
+		originalUrl, err := url.Parse(resumeToken.OriginalUrl)
+		if err != nil {
+			return pollers.Poller{}, fmt.Errorf("parsing original URL %q: %+v", resumeToken.OriginalUrl, err)
+		}
+		apiVersion := originalUrl.Query().Get("api-version")
+		if apiVersion == "" {
+			return pollers.Poller{}, fmt.Errorf("unable to determine `api-version` from %q", resumeToken.OriginalUrl)
+		}
+
+		if resumeToken.Type == pollerTypeDelete {
+			return newPoller(&deletePoller{
+				apiVersion:           apiVersion,
+				client:               client,
+				initialRetryDuration: pollInterval,
+				originalUri:          resumeToken.OriginalUrl,
+				resourcePath:         resumeToken.PollingUrl,
+			}, pollInterval, client), nil
+		}
+		return newPoller(&provisioningStatePoller{
+			apiVersion:           apiVersion,
+			client:               client,
+			initialRetryDuration: pollInterval,
+			originalUri:          resumeToken.OriginalUrl,
+			resourcePath:         resumeToken.PollingUrl,
+		}, pollInterval, client), nil
+	}
+
+	return pollers.Poller{}, fmt.Errorf("unsupported poller type %q in resume token", resumeToken.Type)
+}
+
+// newPoller returns a pollers.Poller for the provided PollerType, instrumented using the client's Tracer and Metrics
+// and without polling delays when these have been disabled for the client
+func newPoller(pollerType pollers.PollerType, initialDelayDuration time.Duration, client *Client) pollers.Poller {
+	poller := pollers.NewPoller(pollerType, initialDelayDuration, pollers.DefaultNumberOfDroppedConnectionsToAllow)
+	poller.Instrument(client.Tracer, client.Metrics)
+	if client.DisablePollingDelays {
+		poller.DisablePollingDelays()
+	}
+	return poller
+}
+
 func isLROSelfReference(lroPollingUri, originalRequestUri string) bool {
 	// Some APIs return a LRO URI of themselves, meaning that we should be checking a 200 OK is returned rather
 	// than polling as usual. Automation@2022-08-08 - DSCNodeConfiguration CreateOrUpdate is one such example.
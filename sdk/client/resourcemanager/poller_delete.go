@@ -14,7 +14,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/sdk/odata"
 )
 
-var _ pollers.PollerType = &deletePoller{}
+var _ pollers.ResumablePollerType = &deletePoller{}
 
 type deletePoller struct {
 	apiVersion           string
@@ -56,6 +56,15 @@ func deletePollerFromResponse(response *client.Response, client *Client, polling
 	}, nil
 }
 
+func (p deletePoller) ResumeToken() pollers.ResumeToken {
+	return pollers.ResumeToken{
+		Type:         pollerTypeDelete,
+		PollingUrl:   p.resourcePath,
+		OriginalUrl:  p.originalUri,
+		PollInterval: p.initialRetryDuration,
+	}
+}
+
 func (p deletePoller) Poll(ctx context.Context) (result *pollers.PollResult, err error) {
 	opts := client.RequestOptions{
 		ContentType: "application/json; charset=utf-8",
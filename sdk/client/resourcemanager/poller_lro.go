@@ -19,7 +19,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
 )
 
-var _ pollers.PollerType = &longRunningOperationPoller{}
+var _ pollers.ResumablePollerType = &longRunningOperationPoller{}
 
 type longRunningOperationPoller struct {
 	client               *client.Client
@@ -70,6 +70,20 @@ func longRunningOperationPollerFromResponse(resp *client.Response, client *clien
 	return &poller, nil
 }
 
+func (p *longRunningOperationPoller) ResumeToken() pollers.ResumeToken {
+	token := pollers.ResumeToken{
+		Type:         pollerTypeLongRunningOperation,
+		PollInterval: p.initialRetryDuration,
+	}
+	if p.pollingUrl != nil {
+		token.PollingUrl = p.pollingUrl.String()
+	}
+	if p.originalUrl != nil {
+		token.OriginalUrl = p.originalUrl.String()
+	}
+	return token
+}
+
 func (p *longRunningOperationPoller) Poll(ctx context.Context) (result *pollers.PollResult, err error) {
 	p.count++
 
@@ -204,6 +218,8 @@ func (p *longRunningOperationPoller) Poll(ctx context.Context) (result *pollers.
 			// HealthBot @ 2022-08-08 (HealthBots CreateOrUpdate) returns `Working` during Creation
 			"Working": pollers.PollingStatusInProgress,
 		}
+		result.PercentComplete = op.PercentComplete
+
 		for k, v := range statuses {
 			if strings.EqualFold(string(op.Properties.ProvisioningState), string(k)) {
 				result.Status = v
@@ -255,6 +271,9 @@ type operationResult struct {
 	// >  cannot parse " 01:58:30 +0000" as "T"
 	StartTime *string `json:"startTime"`
 
+	// PercentComplete is optionally returned to indicate the progress of the operation
+	PercentComplete *float64 `json:"percentComplete"`
+
 	Properties struct {
 		// Some APIs (such as Storage) return the Resource Representation from the LRO API, as such we need to check provisioningState
 		ProvisioningState status `json:"provisioningState"`
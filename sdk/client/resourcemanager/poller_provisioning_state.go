@@ -19,7 +19,7 @@ import (
 
 const DefaultPollingInterval = 10 * time.Second
 
-var _ pollers.PollerType = &provisioningStatePoller{}
+var _ pollers.ResumablePollerType = &provisioningStatePoller{}
 
 type provisioningStatePoller struct {
 	apiVersion           string
@@ -66,6 +66,15 @@ func provisioningStatePollerFromResponse(response *client.Response, lroIsSelfRef
 	}, nil
 }
 
+func (p *provisioningStatePoller) ResumeToken() pollers.ResumeToken {
+	return pollers.ResumeToken{
+		Type:         pollerTypeProvisioningState,
+		PollingUrl:   p.resourcePath,
+		OriginalUrl:  p.originalUri,
+		PollInterval: p.initialRetryDuration,
+	}
+}
+
 func (p *provisioningStatePoller) Poll(ctx context.Context) (*pollers.PollResult, error) {
 	opts := client.RequestOptions{
 		ContentType: "application/json; charset=utf-8",
@@ -4,14 +4,18 @@
 package resourcemanager_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
 	"github.com/hashicorp/go-azure-sdk/sdk/client/resourcemanager"
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/testing/fakearm"
 )
 
 func TestNewPoller_LongRunningOperation(t *testing.T) {
@@ -474,3 +478,140 @@ func TestNewPoller_ProvisioningState_Invalid(t *testing.T) {
 		}
 	}
 }
+
+func TestPollerFromResumeToken(t *testing.T) {
+	for _, mode := range []fakearm.PollingMode{fakearm.PollingModeAsyncOperation, fakearm.PollingModeLocation, fakearm.PollingModeProvisioningState} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(pollers.WithoutPollingDelays(context.Background()), 30*time.Second)
+			defer cancel()
+
+			server := fakearm.NewServer(fakearm.Options{
+				PollingMode:        mode,
+				PollsUntilComplete: 3,
+			})
+			defer server.Close()
+			id := "/subscriptions/11111111-1111-1111-1111-111111111111/resourceGroups/example/providers/Microsoft.Example/things/first"
+
+			for _, method := range []string{http.MethodPut, http.MethodDelete} {
+				c := newFakeResourceManagerClient(t, server)
+				req, err := c.NewRequest(ctx, client.RequestOptions{
+					ContentType: "application/json; charset=utf-8",
+					ExpectedStatusCodes: []int{
+						http.StatusOK,
+						http.StatusCreated,
+						http.StatusAccepted,
+					},
+					HttpMethod: method,
+					Path:       id,
+				})
+				if err != nil {
+					t.Fatalf("building request: %+v", err)
+				}
+				if method == http.MethodPut {
+					if err = req.Marshal(map[string]interface{}{"location": "westeurope"}); err != nil {
+						t.Fatalf("marshaling request: %+v", err)
+					}
+				}
+				resp, err := req.Execute(ctx)
+				if err != nil {
+					t.Fatalf("executing request: %+v", err)
+				}
+
+				poller, err := resourcemanager.PollerFromResponse(resp, c)
+				if err != nil {
+					t.Fatalf("building poller: %+v", err)
+				}
+				token, err := poller.ResumeToken()
+				if err != nil {
+					t.Fatalf("obtaining resume token: %+v", err)
+				}
+
+				// resume polling using a new client, as would be the case in a new process
+				resumed, err := resourcemanager.PollerFromResumeToken(token, newFakeResourceManagerClient(t, server))
+				if err != nil {
+					t.Fatalf("building poller from resume token: %+v", err)
+				}
+				if err = resumed.PollUntilDone(ctx); err != nil {
+					t.Fatalf("polling: %+v", err)
+				}
+			}
+
+			if _, ok := server.Resource(id); ok {
+				t.Fatalf("expected the resource to have been deleted")
+			}
+		})
+	}
+}
+
+func TestPollerFromResumeToken_Invalid(t *testing.T) {
+	server := fakearm.NewServer(fakearm.Options{})
+	defer server.Close()
+	c := newFakeResourceManagerClient(t, server)
+	for _, token := range []string{"", "not-a-token", "eyJ2ZXJzaW9uIjoxLCJ0eXBlIjoiVW5rbm93biJ9"} {
+		if _, err := resourcemanager.PollerFromResumeToken(token, c); err == nil {
+			t.Fatalf("expected an error for the resume token %q but didn't get one", token)
+		}
+	}
+}
+
+func newFakeResourceManagerClient(t *testing.T, server *fakearm.Server) *resourcemanager.Client {
+	c, err := resourcemanager.NewResourceManagerClient(server.Api(), "example", "2020-01-01")
+	if err != nil {
+		t.Fatalf("building client: %+v", err)
+	}
+	c.Authorizer = server.Authorizer()
+	return c
+}
+
+func TestPoller_LongRunningOperationReportsProgress(t *testing.T) {
+	ctx, cancel := context.WithTimeout(pollers.WithoutPollingDelays(context.Background()), 30*time.Second)
+	defer cancel()
+
+	server := fakearm.NewServer(fakearm.Options{
+		PollingMode:        fakearm.PollingModeAsyncOperation,
+		PollsUntilComplete: 4,
+	})
+	defer server.Close()
+	c := newFakeResourceManagerClient(t, server)
+
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType:         "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{http.StatusCreated},
+		HttpMethod:          http.MethodPut,
+		Path:                "/subscriptions/11111111-1111-1111-1111-111111111111/resourceGroups/example",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	if err = req.Marshal(map[string]interface{}{"location": "westeurope"}); err != nil {
+		t.Fatalf("marshaling request: %+v", err)
+	}
+	resp, err := req.Execute(ctx)
+	if err != nil {
+		t.Fatalf("executing request: %+v", err)
+	}
+
+	poller, err := resourcemanager.PollerFromResponse(resp, c)
+	if err != nil {
+		t.Fatalf("building poller: %+v", err)
+	}
+	progress := make([]float64, 0)
+	poller.OnPoll(func(p pollers.PollProgress) {
+		if p.PercentComplete != nil {
+			progress = append(progress, *p.PercentComplete)
+		}
+	})
+	if err = poller.PollUntilDone(ctx); err != nil {
+		t.Fatalf("polling: %+v", err)
+	}
+
+	expected := []float64{25, 50, 75, 100}
+	if len(progress) != len(expected) {
+		t.Fatalf("expected the progress %v but got %v", expected, progress)
+	}
+	for i := range expected {
+		if progress[i] != expected[i] {
+			t.Fatalf("expected the progress %v but got %v", expected, progress)
+		}
+	}
+}
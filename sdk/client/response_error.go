@@ -0,0 +1,225 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+var _ error = &ResponseError{}
+
+// ResponseError is returned by Execute when the API returns an unexpected status code. Details of the error are
+// parsed from Resource Manager and OData (e.g. Microsoft Graph) JSON error responses, and Storage XML error responses.
+// Use errors.As to obtain a *ResponseError from an error.
+type ResponseError struct {
+	// StatusCode is the HTTP status code returned by the API
+	StatusCode int
+
+	// Code is the error code returned by the API, e.g. `ResourceNotFound`
+	Code string
+
+	// Message is the error message returned by the API
+	Message string
+
+	// Target is the target of the error, e.g. the name of an invalid property, when returned by the API
+	Target string
+
+	// Details contains any nested errors returned by the API
+	Details []ResponseErrorDetail
+
+	// RequestId is the ID of the request, as returned in the `x-ms-request-id` or `request-id` header
+	RequestId string
+
+	// CorrelationId is the correlation ID of the request, as returned in the `x-ms-correlation-request-id` or
+	// `client-request-id` header
+	CorrelationId string
+
+	// RawBody is the unparsed body of the response
+	RawBody string
+
+	// OData is the error returned by an OData API, when available
+	OData *odata.Error
+}
+
+// ResponseErrorDetail is a nested error within a ResponseError
+type ResponseErrorDetail struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Target  string                `json:"target"`
+	Details []ResponseErrorDetail `json:"details"`
+}
+
+func (e *ResponseError) Error() string {
+	if e.OData != nil && e.OData.String() != "" {
+		return fmt.Sprintf("unexpected status %d with error: %s", e.StatusCode, e.OData)
+	}
+	if e.RawBody == "" {
+		return fmt.Sprintf("unexpected status %d received with no body", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status %d with response: %s", e.StatusCode, e.RawBody)
+}
+
+// IsNotFound determines whether err is a ResponseError indicating that the requested resource does not exist
+func IsNotFound(err error) bool {
+	e, ok := asResponseError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusNotFound ||
+		strings.HasSuffix(strings.ToLower(e.Code), "notfound") ||
+		(e.OData != nil && e.OData.Match(odata.ErrorResourceDoesNotExist))
+}
+
+// IsConflict determines whether err is a ResponseError indicating a conflict with the current state of a resource,
+// for example because it already exists
+func IsConflict(err error) bool {
+	e, ok := asResponseError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusConflict ||
+		strings.HasSuffix(strings.ToLower(e.Code), "alreadyexists") ||
+		(e.OData != nil && e.OData.Match(odata.ErrorConflictingObjectPresentInDirectory))
+}
+
+// IsThrottled determines whether err is a ResponseError indicating that the request was rate limited
+func IsThrottled(err error) bool {
+	e, ok := asResponseError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests ||
+		strings.EqualFold(e.Code, "TooManyRequests") ||
+		strings.EqualFold(e.Code, "ServerBusy")
+}
+
+// IsAuthorizationFailed determines whether err is a ResponseError indicating that the caller is not permitted to
+// perform the request
+func IsAuthorizationFailed(err error) bool {
+	e, ok := asResponseError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusForbidden ||
+		strings.EqualFold(e.Code, "AuthorizationFailed") ||
+		strings.EqualFold(e.Code, "Authorization_RequestDenied") ||
+		strings.EqualFold(e.Code, "AuthorizationPermissionMismatch")
+}
+
+func asResponseError(err error) (*ResponseError, bool) {
+	var e *ResponseError
+	if errors.As(err, &e) && e != nil {
+		return e, true
+	}
+	return nil, false
+}
+
+// newResponseError builds a ResponseError from an unexpected response. The response body is read and replaced,
+// so that it can still be read by the caller.
+func newResponseError(resp *Response) (*ResponseError, error) {
+	e := &ResponseError{
+		StatusCode:    resp.StatusCode,
+		Code:          resp.Header.Get("X-Ms-Error-Code"),
+		RequestId:     firstHeader(resp.Header, "X-Ms-Request-Id", "Request-Id"),
+		CorrelationId: firstHeader(resp.Header, "X-Ms-Correlation-Request-Id", "Client-Request-Id"),
+	}
+
+	if resp.Body != nil {
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unexpected status %d, could not read response body", resp.StatusCode)
+		}
+		respBody = bytes.TrimPrefix(respBody, []byte("\xef\xbb\xbf"))
+		resp.Body = io.NopCloser(bytes.NewBuffer(respBody))
+		e.RawBody = string(respBody)
+	}
+
+	if resp.OData != nil && resp.OData.Error != nil {
+		e.OData = resp.OData.Error
+		if v := resp.OData.Error.Code; v != nil && *v != "" {
+			e.Code = *v
+		}
+		if v := resp.OData.Error.Message; v != nil {
+			e.Message = *v
+		}
+		if v := resp.OData.Error.RequestId; v != nil && e.RequestId == "" {
+			e.RequestId = *v
+		}
+		if v := resp.OData.Error.ClientRequestId; v != nil && e.CorrelationId == "" {
+			e.CorrelationId = *v
+		}
+	}
+
+	if e.RawBody != "" {
+		contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+		trimmed := strings.TrimSpace(e.RawBody)
+		switch {
+		case strings.Contains(contentType, "json") || strings.HasPrefix(trimmed, "{"):
+			e.parseJson([]byte(trimmed))
+		case strings.Contains(contentType, "xml") || strings.HasPrefix(trimmed, "<"):
+			e.parseXml([]byte(trimmed))
+		}
+	}
+
+	return e, nil
+}
+
+// parseJson populates the ResponseError from a Resource Manager error response, which is either wrapped in an
+// `error` object or specified at the top level
+func (e *ResponseError) parseJson(body []byte) {
+	var wrapped struct {
+		Error *ResponseErrorDetail `json:"error"`
+	}
+	detail := &ResponseErrorDetail{}
+	if err := json.Unmarshal(body, &wrapped); err == nil && wrapped.Error != nil {
+		detail = wrapped.Error
+	} else if err := json.Unmarshal(body, detail); err != nil {
+		return
+	}
+
+	if detail.Code != "" {
+		e.Code = detail.Code
+	}
+	if detail.Message != "" && e.Message == "" {
+		e.Message = detail.Message
+	}
+	e.Target = detail.Target
+	e.Details = detail.Details
+}
+
+// parseXml populates the ResponseError from a Storage error response
+func (e *ResponseError) parseXml(body []byte) {
+	var storageError struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.Unmarshal(body, &storageError); err != nil {
+		return
+	}
+	if storageError.Code != "" {
+		e.Code = storageError.Code
+	}
+	if storageError.Message != "" {
+		e.Message = strings.TrimSpace(storageError.Message)
+	}
+}
+
+func firstHeader(header http.Header, keys ...string) string {
+	for _, k := range keys {
+		if v := header.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,214 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+func TestNewResponseError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		statusCode    int
+		headers       map[string]string
+		body          string
+		code          string
+		message       string
+		target        string
+		details       int
+		requestId     string
+		correlationId string
+		notFound      bool
+		conflict      bool
+		throttled     bool
+		authorization bool
+	}{
+		{
+			name:       "Resource Manager",
+			statusCode: http.StatusNotFound,
+			headers: map[string]string{
+				"Content-Type":                "application/json; charset=utf-8",
+				"X-Ms-Request-Id":             "11111111-1111-1111-1111-111111111111",
+				"X-Ms-Correlation-Request-Id": "22222222-2222-2222-2222-222222222222",
+			},
+			body:          `{"error":{"code":"ResourceGroupNotFound","message":"Resource group 'example' could not be found."}}`,
+			code:          "ResourceGroupNotFound",
+			message:       "Resource group 'example' could not be found.",
+			requestId:     "11111111-1111-1111-1111-111111111111",
+			correlationId: "22222222-2222-2222-2222-222222222222",
+			notFound:      true,
+		},
+		{
+			name:       "Resource Manager with Details",
+			statusCode: http.StatusBadRequest,
+			headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			body:    `{"error":{"code":"InvalidTemplateDeployment","message":"The template deployment failed.","target":"properties","details":[{"code":"QuotaExceeded","message":"Operation could not be completed as it results in exceeding approved quota.","details":[{"code":"Inner","message":"inner"}]}]}}`,
+			code:    "InvalidTemplateDeployment",
+			message: "The template deployment failed.",
+			target:  "properties",
+			details: 1,
+		},
+		{
+			name:       "Resource Manager Top Level",
+			statusCode: http.StatusForbidden,
+			headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			body:          `{"code":"AuthorizationFailed","message":"The client does not have authorization to perform action."}`,
+			code:          "AuthorizationFailed",
+			message:       "The client does not have authorization to perform action.",
+			authorization: true,
+		},
+		{
+			name:       "Microsoft Graph",
+			statusCode: http.StatusBadRequest,
+			headers: map[string]string{
+				"Content-Type":      "application/json",
+				"Request-Id":        "33333333-3333-3333-3333-333333333333",
+				"Client-Request-Id": "44444444-4444-4444-4444-444444444444",
+			},
+			body:          `{"error":{"code":"Request_BadRequest","message":"Another object with the same value for property userPrincipalName already exists.","details":[{"code":"ObjectConflict","target":"userPrincipalName"}]}}`,
+			code:          "Request_BadRequest",
+			message:       "Another object with the same value for property userPrincipalName already exists.",
+			details:       1,
+			requestId:     "33333333-3333-3333-3333-333333333333",
+			correlationId: "44444444-4444-4444-4444-444444444444",
+		},
+		{
+			name:       "Microsoft Graph Not Found",
+			statusCode: http.StatusBadRequest,
+			headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			body:     `{"error":{"code":"Request_BadRequest","message":"Resource '00000000-0000-0000-0000-000000000000' does not exist or one of its queried reference-property objects are not present."}}`,
+			code:     "Request_BadRequest",
+			message:  "Resource '00000000-0000-0000-0000-000000000000' does not exist or one of its queried reference-property objects are not present.",
+			notFound: true,
+		},
+		{
+			name:       "Storage",
+			statusCode: http.StatusConflict,
+			headers: map[string]string{
+				"Content-Type":    "application/xml",
+				"X-Ms-Request-Id": "55555555-5555-5555-5555-555555555555",
+			},
+			body:      "\xef\xbb\xbf<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>ContainerAlreadyExists</Code><Message>The specified container already exists.\nRequestId:55555555-5555-5555-5555-555555555555</Message></Error>",
+			code:      "ContainerAlreadyExists",
+			message:   "The specified container already exists.\nRequestId:55555555-5555-5555-5555-555555555555",
+			requestId: "55555555-5555-5555-5555-555555555555",
+			conflict:  true,
+		},
+		{
+			name:       "Storage Without Body",
+			statusCode: http.StatusServiceUnavailable,
+			headers: map[string]string{
+				"X-Ms-Error-Code": "ServerBusy",
+			},
+			code:      "ServerBusy",
+			throttled: true,
+		},
+		{
+			name:       "Throttled",
+			statusCode: http.StatusTooManyRequests,
+			body:       "slow down",
+			throttled:  true,
+		},
+	}
+
+	for _, v := range testCases {
+		t.Run(v.name, func(t *testing.T) {
+			resp := &Response{
+				Response: &http.Response{
+					StatusCode: v.statusCode,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader(v.body)),
+				},
+			}
+			for k, h := range v.headers {
+				resp.Header.Set(k, h)
+			}
+			resp.OData, _ = odata.FromResponse(resp.Response)
+
+			e, err := newResponseError(resp)
+			if err != nil {
+				t.Fatalf("building error: %+v", err)
+			}
+			if e.StatusCode != v.statusCode || e.Code != v.code || e.Message != v.message || e.Target != v.target || len(e.Details) != v.details {
+				t.Fatalf("unexpected error: %#v", e)
+			}
+			if e.RequestId != v.requestId || e.CorrelationId != v.correlationId {
+				t.Fatalf("expected request ID %q and correlation ID %q but got %q and %q", v.requestId, v.correlationId, e.RequestId, e.CorrelationId)
+			}
+
+			wrapped := fmt.Errorf("retrieving thing: %w", e)
+			if IsNotFound(wrapped) != v.notFound {
+				t.Fatalf("expected IsNotFound to return %t", v.notFound)
+			}
+			if IsConflict(wrapped) != v.conflict {
+				t.Fatalf("expected IsConflict to return %t", v.conflict)
+			}
+			if IsThrottled(wrapped) != v.throttled {
+				t.Fatalf("expected IsThrottled to return %t", v.throttled)
+			}
+			if IsAuthorizationFailed(wrapped) != v.authorization {
+				t.Fatalf("expected IsAuthorizationFailed to return %t", v.authorization)
+			}
+
+			// the body should still be readable by the caller
+			body, _ := io.ReadAll(resp.Body)
+			if !strings.Contains(v.body, string(body)) {
+				t.Fatalf("expected the response body to be readable after parsing the error")
+			}
+		})
+	}
+}
+
+func TestExecuteReturnsResponseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"ResourceNotFound","message":"The Resource was not found."}}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c := &testClient{
+		Client: NewClient(server.URL, "example", "2020-01-01"),
+	}
+	req, err := c.NewRequest(ctx, RequestOptions{
+		ContentType:         "application/json",
+		ExpectedStatusCodes: []int{http.StatusOK},
+		HttpMethod:          http.MethodGet,
+		Path:                "/things/first",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+
+	_, err = req.Execute(ctx)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("expected a *ResponseError but got %T: %+v", err, err)
+	}
+	if respErr.Code != "ResourceNotFound" || !IsNotFound(err) {
+		t.Fatalf("unexpected error: %#v", respErr)
+	}
+	if expected := "unexpected status 404 with error: ResourceNotFound: The Resource was not found."; err.Error() != expected {
+		t.Fatalf("expected the error message %q but got %q", expected, err.Error())
+	}
+}
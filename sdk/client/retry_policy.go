@@ -0,0 +1,181 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+const (
+	defaultRetryMaxAttempts = 5
+	defaultRetryMinBackoff  = 1 * time.Second
+	defaultRetryMaxBackoff  = 30 * time.Second
+)
+
+// RetryPolicy configures how failed requests are retried. A RetryPolicy can be set on a Client, and overridden for
+// an individual Request. Any fields left as their zero value will use the defaults from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request will be sent, including the first attempt
+	MaxAttempts int
+
+	// MinBackoff is the minimum duration to wait between attempts, when the API does not specify one
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum duration to wait between attempts, when the API does not specify one
+	MaxBackoff time.Duration
+
+	// Jitter is the proportion (between 0 and 1) of each computed backoff which is randomized, to avoid
+	// synchronized retries from multiple clients. Durations specified by the API are not randomized.
+	Jitter float64
+
+	// RetryableStatusCodes is a list of HTTP status codes which should be retried to work around eventual
+	// consistency issues. These are not retried when DisableRetries is set on the Client. Note that rate
+	// limiting (429) and server errors are always retried.
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy returns the RetryPolicy used when none is specified for a Client or Request
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultRetryMaxAttempts,
+		MinBackoff:  defaultRetryMinBackoff,
+		MaxBackoff:  defaultRetryMaxBackoff,
+		RetryableStatusCodes: []int{
+			http.StatusFailedDependency,
+
+			// Some APIs don't return a response in time
+			http.StatusRequestTimeout,
+		},
+	}
+}
+
+// WithDefaults returns a copy of the RetryPolicy, where any unset fields are populated from DefaultRetryPolicy
+func (p RetryPolicy) WithDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = d.MinBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.MaxBackoff < p.MinBackoff {
+		p.MaxBackoff = p.MinBackoff
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	if p.RetryableStatusCodes == nil {
+		p.RetryableStatusCodes = d.RetryableStatusCodes
+	}
+	return p
+}
+
+// RetryFunc returns a RequestRetryFunc which retries responses having any of the RetryableStatusCodes, so that the
+// policy can be composed with other RequestRetryFuncs using RequestRetryAny or RequestRetryAll.
+func (p RetryPolicy) RetryFunc() RequestRetryFunc {
+	statusCodes := p.WithDefaults().RetryableStatusCodes
+	return func(resp *http.Response, _ *odata.OData) (bool, error) {
+		return resp != nil && containsStatusCode(statusCodes, resp.StatusCode), nil
+	}
+}
+
+// Backoff determines how long to wait before the next attempt. When the API returns a Retry-After header (either in
+// seconds or as an HTTP date), this is honoured. When the API indicates that a rate limit has been exhausted via an
+// `x-ms-ratelimit-remaining-*` header, the maximum backoff is used. Otherwise, an exponential backoff is computed.
+func (p RetryPolicy) Backoff(attemptNum int, resp *http.Response) time.Duration {
+	p = p.WithDefaults()
+
+	if resp != nil {
+		// Always look for Retry-After header
+		if sleep, ok := RetryAfter(resp); ok {
+			return sleep
+		}
+
+		if rateLimitExhausted(resp) {
+			return p.MaxBackoff
+		}
+	}
+
+	// Default exponential backoff
+	mult := math.Pow(2, float64(attemptNum)) * float64(p.MinBackoff)
+	sleep := time.Duration(mult)
+	if float64(sleep) != mult || sleep > p.MaxBackoff {
+		sleep = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		jitter := time.Duration(float64(sleep) * p.Jitter * rand.Float64())
+		sleep = sleep - time.Duration(float64(sleep)*p.Jitter/2) + jitter
+	}
+
+	// Jitter must not extend the backoff beyond the maximum
+	if sleep > p.MaxBackoff {
+		sleep = p.MaxBackoff
+	}
+
+	return sleep
+}
+
+// RetryAfter parses the Retry-After header from a response, which can be specified either as a number of seconds
+// or as an HTTP date. Returns false when the header is not present or could not be parsed.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+
+	if sleep, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if sleep < 0 {
+			return 0, false
+		}
+		return time.Second * time.Duration(sleep), true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		sleep := time.Until(t)
+		if sleep < 0 {
+			sleep = 0
+		}
+		return sleep, true
+	}
+
+	return 0, false
+}
+
+// rateLimitExhausted determines whether any `x-ms-ratelimit-remaining-*` header indicates that no further requests
+// are permitted within the current rate limiting window.
+func rateLimitExhausted(resp *http.Response) bool {
+	for k, v := range resp.Header {
+		if !strings.HasPrefix(strings.ToLower(k), "x-ms-ratelimit-remaining-") || len(v) == 0 {
+			continue
+		}
+		// values are either a plain number, or a list of `name;number` pairs (e.g. for `x-ms-ratelimit-remaining-resource`)
+		for _, entry := range strings.Split(v[0], ",") {
+			if i := strings.LastIndex(entry, ";"); i >= 0 {
+				entry = entry[i+1:]
+			}
+			if remaining, err := strconv.Atoi(strings.TrimSpace(entry)); err == nil && remaining <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+func TestRetryAfter(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{
+			header: "",
+			ok:     false,
+		},
+		{
+			header:   "5",
+			expected: 5 * time.Second,
+			ok:       true,
+		},
+		{
+			header: "-1",
+			ok:     false,
+		},
+		{
+			header:   time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat),
+			expected: 0,
+			ok:       true,
+		},
+		{
+			header: "soon",
+			ok:     false,
+		},
+	}
+	for _, v := range testCases {
+		resp := &http.Response{
+			Header: http.Header{},
+		}
+		if v.header != "" {
+			resp.Header.Set("Retry-After", v.header)
+		}
+		actual, ok := RetryAfter(resp)
+		if ok != v.ok {
+			t.Fatalf("expected ok to be %t for %q but got %t", v.ok, v.header, ok)
+		}
+		if actual != v.expected {
+			t.Fatalf("expected %s for %q but got %s", v.expected, v.header, actual)
+		}
+	}
+
+	resp := &http.Response{
+		Header: http.Header{
+			"Retry-After": []string{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+		},
+	}
+	if actual, ok := RetryAfter(resp); !ok || actual < 58*time.Minute || actual > time.Hour {
+		t.Fatalf("expected an HTTP date Retry-After to be parsed as roughly one hour, got %s", actual)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MinBackoff: 1 * time.Second,
+		MaxBackoff: 10 * time.Second,
+	}
+
+	if actual := policy.Backoff(0, nil); actual != 1*time.Second {
+		t.Fatalf("expected a backoff of 1s for the first attempt but got %s", actual)
+	}
+	if actual := policy.Backoff(2, nil); actual != 4*time.Second {
+		t.Fatalf("expected a backoff of 4s for the third attempt but got %s", actual)
+	}
+	if actual := policy.Backoff(10, nil); actual != 10*time.Second {
+		t.Fatalf("expected the backoff to be capped at 10s but got %s", actual)
+	}
+
+	rateLimited := &http.Response{
+		Header: http.Header{
+			"X-Ms-Ratelimit-Remaining-Resource": []string{"Microsoft.Compute/HighCostGet3Min;0,Microsoft.Compute/HighCostGet30Min;120"},
+		},
+	}
+	if actual := policy.Backoff(0, rateLimited); actual != 10*time.Second {
+		t.Fatalf("expected the maximum backoff when the rate limit is exhausted but got %s", actual)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if actual := policy.Backoff(2, nil); actual < 3*time.Second || actual > 5*time.Second {
+			t.Fatalf("expected a jittered backoff between 3s and 5s but got %s", actual)
+		}
+		if actual := policy.Backoff(10, nil); actual < 7500*time.Millisecond || actual > 10*time.Second {
+			t.Fatalf("expected a jittered backoff between 7.5s and 10s when capped but got %s", actual)
+		}
+	}
+}
+
+func TestRetryPolicyRetryFunc(t *testing.T) {
+	policy := RetryPolicy{
+		RetryableStatusCodes: []int{http.StatusConflict},
+	}
+	retryOnNotFound := func(resp *http.Response, _ *odata.OData) (bool, error) {
+		return resp.StatusCode == http.StatusNotFound, nil
+	}
+
+	testCases := []struct {
+		statusCode int
+		any        bool
+		all        bool
+	}{
+		{
+			statusCode: http.StatusConflict,
+			any:        true,
+			all:        false,
+		},
+		{
+			statusCode: http.StatusNotFound,
+			any:        true,
+			all:        false,
+		},
+		{
+			statusCode: http.StatusBadRequest,
+			any:        false,
+			all:        false,
+		},
+	}
+	for _, v := range testCases {
+		resp := &http.Response{
+			StatusCode: v.statusCode,
+		}
+		if retry, _ := RequestRetryAny(policy.RetryFunc(), retryOnNotFound)(resp, nil); retry != v.any {
+			t.Fatalf("expected RequestRetryAny to return %t for status %d but got %t", v.any, v.statusCode, retry)
+		}
+		if retry, _ := RequestRetryAll(policy.RetryFunc(), retryOnNotFound)(resp, nil); retry != v.all {
+			t.Fatalf("expected RequestRetryAll to return %t for status %d but got %t", v.all, v.statusCode, retry)
+		}
+	}
+}
+
+func TestRetryPolicyMaxAttempts(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusRequestTimeout)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c := &testClient{
+		Client: NewClient(server.URL, "example", "2020-01-01"),
+	}
+	c.RetryPolicy = &RetryPolicy{
+		MaxAttempts: 5,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	}
+
+	req, err := c.NewRequest(ctx, RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodGet,
+		Path:       "/things",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+
+	// the request policy should take precedence over the client policy
+	req.RetryPolicy = &RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	}
+
+	if _, err = req.Execute(ctx); err == nil {
+		t.Fatalf("expected an error but didn't get one")
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts but got %d", attempts)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package environments
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ApiForHost returns the Api in this Environment which serves the specified host, such as `management.azure.com` or
+// `myvault.vault.azure.net`. An Api whose endpoint has the same host is preferred, otherwise the Api with the longest
+// matching domain suffix is returned. Only Apis for which a token can be obtained are considered.
+func (e *Environment) ApiForHost(host string) (Api, bool) {
+	if e == nil {
+		return nil, false
+	}
+
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return nil, false
+	}
+
+	var match Api
+	var matchLength int
+	for _, api := range e.apis() {
+		if !api.Available() {
+			continue
+		}
+
+		if endpoint, ok := api.Endpoint(); ok && endpoint != nil {
+			if u, err := url.Parse(*endpoint); err == nil && strings.EqualFold(u.Hostname(), host) {
+				return api, true
+			}
+		}
+
+		if suffix, ok := api.DomainSuffix(); ok && suffix != nil && len(*suffix) > matchLength {
+			s := strings.ToLower(strings.TrimPrefix(*suffix, "."))
+			if host == s || strings.HasSuffix(host, "."+s) {
+				match = api
+				matchLength = len(*suffix)
+			}
+		}
+	}
+
+	return match, match != nil
+}
+
+// apis returns all the Apis defined in this Environment, which are determined using reflection so that newly added
+// Apis are always included
+func (e *Environment) apis() []Api {
+	apiType := reflect.TypeOf((*Api)(nil)).Elem()
+	v := reflect.ValueOf(e).Elem()
+
+	apis := make([]Api, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Type() != apiType || field.IsNil() {
+			continue
+		}
+		apis = append(apis, field.Interface().(Api))
+	}
+	return apis
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package environments
+
+import (
+	"testing"
+)
+
+func TestApiForHost(t *testing.T) {
+	env := AzurePublic()
+	testData := []struct {
+		host     string
+		expected string
+	}{
+		{
+			host:     "management.azure.com",
+			expected: "ResourceManager",
+		},
+		{
+			host:     "MANAGEMENT.azure.com:443",
+			expected: "ResourceManager",
+		},
+		{
+			host:     "graph.microsoft.com",
+			expected: "MicrosoftGraph",
+		},
+		{
+			host:     "myvault.vault.azure.net",
+			expected: "AzureKeyVault",
+		},
+		{
+			host:     "myhsm.managedhsm.azure.net",
+			expected: "ManagedHSM",
+		},
+		{
+			host:     "myaccount.blob.core.windows.net",
+			expected: "AzureStorage",
+		},
+		{
+			host:     "myserver.database.windows.net",
+			expected: "AzureSqlDatabase",
+		},
+		{
+			host:     "mynamespace.servicebus.windows.net",
+			expected: "ServiceBus",
+		},
+		{
+			// ApiManagement has a domain suffix but no resource identifier, so a token cannot be obtained
+			host:     "myapim.azure-api.net",
+			expected: "",
+		},
+		{
+			host:     "example.com",
+			expected: "",
+		},
+		{
+			host:     "vault.azure.net.example.com",
+			expected: "",
+		},
+	}
+
+	for _, v := range testData {
+		api, ok := env.ApiForHost(v.host)
+		if v.expected == "" {
+			if ok {
+				t.Fatalf("expected no Api for %q, got %q", v.host, api.Name())
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("expected the Api %q for %q, but no Api was found", v.expected, v.host)
+		}
+		if api.Name() != v.expected {
+			t.Fatalf("expected the Api %q for %q, got %q", v.expected, v.host, api.Name())
+		}
+	}
+}
@@ -7,11 +7,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os/exec"
 	"regexp"
 	"strings"
 
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 	"github.com/hashicorp/go-version"
 )
 
@@ -120,7 +120,7 @@ func JSONUnmarshalAzCmd(cacheable bool, i interface{}, arg ...string) error {
 	}
 
 	if result == nil {
-		log.Printf("[DEBUG] az-cli invocation: az %s", argstring)
+		logging.Default().Debug("az-cli invocation", "command", "az "+argstring)
 
 		cmd := exec.Command("az", arg...)
 		cmd.Stderr = &stderr
@@ -10,11 +10,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"runtime"
 	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
 )
 
 // NOTE: this Client cannot use the base client since it'd cause a circular reference
@@ -37,7 +38,7 @@ func NewClientWithEndpoint(endpoint string) *Client {
 func (c *Client) GetMetaData(ctx context.Context, name string) (*MetaData, error) {
 	metadata, err := c.getMetaDataFrom2022API(ctx, name)
 	if err != nil {
-		log.Printf("[DEBUG] Falling back to ARM Metadata version 2019-05-01 for %s", c.endpoint)
+		logging.Default().Debug("falling back to ARM Metadata version 2019-05-01", "endpoint", c.endpoint)
 		metadata, err = c.getMetaDataFrom2019API(ctx, name)
 		if err != nil {
 			return nil, fmt.Errorf("retrieving metadata from the 2022-09-01 and 2019-05-01 APIs: %+v", err)
@@ -124,7 +125,7 @@ func (c *Client) getMetaDataFrom2022API(ctx context.Context, name string) (*meta
 
 	var model *metaDataResponse
 	if err := json.Unmarshal(respBody, &model); err != nil {
-		log.Printf("[DEBUG] Unrecognised metadata response for %s: %s", uri, respBody)
+		logging.Default().Debug("unrecognised metadata response", "uri", uri, "body", string(respBody))
 		return nil, fmt.Errorf("unmarshaling response: %+v", err)
 	}
 
@@ -177,7 +178,7 @@ func (c *Client) getMetaDataFrom2019API(ctx context.Context, name string) (*meta
 
 	var model *[]metaDataResponse
 	if err := json.Unmarshal(respBody, &model); err != nil {
-		log.Printf("[DEBUG] Unrecognised metadata response for %s: %s", uri, respBody)
+		logging.Default().Debug("unrecognised metadata response", "uri", uri, "body", string(respBody))
 		return nil, fmt.Errorf("unmarshaling response: %+v", err)
 	}
 
@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Logger is a structured, leveled logger used throughout the SDK. Messages are accompanied by alternating keys
+// and values. This interface is compatible with the LeveledLogger interface from hashicorp/go-retryablehttp.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// Level is the severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "OFF"
+}
+
+var (
+	defaultLogger Logger = NewRedactingLogger(NewStandardLogger(log.Default(), LevelDebug))
+	defaultMutex  sync.RWMutex
+)
+
+// Default returns the Logger used by the SDK when no other Logger has been configured. Unless changed using
+// SetDefault, messages are written to the standard library's default logger.
+func Default() Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the Logger used by the SDK when no other Logger has been configured. Messages are always
+// redacted before being passed to the provided Logger. Specify nil to discard log messages.
+func SetDefault(logger Logger) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	if logger == nil {
+		logger = Discard()
+	}
+	defaultLogger = NewRedactingLogger(logger)
+}
+
+var _ Logger = &standardLogger{}
+
+type standardLogger struct {
+	logger *log.Logger
+	level  Level
+}
+
+// NewStandardLogger returns a Logger which writes messages at or above the specified level to the provided
+// *log.Logger, in the format `[LEVEL] message: key=value`.
+func NewStandardLogger(logger *log.Logger, level Level) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &standardLogger{
+		logger: logger,
+		level:  level,
+	}
+}
+
+func (l *standardLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(LevelDebug, msg, keysAndValues...)
+}
+
+func (l *standardLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(LevelInfo, msg, keysAndValues...)
+}
+
+func (l *standardLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(LevelWarn, msg, keysAndValues...)
+}
+
+func (l *standardLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(LevelError, msg, keysAndValues...)
+}
+
+func (l *standardLogger) log(level Level, msg string, keysAndValues ...interface{}) {
+	if level < l.level {
+		return
+	}
+
+	fields := make([]string, 0)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			fields = append(fields, fmt.Sprintf("%v=%v", keysAndValues[i], keysAndValues[i+1]))
+		} else {
+			fields = append(fields, fmt.Sprintf("%v", keysAndValues[i]))
+		}
+	}
+
+	if len(fields) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(fields, " "))
+	}
+	l.logger.Printf("[%s] %s", level, msg)
+}
+
+var _ Logger = discardLogger{}
+
+type discardLogger struct{}
+
+// Discard returns a Logger which discards all messages
+func Discard() Logger {
+	return discardLogger{}
+}
+
+func (discardLogger) Debug(string, ...interface{}) {}
+func (discardLogger) Info(string, ...interface{})  {}
+func (discardLogger) Warn(string, ...interface{})  {}
+func (discardLogger) Error(string, ...interface{}) {}
@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package logging
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Redacted is substituted in place of any sensitive values removed from log messages
+const Redacted = "REDACTED"
+
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Authorization headers, e.g. `Authorization: Bearer eyJ0eX...` or `X-Ms-Authorization-Auxiliary: Bearer a, Bearer b`
+	{
+		pattern:     regexp.MustCompile(`(?im)((?:x-ms-authorization-auxiliary|authorization)["']?\s*[:=]\s*["']?)[^"'\r\n]+`),
+		replacement: "${1}" + Redacted,
+	},
+
+	// Bearer tokens appearing anywhere else
+	{
+		pattern:     regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		replacement: "${1}" + Redacted,
+	},
+
+	// JSON-encoded secrets, e.g. `"access_token": "eyJ0eX..."`
+	{
+		pattern:     regexp.MustCompile(`(?i)("(?:access_token|refresh_token|id_token|client_secret|client_assertion|password)"\s*:\s*")[^"]*(")`),
+		replacement: "${1}" + Redacted + "${2}",
+	},
+
+	// URL-encoded secrets in query strings and form bodies, including SAS signatures
+	{
+		pattern:     regexp.MustCompile(`(?i)([?&\s]|^)((?:access_token|refresh_token|id_token|client_secret|client_assertion|assertion|password|sig)=)[^&\s"']*`),
+		replacement: "${1}${2}" + Redacted,
+	},
+}
+
+// Redact removes sensitive values, such as access tokens, client secrets and SAS signatures, from the provided string
+func Redact(in string) string {
+	for _, r := range redactions {
+		in = r.pattern.ReplaceAllString(in, r.replacement)
+	}
+	return in
+}
+
+var _ Logger = &redactingLogger{}
+
+type redactingLogger struct {
+	logger Logger
+}
+
+// NewRedactingLogger returns a Logger which removes sensitive values from messages and from string, error and
+// fmt.Stringer values, before passing them to the provided Logger
+func NewRedactingLogger(logger Logger) Logger {
+	if logger == nil {
+		return Discard()
+	}
+	if _, ok := logger.(*redactingLogger); ok {
+		return logger
+	}
+	return &redactingLogger{
+		logger: logger,
+	}
+}
+
+func (l *redactingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Debug(Redact(msg), redactValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Info(Redact(msg), redactValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.logger.Warn(Redact(msg), redactValues(keysAndValues)...)
+}
+
+func (l *redactingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Error(Redact(msg), redactValues(keysAndValues)...)
+}
+
+func redactValues(keysAndValues []interface{}) []interface{} {
+	out := make([]interface{}, len(keysAndValues))
+	for i, v := range keysAndValues {
+		switch t := v.(type) {
+		case string:
+			out[i] = Redact(t)
+		case error:
+			out[i] = Redact(t.Error())
+		case fmt.Stringer:
+			out[i] = Redact(t.String())
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,417 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package odata
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+type filterDialect int
+
+const (
+	filterDialectGraph filterDialect = iota
+	filterDialectResourceManager
+)
+
+// filterPropertyRegex matches a property path, e.g. `displayName`, `properties/provisioningState`,
+// `x/skuId` within a lambda, `microsoft.graph.user/department` or `owners/$count`
+var filterPropertyRegex = regexp.MustCompile(`^(\$count|[A-Za-z_@][A-Za-z0-9_.@]*)(/(\$count|[A-Za-z_@][A-Za-z0-9_.@]*))*$`)
+
+// filterVariableRegex matches the name of a lambda variable
+var filterVariableRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// filterEnumTypeRegex matches a namespace-qualified enum type, e.g. `microsoft.graph.riskLevel`
+var filterEnumTypeRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Filter is an expression for the `$filter` query parameter, built using functions such as Eq, And and StartsWith so
+// that property names are validated and values are correctly quoted and escaped. The zero value is an empty filter,
+// which is omitted when combined using And or Or.
+//
+// Use Graph to render the expression for Microsoft Graph, e.g. for Query.Filter, or ResourceManager to render it for
+// Resource Manager APIs, e.g. for the Filter of generated ListOperationOptions.
+type Filter struct {
+	fn func(d filterDialect) (string, error)
+
+	// operator is the logical operator combining multiple expressions, used to determine whether parentheses are needed
+	operator string
+}
+
+// Graph returns the filter expression for use with Microsoft Graph
+func (f Filter) Graph() (string, error) {
+	return f.render(filterDialectGraph)
+}
+
+// ResourceManager returns the filter expression for use with Resource Manager, where GUID, date and enum values are
+// quoted strings, `in` is expanded to a series of `eq` comparisons and lambda operators are not supported
+func (f Filter) ResourceManager() (string, error) {
+	return f.render(filterDialectResourceManager)
+}
+
+// IsEmpty determines whether the filter contains no expression
+func (f Filter) IsEmpty() bool {
+	return f.fn == nil
+}
+
+// render returns the filter expression for the dialect
+func (f Filter) render(d filterDialect) (string, error) {
+	if f.IsEmpty() {
+		return "", nil
+	}
+	return f.fn(d)
+}
+
+// Guid is a GUID value, which is unquoted for Microsoft Graph (Edm.Guid properties) and quoted for Resource Manager
+type Guid string
+
+// DateTime is a timestamp value (Edm.DateTimeOffset), which is unquoted for Microsoft Graph and quoted for Resource
+// Manager. Note that time.Time values are also rendered as a DateTime.
+type DateTime time.Time
+
+// Date is a date value (Edm.Date), which is unquoted for Microsoft Graph and quoted for Resource Manager
+type Date time.Time
+
+// EnumValue is a member of an enum type. When Type is specified, e.g. `microsoft.graph.riskLevel`, a qualified enum
+// literal is rendered for Microsoft Graph, otherwise the member is quoted like a string.
+type EnumValue struct {
+	Type   string
+	Member string
+}
+
+// Enum returns an EnumValue for the member, optionally qualified with the namespace-qualified enum type
+func Enum(enumType, member string) EnumValue {
+	return EnumValue{
+		Type:   enumType,
+		Member: member,
+	}
+}
+
+// Eq returns a filter matching items where the property is equal to the value. Supported values are strings,
+// booleans, numbers, nil, time.Time, Guid, DateTime, Date and EnumValue.
+func Eq(property string, value interface{}) Filter {
+	return comparisonFilter(property, "eq", value)
+}
+
+// Ne returns a filter matching items where the property is not equal to the value
+func Ne(property string, value interface{}) Filter {
+	return comparisonFilter(property, "ne", value)
+}
+
+// Gt returns a filter matching items where the property is greater than the value
+func Gt(property string, value interface{}) Filter {
+	return comparisonFilter(property, "gt", value)
+}
+
+// Ge returns a filter matching items where the property is greater than or equal to the value
+func Ge(property string, value interface{}) Filter {
+	return comparisonFilter(property, "ge", value)
+}
+
+// Lt returns a filter matching items where the property is less than the value
+func Lt(property string, value interface{}) Filter {
+	return comparisonFilter(property, "lt", value)
+}
+
+// Le returns a filter matching items where the property is less than or equal to the value
+func Le(property string, value interface{}) Filter {
+	return comparisonFilter(property, "le", value)
+}
+
+func comparisonFilter(property, operator string, value interface{}) Filter {
+	return Filter{
+		fn: func(d filterDialect) (string, error) {
+			p, err := filterProperty(property)
+			if err != nil {
+				return "", err
+			}
+			v, err := filterValue(value, d)
+			if err != nil {
+				return "", fmt.Errorf("value for %q: %v", property, err)
+			}
+			return fmt.Sprintf("%s %s %s", p, operator, v), nil
+		},
+	}
+}
+
+// StartsWith returns a filter matching items where the string property starts with the value
+func StartsWith(property, value string) Filter {
+	return functionFilter("startswith", property, value)
+}
+
+// EndsWith returns a filter matching items where the string property ends with the value. Note that Microsoft Graph
+// only supports this as an advanced query.
+func EndsWith(property, value string) Filter {
+	return functionFilter("endswith", property, value)
+}
+
+// Contains returns a filter matching items where the string property contains the value
+func Contains(property, value string) Filter {
+	return functionFilter("contains", property, value)
+}
+
+func functionFilter(function, property, value string) Filter {
+	return Filter{
+		fn: func(d filterDialect) (string, error) {
+			p, err := filterProperty(property)
+			if err != nil {
+				return "", err
+			}
+			v, err := filterValue(value, d)
+			if err != nil {
+				return "", fmt.Errorf("value for %q: %v", property, err)
+			}
+			return fmt.Sprintf("%s(%s,%s)", function, p, v), nil
+		},
+	}
+}
+
+// In returns a filter matching items where the property is equal to any of the values. For Resource Manager, this is
+// rendered as a parenthesized series of `eq` comparisons combined using `or`.
+func In(property string, values ...interface{}) Filter {
+	return Filter{
+		fn: func(d filterDialect) (string, error) {
+			if len(values) == 0 {
+				return "", fmt.Errorf("at least one value must be specified for `in` with %q", property)
+			}
+
+			if d == filterDialectResourceManager {
+				filters := make([]Filter, 0, len(values))
+				for _, v := range values {
+					filters = append(filters, Eq(property, v))
+				}
+				v, err := Or(filters...).render(d)
+				if err != nil || len(filters) == 1 {
+					return v, err
+				}
+				return fmt.Sprintf("(%s)", v), nil
+			}
+
+			p, err := filterProperty(property)
+			if err != nil {
+				return "", err
+			}
+			rendered := make([]string, 0, len(values))
+			for _, value := range values {
+				v, err := filterValue(value, d)
+				if err != nil {
+					return "", fmt.Errorf("value for %q: %v", property, err)
+				}
+				rendered = append(rendered, v)
+			}
+			return fmt.Sprintf("%s in (%s)", p, strings.Join(rendered, ",")), nil
+		},
+	}
+}
+
+// And returns a filter matching items which match all the filters. Empty filters are omitted.
+func And(filters ...Filter) Filter {
+	return logicalFilter("and", filters)
+}
+
+// Or returns a filter matching items which match any of the filters. Empty filters are omitted.
+func Or(filters ...Filter) Filter {
+	return logicalFilter("or", filters)
+}
+
+func logicalFilter(operator string, filters []Filter) Filter {
+	nonEmpty := make([]Filter, 0, len(filters))
+	for _, f := range filters {
+		if !f.IsEmpty() {
+			nonEmpty = append(nonEmpty, f)
+		}
+	}
+	switch len(nonEmpty) {
+	case 0:
+		return Filter{}
+	case 1:
+		return nonEmpty[0]
+	}
+
+	return Filter{
+		operator: operator,
+		fn: func(d filterDialect) (string, error) {
+			rendered := make([]string, 0, len(nonEmpty))
+			for _, f := range nonEmpty {
+				v, err := f.render(d)
+				if err != nil {
+					return "", err
+				}
+				if f.operator != "" && f.operator != operator {
+					v = fmt.Sprintf("(%s)", v)
+				}
+				rendered = append(rendered, v)
+			}
+			return strings.Join(rendered, fmt.Sprintf(" %s ", operator)), nil
+		},
+	}
+}
+
+// Not returns a filter matching items which do not match the filter
+func Not(filter Filter) Filter {
+	return Filter{
+		fn: func(d filterDialect) (string, error) {
+			if filter.IsEmpty() {
+				return "", fmt.Errorf("`not` requires a filter")
+			}
+			v, err := filter.render(d)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("not(%s)", v), nil
+		},
+	}
+}
+
+// Any returns a filter matching items where any member of the collection property matches the filter, in which the
+// member is referenced using the variable, e.g. Any("assignedLicenses", "x", Eq("x/skuId", Guid(skuId))). When the
+// filter is empty, items where the collection is not empty are matched. Not supported by Resource Manager.
+func Any(collection, variable string, filter Filter) Filter {
+	return lambdaFilter("any", collection, variable, filter)
+}
+
+// All returns a filter matching items where all members of the collection property match the filter, in which the
+// member is referenced using the variable. Not supported by Resource Manager.
+func All(collection, variable string, filter Filter) Filter {
+	return lambdaFilter("all", collection, variable, filter)
+}
+
+func lambdaFilter(operator, collection, variable string, filter Filter) Filter {
+	return Filter{
+		fn: func(d filterDialect) (string, error) {
+			if d == filterDialectResourceManager {
+				return "", fmt.Errorf("the `%s` operator is not supported by Resource Manager", operator)
+			}
+			p, err := filterProperty(collection)
+			if err != nil {
+				return "", err
+			}
+			if filter.IsEmpty() {
+				if operator == "all" {
+					return "", fmt.Errorf("`all` requires a filter")
+				}
+				return fmt.Sprintf("%s/any()", p), nil
+			}
+			if !filterVariableRegex.MatchString(variable) {
+				return "", fmt.Errorf("invalid lambda variable %q", variable)
+			}
+			v, err := filter.render(d)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s/%s(%s:%s)", p, operator, variable, v), nil
+		},
+	}
+}
+
+// OrderByFields returns an expression for the `$orderby` query parameter, sorting by each field in turn, e.g. for the
+// OrderBy of generated ListOperationOptions. Property names are validated.
+func OrderByFields(fields ...OrderBy) (string, error) {
+	rendered := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if _, err := filterProperty(f.Field); err != nil {
+			return "", err
+		}
+		switch f.Direction {
+		case "", Ascending, Descending:
+		default:
+			return "", fmt.Errorf("invalid direction %q for %q", f.Direction, f.Field)
+		}
+		rendered = append(rendered, f.String())
+	}
+	return strings.Join(rendered, ","), nil
+}
+
+// filterProperty validates a property path, so that it cannot be used to inject arbitrary expressions
+func filterProperty(property string) (string, error) {
+	if !filterPropertyRegex.MatchString(property) {
+		return "", fmt.Errorf("invalid property %q", property)
+	}
+	return property, nil
+}
+
+// filterValue renders a literal value for the dialect
+func filterValue(value interface{}, d filterDialect) (string, error) {
+	quote := func(s string) string {
+		return fmt.Sprintf("'%s'", EscapeSingleQuote(s))
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quote(v), nil
+	case *string:
+		if v == nil {
+			return "null", nil
+		}
+		return quote(*v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+
+	case Guid:
+		if _, err := uuid.ParseUUID(string(v)); err != nil {
+			return "", fmt.Errorf("invalid GUID %q", string(v))
+		}
+		if d == filterDialectResourceManager {
+			return quote(string(v)), nil
+		}
+		return string(v), nil
+
+	case time.Time:
+		return filterValue(DateTime(v), d)
+	case DateTime:
+		s := time.Time(v).UTC().Format(time.RFC3339Nano)
+		if d == filterDialectResourceManager {
+			return quote(s), nil
+		}
+		return s, nil
+	case Date:
+		s := time.Time(v).Format(time.DateOnly)
+		if d == filterDialectResourceManager {
+			return quote(s), nil
+		}
+		return s, nil
+
+	case EnumValue:
+		if v.Type == "" || d == filterDialectResourceManager {
+			return quote(v.Member), nil
+		}
+		if !filterEnumTypeRegex.MatchString(v.Type) {
+			return "", fmt.Errorf("invalid enum type %q", v.Type)
+		}
+		return fmt.Sprintf("%s%s", v.Type, quote(v.Member)), nil
+	}
+
+	// support named types, such as the constants for enums in generated SDKs
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.String:
+		return quote(rv.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("unsupported type %T", value)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package odata_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+type testProvisioningState string
+
+func TestFilter(t *testing.T) {
+	timestamp := time.Date(2023, 6, 1, 12, 30, 0, 0, time.FixedZone("BST", 3600))
+
+	testCases := []struct {
+		filter          odata.Filter
+		graph           string
+		resourceManager string
+	}{
+		{
+			filter: odata.Filter{},
+		},
+		{
+			filter:          odata.And(odata.StartsWith("displayName", "x"), odata.Eq("accountEnabled", true)),
+			graph:           "startswith(displayName,'x') and accountEnabled eq true",
+			resourceManager: "startswith(displayName,'x') and accountEnabled eq true",
+		},
+		{
+			// single quotes are escaped, and other characters are left for URL encoding
+			filter:          odata.Or(odata.Eq("displayName", "O'Brien & Sons' #1"), odata.Eq("displayName", "x) or (true")),
+			graph:           "displayName eq 'O''Brien & Sons'' #1' or displayName eq 'x) or (true'",
+			resourceManager: "displayName eq 'O''Brien & Sons'' #1' or displayName eq 'x) or (true'",
+		},
+		{
+			filter:          odata.Or(odata.Ne("a", 1), odata.Gt("b", 2.5), odata.Ge("c", int64(3)), odata.Lt("d", nil), odata.Le("e", testProvisioningState("Succeeded"))),
+			graph:           "a ne 1 or b gt 2.5 or c ge 3 or d lt null or e le 'Succeeded'",
+			resourceManager: "a ne 1 or b gt 2.5 or c ge 3 or d lt null or e le 'Succeeded'",
+		},
+		{
+			filter:          odata.And(odata.Or(odata.EndsWith("mail", "@example.com"), odata.Contains("mail", "admin")), odata.Not(odata.Eq("userType", "Guest"))),
+			graph:           "(endswith(mail,'@example.com') or contains(mail,'admin')) and not(userType eq 'Guest')",
+			resourceManager: "(endswith(mail,'@example.com') or contains(mail,'admin')) and not(userType eq 'Guest')",
+		},
+		{
+			// empty filters are omitted, and a single remaining filter is not wrapped
+			filter:          odata.And(odata.Filter{}, odata.Or(odata.Eq("a", "b"), odata.Filter{})),
+			graph:           "a eq 'b'",
+			resourceManager: "a eq 'b'",
+		},
+		{
+			filter:          odata.Eq("appId", odata.Guid("00000000-0000-0000-0000-000000000000")),
+			graph:           "appId eq 00000000-0000-0000-0000-000000000000",
+			resourceManager: "appId eq '00000000-0000-0000-0000-000000000000'",
+		},
+		{
+			filter:          odata.And(odata.Ge("createdDateTime", timestamp), odata.Lt("createdDateTime", odata.DateTime(timestamp.Add(time.Millisecond))), odata.Eq("birthday", odata.Date(timestamp))),
+			graph:           "createdDateTime ge 2023-06-01T11:30:00Z and createdDateTime lt 2023-06-01T11:30:00.001Z and birthday eq 2023-06-01",
+			resourceManager: "createdDateTime ge '2023-06-01T11:30:00Z' and createdDateTime lt '2023-06-01T11:30:00.001Z' and birthday eq '2023-06-01'",
+		},
+		{
+			filter:          odata.Or(odata.Eq("riskLevel", odata.Enum("microsoft.graph.riskLevel", "high")), odata.Eq("status", odata.Enum("", "active"))),
+			graph:           "riskLevel eq microsoft.graph.riskLevel'high' or status eq 'active'",
+			resourceManager: "riskLevel eq 'high' or status eq 'active'",
+		},
+		{
+			filter:          odata.And(odata.In("location", "westeurope", "northeurope"), odata.Eq("type", "vm")),
+			graph:           "location in ('westeurope','northeurope') and type eq 'vm'",
+			resourceManager: "(location eq 'westeurope' or location eq 'northeurope') and type eq 'vm'",
+		},
+		{
+			filter:          odata.In("location", "westeurope"),
+			graph:           "location in ('westeurope')",
+			resourceManager: "location eq 'westeurope'",
+		},
+		{
+			filter: odata.And(odata.Any("assignedLicenses", "x", odata.Eq("x/skuId", odata.Guid("11111111-0000-0000-0000-000000000000"))), odata.All("proxyAddresses", "p", odata.StartsWith("p", "smtp:"))),
+			graph:  "assignedLicenses/any(x:x/skuId eq 11111111-0000-0000-0000-000000000000) and proxyAddresses/all(p:startswith(p,'smtp:'))",
+		},
+		{
+			filter: odata.And(odata.Any("owners", "", odata.Filter{}), odata.Eq("owners/$count", 1)),
+			graph:  "owners/any() and owners/$count eq 1",
+		},
+	}
+
+	for _, v := range testCases {
+		graph, err := v.filter.Graph()
+		if err != nil {
+			t.Fatalf("rendering filter for Graph: %v", err)
+		}
+		if graph != v.graph {
+			t.Fatalf("expected the Graph filter:\n%s\nbut got:\n%s", v.graph, graph)
+		}
+
+		resourceManager, err := v.filter.ResourceManager()
+		if v.resourceManager == "" && v.graph != "" {
+			if err == nil {
+				t.Fatalf("expected an error rendering %q for Resource Manager", v.graph)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("rendering filter for Resource Manager: %v", err)
+		}
+		if resourceManager != v.resourceManager {
+			t.Fatalf("expected the Resource Manager filter:\n%s\nbut got:\n%s", v.resourceManager, resourceManager)
+		}
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	testCases := []odata.Filter{
+		odata.Eq("displayName eq 'x' or true", "y"),
+		odata.StartsWith("displayName,'x') or startswith(mail", "y"),
+		odata.Eq("appId", odata.Guid("00000000-0000-0000-0000-000000000000' or appId ne '")),
+		odata.Eq("riskLevel", odata.Enum("microsoft.graph.riskLevel'x' or 1 eq 1 or x", "high")),
+		odata.Any("assignedLicenses", "x:x", odata.Eq("x/skuId", "y")),
+		odata.All("assignedLicenses", "x", odata.Filter{}),
+		odata.In("location"),
+		odata.Not(odata.Filter{}),
+		odata.Eq("tags", []string{"a"}),
+	}
+
+	for _, f := range testCases {
+		v, err := f.Graph()
+		if f.IsEmpty() {
+			t.Fatal("expected a non-empty filter")
+		}
+		if err == nil {
+			t.Fatalf("expected an error, but the filter rendered as %q", v)
+		}
+	}
+}
+
+func TestOrderByFields(t *testing.T) {
+	v, err := odata.OrderByFields(odata.OrderBy{Field: "displayName"}, odata.OrderBy{Field: "createdDateTime", Direction: odata.Descending})
+	if err != nil {
+		t.Fatalf("OrderByFields(): %v", err)
+	}
+	if expected := "displayName,createdDateTime desc"; v != expected {
+		t.Fatalf("expected %q, got %q", expected, v)
+	}
+
+	if _, err = odata.OrderByFields(odata.OrderBy{Field: "displayName desc,id"}); err == nil {
+		t.Fatal("expected an error for an invalid field")
+	}
+	if _, err = odata.OrderByFields(odata.OrderBy{Field: "displayName", Direction: "sideways"}); err == nil {
+		t.Fatal("expected an error for an invalid direction")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -50,8 +51,10 @@ type Query struct {
 	// OrderBy specify the sort order of the items returned
 	OrderBy OrderBy
 
-	// Search restricts the results of a request to match a search criterion
-	Search string // complicated
+	// Search restricts the results of a request to match a search criterion. A single search term is quoted
+	// automatically, whereas a search expression already containing quoted clauses (as built with SearchClause,
+	// SearchAnd and SearchOr) is sent as-is.
+	Search string
 
 	// Select returns a set of properties that are different than the default set for an individual resource or a collection of resources
 	Select []string
@@ -64,6 +67,11 @@ type Query struct {
 
 	// DeltaToken is used to query a delta endpoint
 	DeltaToken string
+
+	// DisableAdvancedQueryDetection prevents the ConsistencyLevel header and the `$count` query parameter from being
+	// set automatically for advanced queries, e.g. for collections other than Microsoft Graph directory objects, which
+	// do not support them. An explicitly specified ConsistencyLevel or Count is still honored.
+	DisableAdvancedQueryDetection bool
 }
 
 // Headers returns a http.Header map containing OData specific headers
@@ -81,6 +89,8 @@ func (q Query) Headers() http.Header {
 
 	if q.ConsistencyLevel != "" {
 		headers.Set("Consistencylevel", string(q.ConsistencyLevel))
+	} else if q.detectAdvancedQuery() {
+		headers.Set("Consistencylevel", string(ConsistencyLevelEventual))
 	}
 
 	return headers
@@ -103,8 +113,8 @@ func (q Query) AppendHeaders(header http.Header) http.Header {
 func (q Query) Values() url.Values {
 	p := url.Values{}
 
-	if q.Count {
-		p.Add("$count", fmt.Sprintf("%t", q.Count))
+	if q.Count || q.detectAdvancedQuery() {
+		p.Add("$count", "true")
 	}
 	if expand := q.Expand.String(); expand != "" {
 		p.Add("$expand", expand)
@@ -118,8 +128,8 @@ func (q Query) Values() url.Values {
 	if orderBy := q.OrderBy.String(); orderBy != "" {
 		p.Add("$orderby", orderBy)
 	}
-	if q.Search != "" {
-		p.Add("$search", fmt.Sprintf(`"%s"`, q.Search))
+	if search := q.search(); search != "" {
+		p.Add("$search", search)
 	}
 	if len(q.Select) > 0 {
 		p.Add("$select", strings.Join(q.Select, ","))
@@ -137,6 +147,66 @@ func (q Query) Values() url.Values {
 	return p
 }
 
+// IsAdvancedQuery determines whether the query makes use of Microsoft Graph advanced query capabilities, which
+// require the `ConsistencyLevel: eventual` header and the `$count=true` query parameter. When this is the case,
+// these are set automatically by Headers and Values, unless DisableAdvancedQueryDetection is set.
+//
+// For more information see:
+// https://learn.microsoft.com/en-us/graph/aad-advanced-queries
+func (q Query) IsAdvancedQuery() bool {
+	if q.Count || q.Search != "" {
+		return true
+	}
+	if q.Filter == "" {
+		return false
+	}
+	if q.OrderBy.Field != "" {
+		// $orderby can only be combined with $filter for advanced queries
+		return true
+	}
+	return advancedFilterRegex.MatchString(stripStringLiterals(q.Filter))
+}
+
+// detectAdvancedQuery determines whether the ConsistencyLevel header and the `$count` query parameter should be set
+// automatically
+func (q Query) detectAdvancedQuery() bool {
+	return !q.DisableAdvancedQueryDetection && q.IsAdvancedQuery()
+}
+
+// advancedFilterRegex matches the $filter operators and functions which are only supported by advanced queries
+var advancedFilterRegex = regexp.MustCompile(`(?i)\bne\b|\bnot\b|\bendswith\s*\(|/\$count\b`)
+
+// stripStringLiterals removes any single-quoted string literals from a $filter expression, so that operators are not
+// detected within string values
+func stripStringLiterals(filter string) string {
+	out := strings.Builder{}
+	inLiteral := false
+	for i := 0; i < len(filter); i++ {
+		if filter[i] == '\'' {
+			// a doubled single quote within a literal is an escaped single quote
+			if inLiteral && i+1 < len(filter) && filter[i+1] == '\'' {
+				i++
+				continue
+			}
+			inLiteral = !inLiteral
+			out.WriteByte(' ')
+			continue
+		}
+		if !inLiteral {
+			out.WriteByte(filter[i])
+		}
+	}
+	return out.String()
+}
+
+// search returns the value of the $search query parameter, quoting a single search term when necessary
+func (q Query) search() string {
+	if q.Search == "" || strings.HasPrefix(q.Search, `"`) {
+		return q.Search
+	}
+	return quoteSearchTerm(q.Search)
+}
+
 // AppendValues returns the provided url.Values map with OData specific query parameters appended, for use in requests
 func (q Query) AppendValues(values url.Values) url.Values {
 	if values == nil {
@@ -191,6 +261,47 @@ func (o OrderBy) String() (val string) {
 	return
 }
 
+// SearchClause returns a quoted $search clause which matches the provided term against the specified property, e.g.
+// `"displayName:foo"`. When property is empty, the term is matched against the default search properties.
+func SearchClause(property, term string) string {
+	if property != "" {
+		term = fmt.Sprintf("%s:%s", property, term)
+	}
+	return quoteSearchTerm(term)
+}
+
+// SearchAnd combines $search clauses such that all of them must match, e.g. `"displayName:foo" AND "mail:bar"`
+func SearchAnd(clauses ...string) string {
+	return joinSearchClauses("AND", clauses)
+}
+
+// SearchOr combines $search clauses such that any of them may match, e.g. `"displayName:foo" OR "mail:bar"`
+func SearchOr(clauses ...string) string {
+	return joinSearchClauses("OR", clauses)
+}
+
+func joinSearchClauses(operator string, clauses []string) string {
+	nonEmpty := make([]string, 0, len(clauses))
+	for _, c := range clauses {
+		if c == "" {
+			continue
+		}
+		if strings.Contains(c, `" AND "`) || strings.Contains(c, `" OR "`) {
+			c = fmt.Sprintf("(%s)", c)
+		}
+		nonEmpty = append(nonEmpty, c)
+	}
+	return strings.Join(nonEmpty, fmt.Sprintf(" %s ", operator))
+}
+
+// quoteSearchTerm wraps a $search term in double quotes, escaping any backslashes or double quotes it contains
+// https://learn.microsoft.com/en-us/graph/search-query-parameter#using-search-on-directory-object-collections
+func quoteSearchTerm(term string) string {
+	term = strings.ReplaceAll(term, `\`, `\\`)
+	term = strings.ReplaceAll(term, `"`, `\"`)
+	return fmt.Sprintf(`"%s"`, term)
+}
+
 // EscapeSingleQuote replaces all occurrences of single quote, with 2 single quotes.
 // For requests that use single quotes, if any parameter values also contain single quotes,
 // those must be double escaped; otherwise, the request will fail due to invalid syntax.
@@ -38,6 +38,28 @@ func TestQueryHeaders(t *testing.T) {
 				"Odata-Version":    []string{odata.ODataVersion},
 			},
 		},
+		{
+			query: odata.Query{
+				Filter: "userType ne 'Member'",
+			},
+			expected: http.Header{
+				"Accept":           []string{"application/json; charset=utf-8; IEEE754Compatible=false"},
+				"Consistencylevel": []string{"eventual"},
+				"Odata-Maxversion": []string{odata.ODataVersion},
+				"Odata-Version":    []string{odata.ODataVersion},
+			},
+		},
+		{
+			query: odata.Query{
+				Filter:                        "userType ne 'Member'",
+				DisableAdvancedQueryDetection: true,
+			},
+			expected: http.Header{
+				"Accept":           []string{"application/json; charset=utf-8; IEEE754Compatible=false"},
+				"Odata-Maxversion": []string{odata.ODataVersion},
+				"Odata-Version":    []string{odata.ODataVersion},
+			},
+		},
 	}
 	for n, c := range testCases {
 		v := c.query.Headers()
@@ -113,9 +135,46 @@ func TestQueryValues(t *testing.T) {
 				Search: "displayName:Astley",
 			},
 			expected: url.Values{
+				"$count":  []string{"true"},
 				"$search": []string{`"displayName:Astley"`},
 			},
 		},
+		{
+			query: odata.Query{
+				Search: odata.SearchOr(odata.SearchClause("displayName", "Rick"), odata.SearchClause("mail", `"Astley"`)),
+			},
+			expected: url.Values{
+				"$count":  []string{"true"},
+				"$search": []string{`"displayName:Rick" OR "mail:\"Astley\""`},
+			},
+		},
+		{
+			query: odata.Query{
+				Filter: "endsWith(mail,'@example.com')",
+			},
+			expected: url.Values{
+				"$count":  []string{"true"},
+				"$filter": []string{"endsWith(mail,'@example.com')"},
+			},
+		},
+		{
+			query: odata.Query{
+				Filter:                        "endsWith(mail,'@example.com')",
+				DisableAdvancedQueryDetection: true,
+			},
+			expected: url.Values{
+				"$filter": []string{"endsWith(mail,'@example.com')"},
+			},
+		},
+		{
+			query: odata.Query{
+				Count:                         true,
+				DisableAdvancedQueryDetection: true,
+			},
+			expected: url.Values{
+				"$count": []string{"true"},
+			},
+		},
 		{
 			query: odata.Query{
 				Select: []string{"id", "userPrincipalName"},
@@ -140,3 +199,95 @@ func TestQueryValues(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryIsAdvancedQuery(t *testing.T) {
+	type testCase struct {
+		query    odata.Query
+		expected bool
+	}
+	testCases := []testCase{
+		{
+			query:    odata.Query{Filter: "startsWith(displayName,'Widgets')", Top: 10},
+			expected: false,
+		},
+		{
+			query:    odata.Query{OrderBy: odata.OrderBy{Field: "displayName"}},
+			expected: false,
+		},
+		{
+			query:    odata.Query{Filter: "displayName eq 'not ne endsWith(x)' or mail eq 'it''s not'"},
+			expected: false,
+		},
+		{
+			query:    odata.Query{Filter: "notes eq 'x'"},
+			expected: false,
+		},
+		{
+			query:    odata.Query{Count: true},
+			expected: true,
+		},
+		{
+			query:    odata.Query{Search: "Astley"},
+			expected: true,
+		},
+		{
+			query:    odata.Query{Filter: "accountEnabled eq true", OrderBy: odata.OrderBy{Field: "displayName"}},
+			expected: true,
+		},
+		{
+			query:    odata.Query{Filter: "NOT(userType eq 'Guest')"},
+			expected: true,
+		},
+		{
+			query:    odata.Query{Filter: "mail eq 'x' and companyName ne null"},
+			expected: true,
+		},
+		{
+			query:    odata.Query{Filter: "endswith (mail,'@example.com')"},
+			expected: true,
+		},
+		{
+			query:    odata.Query{Filter: "owners/$count eq 0"},
+			expected: true,
+		},
+	}
+	for n, c := range testCases {
+		if v := c.query.IsAdvancedQuery(); v != c.expected {
+			t.Errorf("test case %d: expected %t, got %t", n, c.expected, v)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	type testCase struct {
+		search   string
+		expected string
+	}
+	testCases := []testCase{
+		{
+			search:   "Astley",
+			expected: `"Astley"`,
+		},
+		{
+			search:   `Rick "Roll"`,
+			expected: `"Rick \"Roll\""`,
+		},
+		{
+			search:   odata.SearchClause("", "Astley"),
+			expected: `"Astley"`,
+		},
+		{
+			search:   odata.SearchAnd(odata.SearchClause("displayName", "Rick"), "", odata.SearchClause("mail", "rick")),
+			expected: `"displayName:Rick" AND "mail:rick"`,
+		},
+		{
+			search:   odata.SearchAnd(odata.SearchClause("jobTitle", "Singer"), odata.SearchOr(odata.SearchClause("displayName", "Rick"), odata.SearchClause("mail", "rick"))),
+			expected: `"jobTitle:Singer" AND ("displayName:Rick" OR "mail:rick")`,
+		},
+	}
+	for n, c := range testCases {
+		if v := (odata.Query{Search: c.search}).Values().Get("$search"); v != c.expected {
+			t.Errorf("test case %d: expected %s, got %s", n, c.expected, v)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakearm
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"golang.org/x/oauth2"
+)
+
+// accessToken is the access token issued by the Authorizer, and expected by the Server
+const accessToken = "fakearm-access-token"
+
+var _ auth.Authorizer = &authorizer{}
+
+type authorizer struct{}
+
+// Authorizer returns an auth.Authorizer which issues access tokens accepted by the Server
+func (s *Server) Authorizer() auth.Authorizer {
+	return &authorizer{}
+}
+
+func (*authorizer) Token(_ context.Context, _ *http.Request) (*oauth2.Token, error) {
+	return &oauth2.Token{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		Expiry:      time.Now().Add(time.Hour),
+	}, nil
+}
+
+func (*authorizer) AuxiliaryTokens(_ context.Context, _ *http.Request) ([]*oauth2.Token, error) {
+	return []*oauth2.Token{}, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakearm
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// segments splits a resource ID or collection path into its segments
+func segments(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// providersIndex returns the index of the last `providers` segment, or -1 when there isn't one
+func providersIndex(segments []string) int {
+	for i := len(segments) - 1; i >= 0; i-- {
+		if strings.EqualFold(segments[i], "providers") {
+			return i
+		}
+	}
+	return -1
+}
+
+// isCollection determines whether a path refers to a collection of resources (e.g. `/subscriptions/1234/resourceGroups`),
+// rather than a single resource (e.g. `/subscriptions/1234/resourceGroups/example`)
+func isCollection(path string) bool {
+	s := segments(path)
+	if i := providersIndex(s); i >= 0 {
+		// following `providers` is the namespace, then pairs of type and name
+		return (len(s)-i-1)%2 == 0
+	}
+	return len(s)%2 == 1
+}
+
+// inCollection determines whether the resource with the specified ID is a direct member of the collection, either
+// as a child (e.g. `/subscriptions/1234/resourceGroups/example/providers/Microsoft.Foo/bars`) or when the collection
+// spans all resource groups in a subscription (e.g. `/subscriptions/1234/providers/Microsoft.Foo/bars`)
+func inCollection(collection, id string) bool {
+	i := strings.LastIndex(id, "/")
+	if i < 0 {
+		// not a resource ID, e.g. when specified using SetResource
+		return false
+	}
+	if strings.EqualFold(id[:i], collection) {
+		return true
+	}
+
+	c := segments(collection)
+	if len(c) == 5 && strings.EqualFold(c[0], "subscriptions") && strings.EqualFold(c[2], "providers") {
+		r := segments(id)
+		return len(r) == 8 &&
+			strings.EqualFold(r[0], "subscriptions") && strings.EqualFold(r[1], c[1]) &&
+			strings.EqualFold(r[2], "resourceGroups") &&
+			strings.EqualFold(strings.Join(r[4:7], "/"), strings.Join(c[2:5], "/"))
+	}
+
+	return false
+}
+
+// resourceType returns the type of the resource with the specified ID, e.g. `Microsoft.Compute/virtualMachines`
+func resourceType(id string) string {
+	s := segments(id)
+	i := providersIndex(s)
+	if i < 0 {
+		if len(s) >= 2 && strings.EqualFold(s[len(s)-2], "resourceGroups") {
+			return "Microsoft.Resources/resourceGroups"
+		}
+		if len(s) >= 2 {
+			return s[len(s)-2]
+		}
+		return ""
+	}
+
+	types := []string{s[i+1]}
+	for j := i + 2; j < len(s); j += 2 {
+		types = append(types, s[j])
+	}
+	return strings.Join(types, "/")
+}
+
+// withResourceMetadata returns a copy of body with the `id`, `name` and `type` fields populated
+func withResourceMetadata(id string, body map[string]interface{}) map[string]interface{} {
+	out := copyObject(body)
+	s := segments(id)
+	out["id"] = id
+	out["name"] = s[len(s)-1]
+	out["type"] = resourceType(id)
+	return out
+}
+
+// withProvisioningState returns a copy of body with `properties.provisioningState` set to the specified value
+func withProvisioningState(body map[string]interface{}, provisioningState string) map[string]interface{} {
+	out := copyObject(body)
+	properties, ok := out["properties"].(map[string]interface{})
+	if !ok {
+		properties = make(map[string]interface{})
+	}
+	properties["provisioningState"] = provisioningState
+	out["properties"] = properties
+	return out
+}
+
+// mergePatch applies a JSON Merge Patch (RFC 7386) to a copy of target
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	out := copyObject(target)
+	for k, v := range patch {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		if patchObject, ok := v.(map[string]interface{}); ok {
+			if targetObject, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergePatch(targetObject, patchObject)
+				continue
+			}
+			out[k] = mergePatch(map[string]interface{}{}, patchObject)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// copyObject returns a deep copy of a JSON object
+func copyObject(in map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	if in == nil {
+		return out
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(data, &out)
+	return out
+}
@@ -0,0 +1,434 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakearm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+)
+
+// operationsPathPrefix is the path under which long-running operations are exposed for polling
+const operationsPathPrefix = "/fakearm/operations/"
+
+// PollingMode determines how the Server completes PUT, PATCH and DELETE requests
+type PollingMode string
+
+const (
+	// PollingModeNone completes all requests synchronously
+	PollingModeNone PollingMode = "None"
+
+	// PollingModeAsyncOperation returns an `Azure-AsyncOperation` header, which returns the status of the operation
+	PollingModeAsyncOperation PollingMode = "AsyncOperation"
+
+	// PollingModeLocation returns a `Location` header, which returns a 202 until the operation has completed
+	PollingModeLocation PollingMode = "Location"
+
+	// PollingModeProvisioningState returns the resource immediately, with a `provisioningState` which transitions to
+	// `Succeeded` as the resource is retrieved
+	PollingModeProvisioningState PollingMode = "ProvisioningState"
+)
+
+// Options configures a Server
+type Options struct {
+	// PageSize is the maximum number of resources returned in each page of a list, after which a `nextLink` is
+	// returned. When zero, all resources are returned in a single page.
+	PageSize int
+
+	// PollingMode determines how PUT, PATCH and DELETE requests complete. Defaults to PollingModeNone.
+	PollingMode PollingMode
+
+	// PollsUntilComplete is the number of times a long-running operation must be polled before it completes.
+	// Defaults to 1.
+	PollsUntilComplete int
+}
+
+// Server is an in-process stand-in for Azure Resource Manager, which stores resources generically by their ID so
+// that Resource Manager clients can be tested without Azure
+type Server struct {
+	*httptest.Server
+
+	options Options
+
+	mu          sync.Mutex
+	resources   map[string]resource
+	operations  map[string]*operation
+	operationId int
+}
+
+type resource struct {
+	id   string
+	body map[string]interface{}
+}
+
+type operation struct {
+	id             string
+	isDelete       bool
+	remainingPolls int
+	resourceId     string
+}
+
+// NewServer starts and returns a new Server, which should be closed when no longer needed
+func NewServer(options Options) *Server {
+	if options.PollingMode == "" {
+		options.PollingMode = PollingModeNone
+	}
+	if options.PollsUntilComplete <= 0 {
+		options.PollsUntilComplete = 1
+	}
+
+	s := &Server{
+		options:    options,
+		resources:  make(map[string]resource),
+		operations: make(map[string]*operation),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Api returns an environments.Api for the Server, which can be used to build a Resource Manager client
+func (s *Server) Api() environments.Api {
+	return environments.NewApiEndpoint("FakeResourceManager", s.URL, nil)
+}
+
+// SetResource stores a resource with the specified ID, replacing any existing resource
+func (s *Server) SetResource(id string, body map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[strings.ToLower(id)] = resource{
+		id:   id,
+		body: withResourceMetadata(id, body),
+	}
+}
+
+// Resource returns a copy of the resource with the specified ID, if it exists
+func (s *Server) Resource(id string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.resources[strings.ToLower(id)]
+	if !ok {
+		return nil, false
+	}
+	return copyObject(r.body), true
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+accessToken {
+		writeError(w, http.StatusUnauthorized, "AuthenticationFailed", "Authentication failed. The 'Authorization' header is missing or invalid.")
+		return
+	}
+
+	if r.URL.Query().Get("api-version") == "" {
+		writeError(w, http.StatusBadRequest, "MissingApiVersionParameter", "The api-version query parameter (?api-version=) is required for all requests.")
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if strings.HasPrefix(path, operationsPathPrefix) {
+		s.handleOperation(w, r, strings.TrimPrefix(path, operationsPathPrefix))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if isCollection(path) {
+			s.list(w, r, path)
+			return
+		}
+		s.get(w, path)
+	case http.MethodPut:
+		s.put(w, r, path)
+	case http.MethodPatch:
+		s.patch(w, r, path)
+	case http.MethodDelete:
+		s.delete(w, r, path)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", fmt.Sprintf("The method %q is not supported.", r.Method))
+	}
+}
+
+func (s *Server) get(w http.ResponseWriter, id string) {
+	s.advanceOperationsForResource(id)
+
+	existing, ok := s.resources[strings.ToLower(id)]
+	if !ok {
+		writeNotFound(w, id)
+		return
+	}
+	writeJson(w, http.StatusOK, existing.body)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, collection string) {
+	items := make([]resource, 0)
+	for _, v := range s.resources {
+		if inCollection(collection, v.id) {
+			items = append(items, v)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].id) < strings.ToLower(items[j].id)
+	})
+
+	skip := 0
+	if v := r.URL.Query().Get("$skiptoken"); v != "" {
+		var err error
+		if skip, err = strconv.Atoi(v); err != nil || skip < 0 {
+			writeError(w, http.StatusBadRequest, "InvalidSkipToken", fmt.Sprintf("The skip token %q is invalid.", v))
+			return
+		}
+	}
+	if skip > len(items) {
+		skip = len(items)
+	}
+	items = items[skip:]
+
+	out := map[string]interface{}{}
+	if s.options.PageSize > 0 && len(items) > s.options.PageSize {
+		items = items[:s.options.PageSize]
+
+		query := url.Values{}
+		query.Set("api-version", r.URL.Query().Get("api-version"))
+		query.Set("$skiptoken", strconv.Itoa(skip+s.options.PageSize))
+		out["nextLink"] = fmt.Sprintf("%s%s?%s", s.URL, collection, query.Encode())
+	}
+
+	values := make([]interface{}, 0, len(items))
+	for _, v := range items {
+		values = append(values, v.body)
+	}
+	out["value"] = values
+
+	writeJson(w, http.StatusOK, out)
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, id string) {
+	body, ok := readObject(w, r)
+	if !ok {
+		return
+	}
+
+	_, exists := s.resources[strings.ToLower(id)]
+	provisioningState := "Succeeded"
+	if s.options.PollingMode != PollingModeNone {
+		provisioningState = "Creating"
+		if exists {
+			provisioningState = "Updating"
+		}
+	}
+	body = withProvisioningState(withResourceMetadata(id, body), provisioningState)
+	s.resources[strings.ToLower(id)] = resource{
+		id:   id,
+		body: body,
+	}
+
+	statusCode := http.StatusCreated
+	if exists {
+		statusCode = http.StatusOK
+	}
+	s.complete(w, r, id, false, statusCode, body)
+}
+
+func (s *Server) patch(w http.ResponseWriter, r *http.Request, id string) {
+	existing, ok := s.resources[strings.ToLower(id)]
+	if !ok {
+		writeNotFound(w, id)
+		return
+	}
+
+	patch, ok := readObject(w, r)
+	if !ok {
+		return
+	}
+
+	provisioningState := "Succeeded"
+	if s.options.PollingMode != PollingModeNone {
+		provisioningState = "Updating"
+	}
+	body := withProvisioningState(withResourceMetadata(id, mergePatch(existing.body, patch)), provisioningState)
+	s.resources[strings.ToLower(id)] = resource{
+		id:   existing.id,
+		body: body,
+	}
+
+	s.complete(w, r, id, false, http.StatusOK, body)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := s.resources[strings.ToLower(id)]; !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if s.options.PollingMode == PollingModeNone {
+		delete(s.resources, strings.ToLower(id))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.complete(w, r, id, true, http.StatusOK, nil)
+}
+
+// complete writes the response for a PUT, PATCH or DELETE request, starting a long-running operation when required
+func (s *Server) complete(w http.ResponseWriter, r *http.Request, id string, isDelete bool, statusCode int, body map[string]interface{}) {
+	if s.options.PollingMode == PollingModeNone {
+		writeJson(w, statusCode, body)
+		return
+	}
+
+	s.operationId++
+	op := &operation{
+		id:             strconv.Itoa(s.operationId),
+		isDelete:       isDelete,
+		remainingPolls: s.options.PollsUntilComplete,
+		resourceId:     id,
+	}
+	s.operations[op.id] = op
+
+	query := url.Values{}
+	query.Set("api-version", r.URL.Query().Get("api-version"))
+	operationUrl := fmt.Sprintf("%s%s%s?%s", s.URL, operationsPathPrefix, op.id, query.Encode())
+
+	switch s.options.PollingMode {
+	case PollingModeAsyncOperation:
+		w.Header().Set("Azure-AsyncOperation", operationUrl)
+		w.Header().Set("Location", operationUrl)
+	case PollingModeLocation:
+		w.Header().Set("Location", operationUrl)
+	}
+	if s.options.PollingMode != PollingModeProvisioningState && r.Method != http.MethodPut {
+		statusCode = http.StatusAccepted
+	}
+
+	if isDelete {
+		w.WriteHeader(statusCode)
+		return
+	}
+	writeJson(w, statusCode, body)
+}
+
+func (s *Server) handleOperation(w http.ResponseWriter, r *http.Request, operationId string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", fmt.Sprintf("The method %q is not supported.", r.Method))
+		return
+	}
+
+	op, ok := s.operations[operationId]
+	if !ok {
+		writeNotFound(w, operationsPathPrefix+operationId)
+		return
+	}
+	s.advanceOperation(op)
+
+	done := op.remainingPolls <= 0
+	if s.options.PollingMode == PollingModeLocation {
+		if !done {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if op.isDelete {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if existing, ok := s.resources[strings.ToLower(op.resourceId)]; ok {
+			writeJson(w, http.StatusOK, existing.body)
+			return
+		}
+		writeNotFound(w, op.resourceId)
+		return
+	}
+
+	status := "InProgress"
+	if done {
+		status = "Succeeded"
+	}
+	percentComplete := float64(s.options.PollsUntilComplete-op.remainingPolls) / float64(s.options.PollsUntilComplete) * 100
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"id":              operationsPathPrefix + op.id,
+		"name":            op.id,
+		"percentComplete": percentComplete,
+		"status":          status,
+	})
+}
+
+// advanceOperationsForResource advances any outstanding operations for the specified resource, since polling may
+// be performed against the resource itself
+func (s *Server) advanceOperationsForResource(id string) {
+	for _, op := range s.operations {
+		if strings.EqualFold(op.resourceId, id) {
+			s.advanceOperation(op)
+		}
+	}
+}
+
+func (s *Server) advanceOperation(op *operation) {
+	if op.remainingPolls <= 0 {
+		return
+	}
+
+	op.remainingPolls--
+	if op.remainingPolls > 0 {
+		return
+	}
+
+	key := strings.ToLower(op.resourceId)
+	if op.isDelete {
+		delete(s.resources, key)
+		return
+	}
+	if existing, ok := s.resources[key]; ok {
+		existing.body = withProvisioningState(existing.body, "Succeeded")
+		s.resources[key] = existing
+	}
+}
+
+func readObject(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequestContent", fmt.Sprintf("The request content could not be read: %+v", err))
+		return nil, false
+	}
+
+	body := make(map[string]interface{})
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &body); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestContent", fmt.Sprintf("The request content was invalid and could not be deserialized: %+v", err))
+			return nil, false
+		}
+	}
+	return body, true
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	writeJson(w, statusCode, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+func writeNotFound(w http.ResponseWriter, id string) {
+	writeError(w, http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("The Resource %q was not found.", id))
+}
@@ -0,0 +1,276 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakearm_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/databricks/2023-05-01/accessconnector"
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/resourcemanager"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+	"github.com/hashicorp/go-azure-sdk/sdk/testing/fakearm"
+)
+
+const resourceGroupId = "/subscriptions/11111111-1111-1111-1111-111111111111/resourceGroups/example"
+
+type thing struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Location   string `json:"location"`
+	Properties struct {
+		ProvisioningState string `json:"provisioningState"`
+		Size              string `json:"size"`
+	} `json:"properties"`
+}
+
+func TestServer_CRUD(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := fakearm.NewServer(fakearm.Options{})
+	defer server.Close()
+	c := newClient(t, server)
+	id := resourceGroupId + "/providers/Microsoft.Example/things/first"
+
+	resp := execute(ctx, t, c, http.MethodPut, id, map[string]interface{}{
+		"location": "westeurope",
+		"properties": map[string]interface{}{
+			"size": "Small",
+		},
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected a 201 when creating but got %d", resp.StatusCode)
+	}
+
+	resp = execute(ctx, t, c, http.MethodPatch, id, map[string]interface{}{
+		"properties": map[string]interface{}{
+			"size": "Large",
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a 200 when updating but got %d", resp.StatusCode)
+	}
+
+	var model thing
+	if err := execute(ctx, t, c, http.MethodGet, id, nil).Unmarshal(&model); err != nil {
+		t.Fatalf("unmarshaling: %+v", err)
+	}
+	if model.Id != id || model.Name != "first" || model.Type != "Microsoft.Example/things" {
+		t.Fatalf("unexpected resource metadata: %+v", model)
+	}
+	if model.Location != "westeurope" || model.Properties.Size != "Large" || model.Properties.ProvisioningState != "Succeeded" {
+		t.Fatalf("unexpected resource: %+v", model)
+	}
+
+	execute(ctx, t, c, http.MethodDelete, id, nil)
+	if resp = execute(ctx, t, c, http.MethodGet, id, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 after deleting but got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_LongRunningOperations(t *testing.T) {
+	for _, mode := range []fakearm.PollingMode{fakearm.PollingModeAsyncOperation, fakearm.PollingModeLocation, fakearm.PollingModeProvisioningState} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(pollers.WithoutPollingDelays(context.Background()), 30*time.Second)
+			defer cancel()
+
+			server := fakearm.NewServer(fakearm.Options{
+				PollingMode:        mode,
+				PollsUntilComplete: 3,
+			})
+			defer server.Close()
+			c := newClient(t, server)
+			id := resourceGroupId + "/providers/Microsoft.Example/things/first"
+
+			resp := execute(ctx, t, c, http.MethodPut, id, map[string]interface{}{
+				"location": "westeurope",
+			})
+			poller, err := resourcemanager.PollerFromResponse(resp, c)
+			if err != nil {
+				t.Fatalf("building poller for create: %+v", err)
+			}
+			if err = poller.PollUntilDone(ctx); err != nil {
+				t.Fatalf("polling for create: %+v", err)
+			}
+			if v, ok := server.Resource(id); !ok || v["properties"].(map[string]interface{})["provisioningState"] != "Succeeded" {
+				t.Fatalf("expected the resource to have been provisioned but got %+v", v)
+			}
+
+			resp = execute(ctx, t, c, http.MethodDelete, id, nil)
+			poller, err = resourcemanager.PollerFromResponse(resp, c)
+			if err != nil {
+				t.Fatalf("building poller for delete: %+v", err)
+			}
+			if err = poller.PollUntilDone(ctx); err != nil {
+				t.Fatalf("polling for delete: %+v", err)
+			}
+			if _, ok := server.Resource(id); ok {
+				t.Fatalf("expected the resource to have been deleted")
+			}
+		})
+	}
+}
+
+func TestServer_GeneratedClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := fakearm.NewServer(fakearm.Options{
+		PollingMode:        fakearm.PollingModeAsyncOperation,
+		PollsUntilComplete: 2,
+	})
+	defer server.Close()
+
+	c, err := accessconnector.NewAccessConnectorClientWithBaseURI(server.Api())
+	if err != nil {
+		t.Fatalf("building client: %+v", err)
+	}
+	c.Client.Authorizer = server.Authorizer()
+	c.Client.DisablePollingDelays = true
+
+	resourceGroup := commonids.NewResourceGroupID("11111111-1111-1111-1111-111111111111", "example")
+	for _, name := range []string{"first", "second"} {
+		id := accessconnector.NewAccessConnectorID(resourceGroup.SubscriptionId, resourceGroup.ResourceGroupName, name)
+		if err = c.CreateOrUpdateThenPoll(ctx, id, accessconnector.AccessConnector{Location: "westeurope"}); err != nil {
+			t.Fatalf("creating %s: %+v", id, err)
+		}
+	}
+
+	result, err := c.ListByResourceGroupComplete(ctx, resourceGroup)
+	if err != nil {
+		t.Fatalf("listing: %+v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("expected 2 access connectors but got %d", len(result.Items))
+	}
+	for _, item := range result.Items {
+		if item.Name == nil || item.Properties == nil || item.Properties.ProvisioningState == nil {
+			t.Fatalf("expected the name and provisioning state to be returned, got %+v", item)
+		}
+		if *item.Properties.ProvisioningState != accessconnector.ProvisioningStateSucceeded {
+			t.Fatalf("expected %q to have been provisioned but got %q", *item.Name, *item.Properties.ProvisioningState)
+		}
+	}
+
+	id := accessconnector.NewAccessConnectorID(resourceGroup.SubscriptionId, resourceGroup.ResourceGroupName, "first")
+	if err = c.DeleteThenPoll(ctx, id); err != nil {
+		t.Fatalf("deleting %s: %+v", id, err)
+	}
+	if _, ok := server.Resource(id.ID()); ok {
+		t.Fatalf("expected %s to have been deleted", id)
+	}
+}
+
+type nextLinkPager struct {
+	NextLink *odata.Link `json:"nextLink"`
+}
+
+func (p *nextLinkPager) NextPageLink() *odata.Link {
+	defer func() {
+		p.NextLink = nil
+	}()
+	return p.NextLink
+}
+
+func TestServer_ListPagination(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := fakearm.NewServer(fakearm.Options{
+		PageSize: 2,
+	})
+	defer server.Close()
+	c := newClient(t, server)
+
+	for i := 0; i < 5; i++ {
+		server.SetResource(fmt.Sprintf("%s/providers/Microsoft.Example/things/thing%d", resourceGroupId, i), map[string]interface{}{})
+	}
+	server.SetResource(resourceGroupId+"/providers/Microsoft.Example/others/other", map[string]interface{}{})
+	server.SetResource("/subscriptions/11111111-1111-1111-1111-111111111111/resourceGroups/another/providers/Microsoft.Example/things/thing5", map[string]interface{}{})
+	// an invalid resource ID is never listed
+	server.SetResource("thing6", map[string]interface{}{})
+
+	testCases := []struct {
+		path     string
+		expected int
+	}{
+		{
+			path:     resourceGroupId + "/providers/Microsoft.Example/things",
+			expected: 5,
+		},
+		{
+			path:     "/subscriptions/11111111-1111-1111-1111-111111111111/providers/Microsoft.Example/things",
+			expected: 6,
+		},
+	}
+	for _, v := range testCases {
+		req, err := c.NewRequest(ctx, client.RequestOptions{
+			ContentType:         "application/json; charset=utf-8",
+			ExpectedStatusCodes: []int{http.StatusOK},
+			HttpMethod:          http.MethodGet,
+			Pager:               &nextLinkPager{},
+			Path:                v.path,
+		})
+		if err != nil {
+			t.Fatalf("building request: %+v", err)
+		}
+
+		iterator := client.NewListIterator[thing](req.PageIterator())
+		count := 0
+		for iterator.Next(ctx) {
+			count++
+		}
+		if err = iterator.Err(); err != nil {
+			t.Fatalf("listing %q: %+v", v.path, err)
+		}
+		if count != v.expected {
+			t.Fatalf("expected %d items for %q but got %d", v.expected, v.path, count)
+		}
+	}
+}
+
+func newClient(t *testing.T, server *fakearm.Server) *resourcemanager.Client {
+	c, err := resourcemanager.NewResourceManagerClient(server.Api(), "example", "2020-01-01")
+	if err != nil {
+		t.Fatalf("building client: %+v", err)
+	}
+	c.Authorizer = server.Authorizer()
+	return c
+}
+
+func execute(ctx context.Context, t *testing.T, c *resourcemanager.Client, method, path string, body interface{}) *client.Response {
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+			http.StatusCreated,
+			http.StatusAccepted,
+			http.StatusNoContent,
+			http.StatusNotFound,
+		},
+		HttpMethod: method,
+		Path:       path,
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	if body != nil {
+		if err = req.Marshal(body); err != nil {
+			t.Fatalf("marshaling request: %+v", err)
+		}
+	}
+	resp, err := req.Execute(ctx)
+	if err != nil {
+		t.Fatalf("executing %s %q: %+v", method, path, err)
+	}
+	return resp
+}
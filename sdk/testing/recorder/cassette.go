@@ -0,0 +1,71 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Cassette is a sequence of recorded HTTP interactions, which is persisted as JSON
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded HTTP request and the response received for it
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded HTTP request
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded HTTP response
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads a Cassette from the file at the specified path
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette %q: %+v", path, err)
+	}
+
+	var cassette Cassette
+	if err = json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("parsing cassette %q: %+v", path, err)
+	}
+
+	return &cassette, nil
+}
+
+// Save writes the Cassette to the file at the specified path, creating any parent directories
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cassette: %+v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating directory for cassette %q: %+v", path, err)
+	}
+
+	if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing cassette %q: %+v", path, err)
+	}
+
+	return nil
+}
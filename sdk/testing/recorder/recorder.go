@@ -0,0 +1,338 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
+)
+
+// ModeEnvironmentVariable is the name of the environment variable used by ModeFromEnvironment
+const ModeEnvironmentVariable = "RECORDING_MODE"
+
+// Mode determines whether a Recorder records or replays interactions
+type Mode string
+
+const (
+	// ModeLive sends requests to the API without recording them
+	ModeLive Mode = "live"
+
+	// ModeRecord sends requests to the API and records them, saving the Cassette when the Recorder is stopped
+	ModeRecord Mode = "record"
+
+	// ModeReplay replays previously recorded interactions without sending requests to the API
+	ModeReplay Mode = "replay"
+)
+
+// ModeFromEnvironment returns the Mode specified by the RECORDING_MODE environment variable, defaulting to ModeReplay
+func ModeFromEnvironment() Mode {
+	switch Mode(strings.ToLower(os.Getenv(ModeEnvironmentVariable))) {
+	case ModeLive:
+		return ModeLive
+	case ModeRecord:
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Options configures a Recorder
+type Options struct {
+	// CassettePath is the path of the file in which interactions are recorded
+	CassettePath string
+
+	// Mode determines whether interactions are recorded or replayed
+	Mode Mode
+
+	// MatchBody additionally requires the request body to match when replaying. JSON bodies are compared semantically.
+	MatchBody bool
+
+	// Scrubbers are applied to each Interaction, in addition to ScrubSecrets. When replaying, these are also applied
+	// to incoming requests before matching, so that placeholders can be used consistently.
+	Scrubbers []Scrubber
+
+	// Transport is used to send requests when recording or live. When nil, client.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+var _ http.RoundTripper = &Recorder{}
+
+// Recorder is a http.RoundTripper which records and replays HTTP interactions, allowing tests to be run against
+// the generated clients without network access
+type Recorder struct {
+	cassette *Cassette
+	options  Options
+	used     []bool
+
+	mu sync.Mutex
+}
+
+// NewRecorder returns a Recorder using the specified Options. When replaying, the Cassette is loaded immediately.
+func NewRecorder(options Options) (*Recorder, error) {
+	if options.Mode == "" {
+		options.Mode = ModeReplay
+	}
+	if options.Mode != ModeLive && options.CassettePath == "" {
+		return nil, fmt.Errorf("a `CassettePath` must be specified when recording or replaying")
+	}
+
+	r := &Recorder{
+		cassette: &Cassette{},
+		options:  options,
+	}
+
+	if options.Mode == ModeReplay {
+		cassette, err := LoadCassette(options.CassettePath)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Start returns a Recorder for the current test using the Mode from ModeFromEnvironment, recording into the file
+// `testdata/recordings/<test name>.json`. When recording, the Cassette is saved when the test completes.
+func Start(t testing.TB, scrubbers ...Scrubber) *Recorder {
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	r, err := NewRecorder(Options{
+		CassettePath: filepath.Join("testdata", "recordings", fmt.Sprintf("%s.json", name)),
+		Mode:         ModeFromEnvironment(),
+		Scrubbers:    scrubbers,
+	})
+	if err != nil {
+		t.Fatalf("starting recorder: %+v", err)
+	}
+	t.Cleanup(func() {
+		if err := r.Stop(); err != nil {
+			t.Errorf("stopping recorder: %+v", err)
+		}
+	})
+	return r
+}
+
+// Mode returns the Mode of the Recorder
+func (r *Recorder) Mode() Mode {
+	return r.options.Mode
+}
+
+// Configure configures the provided client.Client to send requests via the Recorder. When replaying, polling delays
+// are also disabled for the client, so that long-running operations complete instantly.
+func (r *Recorder) Configure(c *client.Client) {
+	c.Transport = r
+	c.DisablePollingDelays = r.options.Mode == ModeReplay
+}
+
+// Context returns a copy of ctx which, when replaying, causes pollers to poll again immediately rather than
+// waiting for the poll interval. This is only needed for clients which have not been set up using Configure.
+func (r *Recorder) Context(ctx context.Context) context.Context {
+	if r.options.Mode == ModeReplay {
+		return pollers.WithoutPollingDelays(ctx)
+	}
+	return ctx
+}
+
+// Stop saves the Cassette when recording
+func (r *Recorder) Stop() error {
+	if r.options.Mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.options.CassettePath)
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch r.options.Mode {
+	case ModeLive:
+		return r.transport().RoundTrip(req)
+	case ModeRecord:
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var responseBody []byte
+	if resp.Body != nil {
+		responseBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading response body: %+v", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+	}
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: req.Header.Clone(),
+			Body:    string(requestBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Header.Clone(),
+			Body:       string(responseBody),
+		},
+	}
+	r.scrub(&interaction)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// scrub the incoming request in the same way as the recorded requests, so that they can be compared
+	incoming := Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: req.Header.Clone(),
+			Body:    string(requestBody),
+		},
+	}
+	r.scrub(&incoming)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.matches(incoming.Request, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+
+		headers := interaction.Response.Headers.Clone()
+		if headers == nil {
+			headers = http.Header{}
+		}
+		// the recorded delay isn't needed since nothing is happening on the other end
+		if headers.Get("Retry-After") != "" {
+			headers.Set("Retry-After", "0")
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        headers,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction matches %s %s in cassette %q", req.Method, req.URL.String(), r.options.CassettePath)
+}
+
+// matches determines whether the incoming request matches a recorded request, by comparing the method, path and
+// api-version, and optionally the body
+func (r *Recorder) matches(incoming, recorded RecordedRequest) bool {
+	if !strings.EqualFold(incoming.Method, recorded.Method) {
+		return false
+	}
+
+	incomingUrl, err := url.Parse(incoming.URL)
+	if err != nil {
+		return false
+	}
+	recordedUrl, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(strings.TrimSuffix(incomingUrl.Path, "/"), strings.TrimSuffix(recordedUrl.Path, "/")) {
+		return false
+	}
+	if incomingUrl.Query().Get("api-version") != recordedUrl.Query().Get("api-version") {
+		return false
+	}
+
+	if r.options.MatchBody {
+		return bodiesMatch(incoming.Body, recorded.Body)
+	}
+
+	return true
+}
+
+func (r *Recorder) scrub(i *Interaction) {
+	ScrubSecrets(i)
+	for _, scrubber := range r.options.Scrubbers {
+		scrubber(i)
+	}
+}
+
+func (r *Recorder) transport() http.RoundTripper {
+	if r.options.Transport != nil {
+		return r.options.Transport
+	}
+	return client.DefaultTransport
+}
+
+// bodiesMatch compares two request bodies, semantically when both are JSON
+func bodiesMatch(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	var aJson, bJson interface{}
+	if json.Unmarshal([]byte(a), &aJson) != nil || json.Unmarshal([]byte(b), &bJson) != nil {
+		return false
+	}
+	aNormalized, _ := json.Marshal(aJson)
+	bNormalized, _ := json.Marshal(bJson)
+	return bytes.Equal(aNormalized, bNormalized)
+}
+
+// readRequestBody reads the body of req, replacing it so that the request can still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %+v", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,209 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package recorder_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/resourcemanager"
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/internal/test"
+	"github.com/hashicorp/go-azure-sdk/sdk/testing/recorder"
+)
+
+const subscriptionId = "11111111-1111-1111-1111-111111111111"
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "10")
+		switch r.Method {
+		case http.MethodPut:
+			w.Header().Set("Azure-AsyncOperation", "http://"+r.Host+"/subscriptions/"+subscriptionId+"/operations/abc123?api-version=2020-01-01")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"properties":{"provisioningState":"Accepted"}}`))
+		case http.MethodGet:
+			polls++
+			w.WriteHeader(http.StatusOK)
+			if polls < 3 {
+				w.Write([]byte(`{"status":"InProgress","access_token":"s3cr3t"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"Succeeded"}`))
+		}
+	}))
+
+	cassettePath := filepath.Join(t.TempDir(), "recording.json")
+	scrubber := recorder.ReplaceString(subscriptionId, "00000000-0000-0000-0000-000000000000")
+
+	// record the initial request and each poll, without waiting between them
+	rec, err := recorder.NewRecorder(recorder.Options{
+		CassettePath: cassettePath,
+		Mode:         recorder.ModeRecord,
+		Scrubbers:    []recorder.Scrubber{scrubber},
+	})
+	if err != nil {
+		t.Fatalf("building recorder: %+v", err)
+	}
+	c := newClient(t, server.URL, rec)
+	resp := execute(ctx, t, c, http.MethodPut, "/subscriptions/"+subscriptionId+"/resourceGroups/example")
+	for i := 0; i < 3; i++ {
+		execute(ctx, t, c, http.MethodGet, "/subscriptions/"+subscriptionId+"/operations/abc123")
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected a 201 when recording but got %d", resp.StatusCode)
+	}
+	if err = rec.Stop(); err != nil {
+		t.Fatalf("saving cassette: %+v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(cassettePath)
+	if err != nil {
+		t.Fatalf("reading cassette: %+v", err)
+	}
+	for _, secret := range []string{"s3cr3t", "Bearer", subscriptionId} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("expected %q to be scrubbed from the cassette", secret)
+		}
+	}
+
+	// replay the interactions, polling until the operation has completed
+	rec, err = recorder.NewRecorder(recorder.Options{
+		CassettePath: cassettePath,
+		Mode:         recorder.ModeReplay,
+		Scrubbers:    []recorder.Scrubber{scrubber},
+	})
+	if err != nil {
+		t.Fatalf("building recorder: %+v", err)
+	}
+	c = newClient(t, "https://management.example.local", rec)
+
+	resp = execute(ctx, t, c, http.MethodPut, "/subscriptions/"+subscriptionId+"/resourceGroups/example")
+	poller, err := resourcemanager.PollerFromResponse(resp, c)
+	if err != nil {
+		t.Fatalf("building poller: %+v", err)
+	}
+
+	start := time.Now()
+	if err = poller.PollUntilDone(ctx); err != nil {
+		t.Fatalf("polling: %+v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected polling to complete without waiting but it took %s", elapsed)
+	}
+
+	// all interactions have been used, so further requests should fail
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType:         "application/json",
+		ExpectedStatusCodes: []int{http.StatusOK},
+		HttpMethod:          http.MethodGet,
+		Path:                "/subscriptions/" + subscriptionId + "/operations/abc123",
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	req.RetryPolicy = &client.RetryPolicy{
+		MaxAttempts: 1,
+	}
+	if _, err = req.Execute(ctx); err == nil {
+		t.Fatalf("expected an error when no interactions remain but didn't get one")
+	}
+}
+
+func TestReplayMatchBody(t *testing.T) {
+	cassettePath := filepath.Join(t.TempDir(), "recording.json")
+	cassette := recorder.Cassette{
+		Interactions: []recorder.Interaction{
+			{
+				Request: recorder.RecordedRequest{
+					Method: http.MethodPut,
+					URL:    "https://management.azure.com/things/first?api-version=2020-01-01",
+					Body:   `{"name": "first", "tags": {"env": "test"}}`,
+				},
+				Response: recorder.RecordedResponse{
+					StatusCode: http.StatusOK,
+				},
+			},
+		},
+	}
+	if err := cassette.Save(cassettePath); err != nil {
+		t.Fatalf("saving cassette: %+v", err)
+	}
+
+	testCases := []struct {
+		body    string
+		matches bool
+	}{
+		{
+			body:    `{"tags":{"env":"test"},"name":"first"}`,
+			matches: true,
+		},
+		{
+			body:    `{"name":"second"}`,
+			matches: false,
+		},
+	}
+	for _, v := range testCases {
+		rec, err := recorder.NewRecorder(recorder.Options{
+			CassettePath: cassettePath,
+			Mode:         recorder.ModeReplay,
+			MatchBody:    true,
+		})
+		if err != nil {
+			t.Fatalf("building recorder: %+v", err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, "https://management.azure.com/things/first?api-version=2020-01-01", strings.NewReader(v.body))
+		_, err = rec.RoundTrip(req)
+		if v.matches && err != nil {
+			t.Fatalf("expected %q to match: %+v", v.body, err)
+		}
+		if !v.matches && err == nil {
+			t.Fatalf("expected %q not to match", v.body)
+		}
+	}
+}
+
+func newClient(t *testing.T, endpoint string, rec *recorder.Recorder) *resourcemanager.Client {
+	api := environments.NewApiEndpoint("Example", endpoint, nil)
+	c, err := resourcemanager.NewResourceManagerClient(api, "example", "2020-01-01")
+	if err != nil {
+		t.Fatalf("building client: %+v", err)
+	}
+	c.Authorizer = &test.TestAuthorizer{}
+	rec.Configure(c.Client)
+	return c
+}
+
+func execute(ctx context.Context, t *testing.T, c *resourcemanager.Client, method, path string) *client.Response {
+	req, err := c.NewRequest(ctx, client.RequestOptions{
+		ContentType: "application/json",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+			http.StatusCreated,
+		},
+		HttpMethod: method,
+		Path:       path,
+	})
+	if err != nil {
+		t.Fatalf("building request: %+v", err)
+	}
+	resp, err := req.Execute(ctx)
+	if err != nil {
+		t.Fatalf("executing request: %+v", err)
+	}
+	return resp
+}
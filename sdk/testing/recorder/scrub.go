@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package recorder
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/logging"
+)
+
+// Scrubber modifies an Interaction before it is saved, for example to remove secrets or identifiers
+type Scrubber func(*Interaction)
+
+// sensitiveHeaders are removed from all recorded requests and responses
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Ms-Authorization-Auxiliary",
+}
+
+// ScrubSecrets removes authorization headers, and redacts access tokens, client secrets and SAS signatures from the
+// URL, headers and body of an Interaction. This Scrubber is always applied when recording.
+func ScrubSecrets(i *Interaction) {
+	i.Request.URL = logging.Redact(i.Request.URL)
+	i.Request.Headers = scrubHeaders(i.Request.Headers)
+	i.Request.Body = logging.Redact(i.Request.Body)
+
+	i.Response.Headers = scrubHeaders(i.Response.Headers)
+	i.Response.Body = logging.Redact(i.Response.Body)
+}
+
+// ReplaceString returns a Scrubber which replaces all instances of old with new in the URL, headers and body of an
+// Interaction, for example to replace a Subscription ID with a placeholder.
+func ReplaceString(old, new string) Scrubber {
+	replace := func(in string) string {
+		if old == "" {
+			return in
+		}
+		return strings.ReplaceAll(in, old, new)
+	}
+	replaceHeaders := func(in http.Header) http.Header {
+		for k, values := range in {
+			for j := range values {
+				values[j] = replace(values[j])
+			}
+			in[k] = values
+		}
+		return in
+	}
+
+	return func(i *Interaction) {
+		i.Request.URL = replace(i.Request.URL)
+		i.Request.Headers = replaceHeaders(i.Request.Headers)
+		i.Request.Body = replace(i.Request.Body)
+
+		i.Response.Headers = replaceHeaders(i.Response.Headers)
+		i.Response.Body = replace(i.Response.Body)
+	}
+}
+
+func scrubHeaders(in http.Header) http.Header {
+	if in == nil {
+		return nil
+	}
+
+	out := in.Clone()
+	for _, k := range sensitiveHeaders {
+		out.Del(k)
+	}
+	for k, values := range out {
+		for j := range values {
+			values[j] = logging.Redact(values[j])
+		}
+		out[k] = values
+	}
+	return out
+}